@@ -0,0 +1,70 @@
+package cache
+
+// arcPolicy is the adaptive replacement cache of Megiddo and Modha. Entries seen once live in t1,
+// entries seen again in t2. The ghost lists b1 and b2 remember keys recently evicted from them, and a
+// miss on a ghost moves the target size p of t1 towards the list that would have kept the key.
+type arcPolicy[K comparable] struct {
+	capacity int
+	p        int
+	t1, t2   *lruPolicy[K]
+	b1, b2   *lruPolicy[K] // ghosts, keys only
+}
+
+func newARC[K comparable](capacity int) *arcPolicy[K] {
+	return &arcPolicy[K]{capacity: capacity, t1: newLRU[K](), t2: newLRU[K](), b1: newLRU[K](), b2: newLRU[K]()}
+}
+
+func (a *arcPolicy[K]) insert(key K) {
+	switch {
+	case a.b1.has(key):
+		a.p = min(a.p+max(a.b2.len()/a.b1.len(), 1), a.capacity)
+		a.b1.remove(key)
+		a.t2.insert(key)
+	case a.b2.has(key):
+		a.p = max(a.p-max(a.b1.len()/a.b2.len(), 1), 0)
+		a.b2.remove(key)
+		a.t2.insert(key)
+	default:
+		a.t1.insert(key)
+	}
+	a.trimGhosts()
+}
+
+func (a *arcPolicy[K]) hit(key K) {
+	if a.t1.has(key) {
+		a.t1.remove(key)
+		a.t2.insert(key)
+	} else {
+		a.t2.hit(key)
+	}
+}
+
+func (a *arcPolicy[K]) remove(key K) {
+	a.t1.remove(key)
+	a.t2.remove(key)
+}
+
+func (a *arcPolicy[K]) evict(incoming K) (K, bool) {
+	fromT1 := a.t1.len() > 0 && (a.t1.len() > a.p || (a.b2.has(incoming) && a.t1.len() == a.p) || a.t2.len() == 0)
+	if fromT1 {
+		key, _ := a.t1.evict(incoming)
+		a.b1.insert(key)
+		return key, true
+	}
+	key, ok := a.t2.evict(incoming)
+	if ok {
+		a.b2.insert(key)
+	}
+	return key, ok
+}
+
+// trimGhosts keeps t1+b1 within capacity and all four lists within twice the capacity.
+func (a *arcPolicy[K]) trimGhosts() {
+	c := max(a.capacity, 1)
+	for a.b1.len() > 0 && a.t1.len()+a.b1.len() > c {
+		a.b1.evict(*new(K))
+	}
+	for a.b2.len() > 0 && a.t1.len()+a.t2.len()+a.b1.len()+a.b2.len() > 2*c {
+		a.b2.evict(*new(K))
+	}
+}
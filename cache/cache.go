@@ -1 +1,289 @@
 package cache
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+/*
+Cache[K, V] is an in-process cache safe for concurrent use.
+
+	c, err := cache.New(cache.Options[string, []byte]{
+		Policy:   cache.LRU,
+		Capacity: 64 << 20,
+		Cost:     func(_ string, v []byte) int64 { return int64(len(v)) },
+		TTL:      time.Minute,
+	})
+
+Capacity bounds the total cost of the entries, each entry costs 1 unless Cost is set. An entry
+that costs more than Capacity is not stored, it is passed to OnEvict right away. ARC and W_TINY_LFU
+size their bookkeeping by entry count, New returns ErrCostNotSupported if they are given a Cost.
+Expired entries are removed lazily when they are read or picked for eviction, or by PurgeExpired.
+*/
+
+type Policy int
+
+const (
+	LRU        Policy = iota // evict the least recently used entry
+	LFU                      // evict the least frequently used entry, the least recent among equals
+	ARC                      // adaptive replacement cache, balances recency and frequency
+	W_TINY_LFU               // window TinyLFU, admits entries to the main cache by estimated frequency
+)
+
+type EvictionReason int
+
+const (
+	EVICTED_CAPACITY EvictionReason = iota // removed to make room
+	EVICTED_EXPIRED                        // the TTL ran out
+	EVICTED_REMOVED                        // deleted, replaced or cleared
+)
+
+type Options[K comparable, V any] struct {
+	Policy   Policy
+	Capacity int64                      // maximum total cost, 0 means unbounded
+	Cost     func(K, V) int64           // cost of an entry, defaults to 1
+	TTL      time.Duration              // default time to live, 0 means no expiry
+	OnEvict  func(K, V, EvictionReason) // called without the cache lock held
+	Now      func() time.Time           // defaults to time.Now
+}
+
+type Stats struct {
+	Hits        int64
+	Misses      int64
+	Evictions   int64 // entries removed for capacity
+	Expirations int64
+}
+
+type Cache[K comparable, V any] struct {
+	opts    Options[K, V]
+	entries map[K]*entry[V]
+	policy  policy[K]
+	cost    int64
+	stats   Stats
+	mu      sync.Mutex
+}
+
+type entry[V any] struct {
+	value   V
+	cost    int64
+	expires time.Time // zero means never
+}
+
+// ErrCostNotSupported is returned by New when ARC or W_TINY_LFU is given a Cost.
+var ErrCostNotSupported = errors.New("cache: ARC and W_TINY_LFU capacities count entries, Cost is not supported")
+
+type eviction[K comparable, V any] struct {
+	key    K
+	value  V
+	reason EvictionReason
+}
+
+func New[K comparable, V any](opts Options[K, V]) (*Cache[K, V], error) {
+	if opts.Cost != nil && (opts.Policy == ARC || opts.Policy == W_TINY_LFU) {
+		return nil, ErrCostNotSupported
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	return &Cache[K, V]{opts: opts, entries: make(map[K]*entry[V]), policy: newPolicy[K](opts.Policy, opts.Capacity)}, nil
+}
+
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	v, _, ok := c.get(key)
+	return v, ok
+}
+
+// get is Get that also returns the expiry time of the entry, zero if it never expires.
+func (c *Cache[K, V]) get(key K) (V, time.Time, bool) {
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	var evicted []eviction[K, V]
+	if ok && c.expired(e) {
+		evicted = append(evicted, c.removeLocked(key, EVICTED_EXPIRED))
+		ok = false
+	}
+	if ok {
+		c.stats.Hits++
+		c.policy.hit(key)
+	} else {
+		c.stats.Misses++
+	}
+	c.mu.Unlock()
+	c.notify(evicted)
+	if !ok {
+		return *new(V), time.Time{}, false
+	}
+	return e.value, e.expires, true
+}
+
+// Peek returns the value without counting a hit or changing the eviction order.
+func (c *Cache[K, V]) Peek(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[key]; ok && !c.expired(e) {
+		return e.value, true
+	}
+	return *new(V), false
+}
+
+// Set stores value with the default TTL.
+func (c *Cache[K, V]) Set(key K, value V) {
+	c.SetWithTTL(key, value, c.opts.TTL)
+}
+
+// SetWithTTL stores value for ttl, 0 means no expiry.
+func (c *Cache[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+	e := &entry[V]{value: value, cost: 1}
+	if c.opts.Cost != nil {
+		e.cost = c.opts.Cost(key, value)
+	}
+	if ttl > 0 {
+		e.expires = c.opts.Now().Add(ttl)
+	}
+
+	c.mu.Lock()
+	var evicted []eviction[K, V]
+	old, exists := c.entries[key]
+	switch {
+	case c.opts.Capacity > 0 && e.cost > c.opts.Capacity:
+		if exists {
+			evicted = append(evicted, c.removeLocked(key, EVICTED_REMOVED))
+		}
+		c.stats.Evictions++
+		evicted = append(evicted, eviction[K, V]{key, value, EVICTED_CAPACITY})
+	case exists:
+		// a replaced entry keeps its place in the policy
+		evicted = append(evicted, eviction[K, V]{key, old.value, EVICTED_REMOVED})
+		c.entries[key] = e
+		c.cost += e.cost - old.cost
+		c.policy.hit(key)
+		evicted = c.shrinkLocked(evicted, key, 0)
+	default:
+		evicted = c.shrinkLocked(evicted, key, e.cost)
+		c.entries[key] = e
+		c.cost += e.cost
+		c.policy.insert(key)
+	}
+	c.mu.Unlock()
+	c.notify(evicted)
+}
+
+func (c *Cache[K, V]) Delete(key K) bool {
+	c.mu.Lock()
+	_, ok := c.entries[key]
+	var evicted []eviction[K, V]
+	if ok {
+		evicted = append(evicted, c.removeLocked(key, EVICTED_REMOVED))
+	}
+	c.mu.Unlock()
+	c.notify(evicted)
+	return ok
+}
+
+// Clear removes all entries, the statistics are kept.
+func (c *Cache[K, V]) Clear() {
+	c.mu.Lock()
+	var evicted []eviction[K, V]
+	for key := range c.entries {
+		evicted = append(evicted, c.removeLocked(key, EVICTED_REMOVED))
+	}
+	c.mu.Unlock()
+	c.notify(evicted)
+}
+
+// PurgeExpired removes all expired entries and returns how many there were.
+func (c *Cache[K, V]) PurgeExpired() int {
+	c.mu.Lock()
+	var evicted []eviction[K, V]
+	for key, e := range c.entries {
+		if c.expired(e) {
+			evicted = append(evicted, c.removeLocked(key, EVICTED_EXPIRED))
+		}
+	}
+	c.mu.Unlock()
+	c.notify(evicted)
+	return len(evicted)
+}
+
+// Len returns the number of entries, including expired ones not yet removed.
+func (c *Cache[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// Cost returns the total cost of the entries.
+func (c *Cache[K, V]) Cost() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cost
+}
+
+func (c *Cache[K, V]) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
+
+func (s Stats) HitRatio() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+// helpers
+
+func (c *Cache[K, V]) expired(e *entry[V]) bool {
+	return !e.expires.IsZero() && !c.opts.Now().Before(e.expires)
+}
+
+func (c *Cache[K, V]) removeLocked(key K, reason EvictionReason) eviction[K, V] {
+	c.policy.remove(key)
+	return c.dropLocked(key, reason)
+}
+
+// dropLocked removes the entry of a key the policy no longer tracks.
+func (c *Cache[K, V]) dropLocked(key K, reason EvictionReason) eviction[K, V] {
+	e := c.entries[key]
+	delete(c.entries, key)
+	c.cost -= e.cost
+	switch reason {
+	case EVICTED_CAPACITY:
+		c.stats.Evictions++
+	case EVICTED_EXPIRED:
+		c.stats.Expirations++
+	}
+	return eviction[K, V]{key, e.value, reason}
+}
+
+// shrinkLocked evicts entries in policy order until there is room for extra more cost for
+// incoming. A victim whose TTL has run out is reported as expired.
+func (c *Cache[K, V]) shrinkLocked(evicted []eviction[K, V], incoming K, extra int64) []eviction[K, V] {
+	limit := c.opts.Capacity - extra
+	if c.opts.Capacity <= 0 || c.cost <= limit {
+		return evicted
+	}
+	for c.cost > limit {
+		key, ok := c.policy.evict(incoming)
+		if !ok {
+			break
+		}
+		reason := EVICTED_CAPACITY
+		if c.expired(c.entries[key]) {
+			reason = EVICTED_EXPIRED
+		}
+		evicted = append(evicted, c.dropLocked(key, reason))
+	}
+	return evicted
+}
+
+func (c *Cache[K, V]) notify(evicted []eviction[K, V]) {
+	if c.opts.OnEvict == nil {
+		return
+	}
+	for _, ev := range evicted {
+		c.opts.OnEvict(ev.key, ev.value, ev.reason)
+	}
+}
@@ -1,13 +1,180 @@
 package cache
 
 import (
+	"errors"
+	"fmt"
+	"sync"
 	"testing"
+	"time"
 
 	. "github.com/jnsoft/jngo/testhelper"
 )
 
-func TestBag(t *testing.T) {
-	t.Run("test", func(t *testing.T) {
-		AssertEqual(t, true, true)
+// fakeNow is a settable clock
+type fakeNow struct {
+	t  time.Time
+	mu sync.Mutex
+}
+
+func (f *fakeNow) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.t
+}
+
+func (f *fakeNow) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.t = f.t.Add(d)
+}
+
+func TestCache(t *testing.T) {
+
+	t.Run("LRU eviction", func(t *testing.T) {
+		var evicted []string
+		c, _ := New(Options[string, int]{Capacity: 2, OnEvict: func(k string, _ int, r EvictionReason) {
+			if r == EVICTED_CAPACITY {
+				evicted = append(evicted, k)
+			}
+		}})
+		c.Set("a", 1)
+		c.Set("b", 2)
+		_, _ = c.Get("a")
+		c.Set("c", 3)
+		_, ok := c.Get("b")
+		AssertFalse(t, ok)
+		v, ok := c.Get("a")
+		AssertTrue(t, ok)
+		AssertEqual(t, v, 1)
+		CollectionAssertEqual(t, evicted, []string{"b"})
+		AssertEqual(t, c.Len(), 2)
+	})
+
+	t.Run("LFU eviction", func(t *testing.T) {
+		c, _ := New(Options[string, int]{Policy: LFU, Capacity: 2})
+		c.Set("a", 1)
+		c.Set("b", 2)
+		for i := 0; i < 3; i++ {
+			_, _ = c.Get("b")
+		}
+		_, _ = c.Get("a")
+		c.Set("c", 3) // a has 2 uses, b 4
+		_, ok := c.Peek("a")
+		AssertFalse(t, ok)
+		c.Set("d", 4) // c has 1 use
+		_, ok = c.Peek("c")
+		AssertFalse(t, ok)
+		_, ok = c.Peek("b")
+		AssertTrue(t, ok)
+	})
+
+	t.Run("Cost based capacity", func(t *testing.T) {
+		c, _ := New(Options[string, string]{Capacity: 10, Cost: func(_ string, v string) int64 { return int64(len(v)) }})
+		c.Set("a", "12345")
+		c.Set("b", "1234")
+		AssertEqual(t, c.Cost(), 9)
+		c.Set("c", "123")
+		AssertEqual(t, c.Cost(), 7)
+		_, ok := c.Peek("a")
+		AssertFalse(t, ok)
+		// too large to ever fit, the other entries stay
+		c.Set("huge", "12345678901")
+		_, ok = c.Peek("huge")
+		AssertFalse(t, ok)
+		AssertEqual(t, c.Cost(), 7)
+	})
+
+	t.Run("TTL expiry", func(t *testing.T) {
+		clock := &fakeNow{t: time.Unix(0, 0)}
+		reasons := map[string]EvictionReason{}
+		c, _ := New(Options[string, int]{TTL: time.Second, Now: clock.Now, OnEvict: func(k string, _ int, r EvictionReason) { reasons[k] = r }})
+		c.Set("a", 1)
+		c.SetWithTTL("b", 2, time.Hour)
+		c.SetWithTTL("c", 3, 0)
+		clock.Advance(2 * time.Second)
+		_, ok := c.Get("a")
+		AssertFalse(t, ok)
+		_, ok = c.Get("b")
+		AssertTrue(t, ok)
+		clock.Advance(time.Hour)
+		AssertEqual(t, c.PurgeExpired(), 1)
+		AssertEqual(t, c.Len(), 1)
+		AssertEqual(t, reasons["a"], EVICTED_EXPIRED)
+		AssertEqual(t, reasons["b"], EVICTED_EXPIRED)
+		AssertEqual(t, c.Stats().Expirations, 2)
+	})
+
+	t.Run("Expired victims are reported as expired", func(t *testing.T) {
+		clock := &fakeNow{t: time.Unix(0, 0)}
+		reasons := map[string]EvictionReason{}
+		c, _ := New(Options[string, int]{Capacity: 2, Now: clock.Now, OnEvict: func(k string, _ int, r EvictionReason) { reasons[k] = r }})
+		c.SetWithTTL("old", 1, time.Second)
+		c.Set("recent", 2)
+		clock.Advance(2 * time.Second)
+		c.Set("new", 3)
+		_, ok := c.Peek("recent")
+		AssertTrue(t, ok)
+		AssertEqual(t, len(reasons), 1)
+		AssertEqual(t, reasons["old"], EVICTED_EXPIRED)
+		AssertEqual(t, c.Stats().Evictions, 0)
+		AssertEqual(t, c.Stats().Expirations, 1)
+	})
+
+	t.Run("Cost is rejected for entry count policies", func(t *testing.T) {
+		for _, p := range []Policy{ARC, W_TINY_LFU} {
+			_, err := New(Options[string, string]{Policy: p, Capacity: 10, Cost: func(_ string, v string) int64 { return int64(len(v)) }})
+			AssertTrue(t, errors.Is(err, ErrCostNotSupported))
+			_, err = NewSharded(ShardedOptions[string, string]{Options: Options[string, string]{Policy: p, Cost: func(string, string) int64 { return 1 }}})
+			AssertTrue(t, errors.Is(err, ErrCostNotSupported))
+		}
+	})
+
+	t.Run("Replace and delete", func(t *testing.T) {
+		var removed []int
+		c, _ := New(Options[string, int]{OnEvict: func(_ string, v int, r EvictionReason) {
+			if r == EVICTED_REMOVED {
+				removed = append(removed, v)
+			}
+		}})
+		c.Set("a", 1)
+		c.Set("a", 2)
+		AssertTrue(t, c.Delete("a"))
+		AssertFalse(t, c.Delete("a"))
+		c.Set("b", 3)
+		c.Clear()
+		CollectionAssertEqual(t, removed, []int{1, 2, 3})
+	})
+
+	t.Run("Stats", func(t *testing.T) {
+		c, _ := New(Options[int, int]{})
+		c.Set(1, 1)
+		_, _ = c.Get(1)
+		_, _ = c.Get(1)
+		_, _ = c.Get(2)
+		s := c.Stats()
+		AssertEqual(t, s.Hits, 2)
+		AssertEqual(t, s.Misses, 1)
+		AssertEqual(t, s.HitRatio(), 2.0/3)
+	})
+
+	t.Run("Concurrent use", func(t *testing.T) {
+		for _, p := range []Policy{LRU, LFU} {
+			c, _ := New(Options[string, int]{Policy: p, Capacity: 50})
+			var wg sync.WaitGroup
+			for g := 0; g < 8; g++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for i := 0; i < 1000; i++ {
+						key := fmt.Sprint((g * i) % 200)
+						if _, ok := c.Get(key); !ok {
+							c.Set(key, i)
+						}
+					}
+				}()
+			}
+			wg.Wait()
+			AssertTrue(t, c.Len() <= 50)
+		}
 	})
 }
@@ -0,0 +1,141 @@
+package cache
+
+import "container/list"
+
+// policy tracks the keys of a cache and picks the next one to evict. Callers hold the cache lock.
+type policy[K comparable] interface {
+	insert(key K) // a new entry was stored
+	hit(key K)    // an entry was read or replaced
+	remove(key K) // an entry was deleted or expired
+	// evict chooses an entry to make room for incoming, which is not stored yet, and forgets it
+	evict(incoming K) (K, bool)
+}
+
+func newPolicy[K comparable](p Policy, capacity int64) policy[K] {
+	switch p {
+	case LFU:
+		return newLFU[K]()
+	case ARC:
+		return newARC[K](int(capacity))
+	case W_TINY_LFU:
+		return newTinyLFU[K](int(capacity))
+	default:
+		return newLRU[K]()
+	}
+}
+
+// --- LRU ---
+
+type lruPolicy[K comparable] struct {
+	order *list.List // front = most recently used
+	nodes map[K]*list.Element
+}
+
+func newLRU[K comparable]() *lruPolicy[K] {
+	return &lruPolicy[K]{order: list.New(), nodes: make(map[K]*list.Element)}
+}
+
+func (p *lruPolicy[K]) insert(key K) {
+	p.nodes[key] = p.order.PushFront(key)
+}
+
+func (p *lruPolicy[K]) hit(key K) {
+	if e, ok := p.nodes[key]; ok {
+		p.order.MoveToFront(e)
+	}
+}
+
+func (p *lruPolicy[K]) remove(key K) {
+	if e, ok := p.nodes[key]; ok {
+		p.order.Remove(e)
+		delete(p.nodes, key)
+	}
+}
+
+func (p *lruPolicy[K]) evict(K) (K, bool) {
+	e := p.order.Back()
+	if e == nil {
+		return *new(K), false
+	}
+	key := e.Value.(K)
+	p.remove(key)
+	return key, true
+}
+
+func (p *lruPolicy[K]) has(key K) bool {
+	_, ok := p.nodes[key]
+	return ok
+}
+
+func (p *lruPolicy[K]) len() int {
+	return len(p.nodes)
+}
+
+// --- LFU ---
+
+// lfuPolicy keeps one list per use count, so every operation is O(1). Ties are broken by recency.
+type lfuPolicy[K comparable] struct {
+	buckets map[int]*list.List // use count -> keys, front = most recent
+	nodes   map[K]*list.Element
+	counts  map[K]int
+	minimum int
+}
+
+func newLFU[K comparable]() *lfuPolicy[K] {
+	return &lfuPolicy[K]{buckets: make(map[int]*list.List), nodes: make(map[K]*list.Element), counts: make(map[K]int)}
+}
+
+func (p *lfuPolicy[K]) insert(key K) {
+	p.push(key, 1)
+	p.minimum = 1
+}
+
+func (p *lfuPolicy[K]) hit(key K) {
+	n, ok := p.counts[key]
+	if !ok {
+		return
+	}
+	p.unlink(key, n)
+	if n == p.minimum && p.buckets[n] == nil {
+		p.minimum++
+	}
+	p.push(key, n+1)
+}
+
+func (p *lfuPolicy[K]) remove(key K) {
+	if n, ok := p.counts[key]; ok {
+		p.unlink(key, n)
+		delete(p.counts, key)
+		delete(p.nodes, key)
+	}
+}
+
+func (p *lfuPolicy[K]) evict(K) (K, bool) {
+	if len(p.nodes) == 0 {
+		return *new(K), false
+	}
+	for p.buckets[p.minimum] == nil { // after a remove the minimum can be stale
+		p.minimum++
+	}
+	key := p.buckets[p.minimum].Back().Value.(K)
+	p.remove(key)
+	return key, true
+}
+
+func (p *lfuPolicy[K]) push(key K, n int) {
+	b := p.buckets[n]
+	if b == nil {
+		b = list.New()
+		p.buckets[n] = b
+	}
+	p.nodes[key] = b.PushFront(key)
+	p.counts[key] = n
+}
+
+func (p *lfuPolicy[K]) unlink(key K, n int) {
+	b := p.buckets[n]
+	b.Remove(p.nodes[key])
+	if b.Len() == 0 {
+		delete(p.buckets, n)
+	}
+}
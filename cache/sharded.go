@@ -0,0 +1,210 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jnsoft/jngo/misc"
+)
+
+const (
+	DEFAULT_SHARDS = 16
+)
+
+// ShardedOptions configures a ShardedCache. Capacity is the total for all shards.
+type ShardedOptions[K comparable, V any] struct {
+	Options[K, V]
+	Shards       int           // defaults to DEFAULT_SHARDS
+	Hash         func(K) int   // defaults to misc.HashKey of the key formatted as a string
+	RefreshAhead time.Duration // GetOrLoad reloads an entry in the background once it is this close to expiry
+}
+
+// ShardedCache spreads keys over independently locked caches so that concurrent callers rarely
+// contend, and deduplicates concurrent loads of the same key.
+type ShardedCache[K comparable, V any] struct {
+	shards       []*Cache[K, V]
+	hash         func(K) int
+	now          func() time.Time
+	refreshAhead time.Duration
+	calls        map[K]*call[V]
+	mu           sync.Mutex // guards calls
+}
+
+// call is a load in progress. It is canceled when every caller waiting for it has given up.
+type call[V any] struct {
+	done    chan struct{}
+	value   V
+	err     error
+	cancel  context.CancelFunc
+	waiters int
+}
+
+func NewSharded[K comparable, V any](opts ShardedOptions[K, V]) (*ShardedCache[K, V], error) {
+	shards := opts.Shards
+	if shards <= 0 {
+		shards = DEFAULT_SHARDS
+	}
+	hash := opts.Hash
+	if hash == nil {
+		hash = defaultHash[K]
+	}
+	per := opts.Options
+	if per.Capacity > 0 {
+		per.Capacity = max((per.Capacity+int64(shards)-1)/int64(shards), 1)
+	}
+	sc := &ShardedCache[K, V]{shards: make([]*Cache[K, V], shards), hash: hash, refreshAhead: opts.RefreshAhead, calls: make(map[K]*call[V])}
+	for i := range sc.shards {
+		c, err := New(per)
+		if err != nil {
+			return nil, err
+		}
+		sc.shards[i] = c
+	}
+	sc.now = sc.shards[0].opts.Now
+	return sc, nil
+}
+
+func (sc *ShardedCache[K, V]) Get(key K) (V, bool) {
+	return sc.shard(key).Get(key)
+}
+
+func (sc *ShardedCache[K, V]) Set(key K, value V) {
+	sc.shard(key).Set(key, value)
+}
+
+func (sc *ShardedCache[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+	sc.shard(key).SetWithTTL(key, value, ttl)
+}
+
+func (sc *ShardedCache[K, V]) Delete(key K) bool {
+	return sc.shard(key).Delete(key)
+}
+
+// GetOrLoad returns the cached value of key, or calls loader and caches its result.
+// Concurrent calls for the same key share one loader call. If ctx is done first, GetOrLoad
+// returns ctx.Err(); the load is canceled only when no caller is waiting for it any more.
+// Loader errors are returned and not cached.
+func (sc *ShardedCache[K, V]) GetOrLoad(ctx context.Context, key K, loader func(context.Context, K) (V, error)) (V, error) {
+	shard := sc.shard(key)
+	if v, expires, ok := shard.get(key); ok {
+		if sc.refreshAhead > 0 && !expires.IsZero() && !sc.now().Before(expires.Add(-sc.refreshAhead)) {
+			// the refresh holds its own waiter until it finishes, so it is not canceled with ctx
+			c := sc.join(key, loader, context.WithoutCancel(ctx))
+			go func() {
+				<-c.done
+				sc.leave(key, c)
+			}()
+		}
+		return v, nil
+	}
+
+	c := sc.join(key, loader, context.WithoutCancel(ctx))
+	select {
+	case <-c.done:
+		return c.value, c.err
+	case <-ctx.Done():
+		sc.leave(key, c)
+		return *new(V), ctx.Err()
+	}
+}
+
+func (sc *ShardedCache[K, V]) Len() int {
+	n := 0
+	for _, s := range sc.shards {
+		n += s.Len()
+	}
+	return n
+}
+
+func (sc *ShardedCache[K, V]) Clear() {
+	for _, s := range sc.shards {
+		s.Clear()
+	}
+}
+
+func (sc *ShardedCache[K, V]) PurgeExpired() int {
+	n := 0
+	for _, s := range sc.shards {
+		n += s.PurgeExpired()
+	}
+	return n
+}
+
+// Stats sums the statistics of all shards.
+func (sc *ShardedCache[K, V]) Stats() Stats {
+	var total Stats
+	for _, s := range sc.shards {
+		st := s.Stats()
+		total.Hits += st.Hits
+		total.Misses += st.Misses
+		total.Evictions += st.Evictions
+		total.Expirations += st.Expirations
+	}
+	return total
+}
+
+// helpers
+
+func (sc *ShardedCache[K, V]) shard(key K) *Cache[K, V] {
+	return sc.shards[uint(sc.hash(key))%uint(len(sc.shards))]
+}
+
+// join returns the load in progress for key, starting one if there is none.
+func (sc *ShardedCache[K, V]) join(key K, loader func(context.Context, K) (V, error), parent context.Context) *call[V] {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	if c, ok := sc.calls[key]; ok {
+		c.waiters++
+		return c
+	}
+	ctx, cancel := context.WithCancel(parent)
+	c := &call[V]{done: make(chan struct{}), cancel: cancel, waiters: 1}
+	sc.calls[key] = c
+	go func() {
+		defer cancel()
+		c.value, c.err = safeLoad(ctx, key, loader)
+		if c.err == nil {
+			sc.shard(key).Set(key, c.value)
+		}
+		sc.mu.Lock()
+		if sc.calls[key] == c {
+			delete(sc.calls, key)
+		}
+		sc.mu.Unlock()
+		close(c.done)
+	}()
+	return c
+}
+
+// leave cancels the load when its last waiter gives up, and forgets it so that the next caller
+// starts a fresh load instead of joining the canceled one.
+func (sc *ShardedCache[K, V]) leave(key K, c *call[V]) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	c.waiters--
+	if c.waiters == 0 {
+		c.cancel()
+		if sc.calls[key] == c {
+			delete(sc.calls, key)
+		}
+	}
+}
+
+// safeLoad turns a panic in loader into an error, so the waiters are released.
+func safeLoad[K comparable, V any](ctx context.Context, key K, loader func(context.Context, K) (V, error)) (v V, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("cache: loader panicked: %v", r)
+		}
+	}()
+	return loader(ctx, key)
+}
+
+func defaultHash[K comparable](key K) int {
+	if s, ok := any(key).(string); ok {
+		return misc.HashKey(s)
+	}
+	return misc.HashKey(fmt.Sprint(key))
+}
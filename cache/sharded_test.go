@@ -0,0 +1,165 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/jnsoft/jngo/testhelper"
+)
+
+var errLoad = errors.New("load failed")
+
+func TestShardedCache(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Keys spread over shards", func(t *testing.T) {
+		sc, _ := NewSharded(ShardedOptions[int, int]{Shards: 4, Options: Options[int, int]{Capacity: 400}})
+		for i := 0; i < 100; i++ {
+			sc.Set(i, i*i)
+		}
+		AssertEqual(t, sc.Len(), 100)
+		for _, s := range sc.shards {
+			AssertTrue(t, s.Len() > 0)
+		}
+		v, ok := sc.Get(9)
+		AssertTrue(t, ok)
+		AssertEqual(t, v, 81)
+		AssertTrue(t, sc.Delete(9))
+		_, ok = sc.Get(9)
+		AssertFalse(t, ok)
+		AssertEqual(t, sc.Stats().Misses, 1)
+	})
+
+	t.Run("Concurrent loads are deduplicated", func(t *testing.T) {
+		sc, _ := NewSharded(ShardedOptions[string, string]{})
+		var loads atomic.Int32
+		release := make(chan struct{})
+		loader := func(_ context.Context, k string) (string, error) {
+			loads.Add(1)
+			<-release
+			return "v-" + k, nil
+		}
+		var wg sync.WaitGroup
+		results := make([]string, 20)
+		for i := range results {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				results[i], _ = sc.GetOrLoad(ctx, "k", loader)
+			}()
+		}
+		time.Sleep(20 * time.Millisecond)
+		close(release)
+		wg.Wait()
+		AssertEqual(t, loads.Load(), 1)
+		for _, r := range results {
+			AssertEqual(t, r, "v-k")
+		}
+		v, ok := sc.Get("k")
+		AssertTrue(t, ok)
+		AssertEqual(t, v, "v-k")
+	})
+
+	t.Run("Errors are not cached", func(t *testing.T) {
+		sc, _ := NewSharded(ShardedOptions[string, int]{})
+		_, err := sc.GetOrLoad(ctx, "k", func(context.Context, string) (int, error) { return 0, errLoad })
+		AssertTrue(t, errors.Is(err, errLoad))
+		v, err := sc.GetOrLoad(ctx, "k", func(context.Context, string) (int, error) { return 7, nil })
+		AssertNil(t, err)
+		AssertEqual(t, v, 7)
+	})
+
+	t.Run("Cancellation", func(t *testing.T) {
+		sc, _ := NewSharded(ShardedOptions[string, int]{})
+		canceled := make(chan struct{})
+		loader := func(ctx context.Context, _ string) (int, error) {
+			<-ctx.Done()
+			close(canceled)
+			return 0, ctx.Err()
+		}
+		short, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+		_, err := sc.GetOrLoad(short, "k", loader)
+		AssertTrue(t, errors.Is(err, context.DeadlineExceeded))
+		select {
+		case <-canceled:
+		case <-time.After(time.Second):
+			t.Fatal("load was not canceled after the last caller left")
+		}
+	})
+
+	t.Run("Load again after every waiter canceled", func(t *testing.T) {
+		sc, _ := NewSharded(ShardedOptions[string, int]{})
+		release := make(chan struct{})
+		slow := func(ctx context.Context, _ string) (int, error) {
+			<-release // ignores ctx, so the canceled load is still running below
+			return 1, nil
+		}
+		short, cancel := context.WithCancel(ctx)
+		cancel()
+		_, err := sc.GetOrLoad(short, "k", slow)
+		AssertTrue(t, errors.Is(err, context.Canceled))
+
+		v, err := sc.GetOrLoad(ctx, "k", func(context.Context, string) (int, error) { return 2, nil })
+		AssertNil(t, err)
+		AssertEqual(t, v, 2)
+		close(release)
+	})
+
+	t.Run("Loader panics are returned as errors", func(t *testing.T) {
+		sc, _ := NewSharded(ShardedOptions[string, int]{})
+		_, err := sc.GetOrLoad(ctx, "k", func(context.Context, string) (int, error) { panic("boom") })
+		AssertNotEqual(t, err, nil)
+		_, ok := sc.Get("k")
+		AssertFalse(t, ok)
+	})
+
+	t.Run("Negative hashes", func(t *testing.T) {
+		sc, _ := NewSharded(ShardedOptions[int, int]{Shards: 3, Hash: func(k int) int { return k }})
+		for _, k := range []int{math.MinInt, -7, -1, 0, 5} {
+			sc.Set(k, k)
+			v, ok := sc.Get(k)
+			AssertTrue(t, ok)
+			AssertEqual(t, v, k)
+		}
+	})
+
+	t.Run("Refresh ahead of expiry", func(t *testing.T) {
+		clock := &fakeNow{t: time.Unix(0, 0)}
+		sc, _ := NewSharded(ShardedOptions[string, string]{
+			Options:      Options[string, string]{TTL: 10 * time.Second, Now: clock.Now},
+			RefreshAhead: 2 * time.Second,
+		})
+		var version atomic.Int32
+		loader := func(_ context.Context, _ string) (string, error) {
+			return fmt.Sprint("v", version.Add(1)), nil
+		}
+		v, _ := sc.GetOrLoad(ctx, "k", loader)
+		AssertEqual(t, v, "v1")
+
+		clock.Advance(5 * time.Second)
+		v, _ = sc.GetOrLoad(ctx, "k", loader)
+		AssertEqual(t, v, "v1")
+		AssertEqual(t, version.Load(), 1)
+
+		clock.Advance(4 * time.Second)
+		v, _ = sc.GetOrLoad(ctx, "k", loader) // stale value is served while refreshing
+		AssertEqual(t, v, "v1")
+		deadline := time.Now().Add(time.Second)
+		for {
+			if v, _ := sc.Get("k"); v == "v2" {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatal("entry was not refreshed")
+			}
+			time.Sleep(time.Millisecond)
+		}
+	})
+}
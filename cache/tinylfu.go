@@ -0,0 +1,183 @@
+package cache
+
+import (
+	"hash/maphash"
+	"math/bits"
+)
+
+const (
+	SKETCH_DEPTH   = 4
+	SKETCH_MAX     = 15 // counters saturate like 4-bit counters
+	WINDOW_PERCENT = 1  // share of the capacity for the admission window
+)
+
+// tinyLFUPolicy is Window TinyLFU (Einziger, Friedman, Manes). New keys enter a small LRU window.
+// A key pushed out of the window competes with the victim of the main segmented LRU and the one
+// with the lower estimated frequency is evicted. Frequencies come from a count-min sketch behind a
+// doorkeeper that absorbs keys seen only once, both are halved periodically so old popularity fades.
+type tinyLFUPolicy[K comparable] struct {
+	window    *lruPolicy[K]
+	probation *lruPolicy[K]
+	protected *lruPolicy[K]
+	windowCap int
+	mainCap   int
+	protCap   int
+	sketch    *countMinSketch[K]
+}
+
+type countMinSketch[K comparable] struct {
+	seed       maphash.Seed
+	rows       [SKETCH_DEPTH][]uint8
+	mask       uint64
+	doorkeeper []uint64 // bit set
+	additions  int
+	resetAt    int
+}
+
+func newTinyLFU[K comparable](capacity int) *tinyLFUPolicy[K] {
+	capacity = max(capacity, 1)
+	windowCap := max(capacity*WINDOW_PERCENT/100, 1)
+	mainCap := max(capacity-windowCap, 1)
+	return &tinyLFUPolicy[K]{
+		window:    newLRU[K](),
+		probation: newLRU[K](),
+		protected: newLRU[K](),
+		windowCap: windowCap,
+		mainCap:   mainCap,
+		protCap:   max(mainCap*8/10, 1),
+		sketch:    newCountMinSketch[K](capacity),
+	}
+}
+
+func (t *tinyLFUPolicy[K]) insert(key K) {
+	t.sketch.add(key)
+	t.window.insert(key)
+	// while the cache fills up, keys leaving the window go straight to the main cache
+	for t.window.len() > t.windowCap {
+		moved, _ := t.window.evict(key)
+		t.probation.insert(moved)
+	}
+}
+
+func (t *tinyLFUPolicy[K]) hit(key K) {
+	t.sketch.add(key)
+	switch {
+	case t.window.has(key):
+		t.window.hit(key)
+	case t.probation.has(key):
+		t.probation.remove(key)
+		t.protected.insert(key)
+		if t.protected.len() > t.protCap {
+			demoted, _ := t.protected.evict(key)
+			t.probation.insert(demoted)
+		}
+	default:
+		t.protected.hit(key)
+	}
+}
+
+func (t *tinyLFUPolicy[K]) remove(key K) {
+	t.window.remove(key)
+	t.probation.remove(key)
+	t.protected.remove(key)
+}
+
+func (t *tinyLFUPolicy[K]) evict(incoming K) (K, bool) {
+	// the window is full, its oldest key becomes a candidate for the main cache
+	if t.window.len() >= t.windowCap && t.window.len() > 0 {
+		candidate, _ := t.window.evict(incoming)
+		if t.probation.len()+t.protected.len() < t.mainCap {
+			t.probation.insert(candidate)
+			return t.evictMain(incoming)
+		}
+		victim, ok := t.mainVictim()
+		if !ok || t.sketch.estimate(candidate) <= t.sketch.estimate(victim) {
+			return candidate, true
+		}
+		t.remove(victim)
+		t.probation.insert(candidate)
+		return victim, true
+	}
+	return t.evictMain(incoming)
+}
+
+func (t *tinyLFUPolicy[K]) mainVictim() (K, bool) {
+	if e := t.probation.order.Back(); e != nil {
+		return e.Value.(K), true
+	}
+	if e := t.protected.order.Back(); e != nil {
+		return e.Value.(K), true
+	}
+	return *new(K), false
+}
+
+func (t *tinyLFUPolicy[K]) evictMain(incoming K) (K, bool) {
+	if key, ok := t.probation.evict(incoming); ok {
+		return key, true
+	}
+	if key, ok := t.protected.evict(incoming); ok {
+		return key, true
+	}
+	return t.window.evict(incoming)
+}
+
+// --- Count-min sketch ---
+
+func newCountMinSketch[K comparable](capacity int) *countMinSketch[K] {
+	width := max(1<<bits.Len(uint(capacity)), 16)
+	s := &countMinSketch[K]{
+		seed:       maphash.MakeSeed(),
+		mask:       uint64(width - 1),
+		doorkeeper: make([]uint64, (width+63)/64),
+		resetAt:    10 * width,
+	}
+	for i := range s.rows {
+		s.rows[i] = make([]uint8, width)
+	}
+	return s
+}
+
+func (s *countMinSketch[K]) add(key K) {
+	h := maphash.Comparable(s.seed, key)
+	if bit := h & s.mask; s.doorkeeper[bit/64]&(1<<(bit%64)) == 0 {
+		s.doorkeeper[bit/64] |= 1 << (bit % 64)
+	} else {
+		for i := range s.rows {
+			if c := &s.rows[i][s.index(h, i)]; *c < SKETCH_MAX {
+				*c++
+			}
+		}
+	}
+	s.additions++
+	if s.additions >= s.resetAt {
+		s.age()
+	}
+}
+
+// estimate is the smallest counter of key, plus one if the doorkeeper has seen it.
+func (s *countMinSketch[K]) estimate(key K) int {
+	h := maphash.Comparable(s.seed, key)
+	least := SKETCH_MAX
+	for i := range s.rows {
+		least = min(least, int(s.rows[i][s.index(h, i)]))
+	}
+	if bit := h & s.mask; s.doorkeeper[bit/64]&(1<<(bit%64)) != 0 {
+		least++
+	}
+	return least
+}
+
+func (s *countMinSketch[K]) index(h uint64, row int) uint64 {
+	h2 := h>>32 | h<<32
+	return (h + uint64(row+1)*h2) & s.mask
+}
+
+func (s *countMinSketch[K]) age() {
+	for i := range s.rows {
+		for j := range s.rows[i] {
+			s.rows[i][j] /= 2
+		}
+	}
+	clear(s.doorkeeper)
+	s.additions /= 2
+}
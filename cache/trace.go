@@ -0,0 +1,56 @@
+package cache
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ReplayResult is the outcome of replaying a key trace against one policy.
+type ReplayResult struct {
+	Policy Policy
+	Stats  Stats
+}
+
+func (p Policy) String() string {
+	switch p {
+	case LRU:
+		return "LRU"
+	case LFU:
+		return "LFU"
+	case ARC:
+		return "ARC"
+	case W_TINY_LFU:
+		return "W-TinyLFU"
+	default:
+		return fmt.Sprintf("Policy(%d)", int(p))
+	}
+}
+
+// ReplayTrace runs keys through a read-through cache holding capacity entries, once per policy.
+func ReplayTrace[K comparable](keys []K, capacity int64, policies ...Policy) []ReplayResult {
+	results := make([]ReplayResult, len(policies))
+	for i, p := range policies {
+		c, _ := New(Options[K, struct{}]{Policy: p, Capacity: capacity})
+		for _, key := range keys {
+			if _, ok := c.Get(key); !ok {
+				c.Set(key, struct{}{})
+			}
+		}
+		results[i] = ReplayResult{Policy: p, Stats: c.Stats()}
+	}
+	return results
+}
+
+// LoadTrace reads a recorded trace with one key per line. Blank lines are skipped.
+func LoadTrace(r io.Reader) ([]string, error) {
+	var keys []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if key := strings.TrimSpace(scanner.Text()); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys, scanner.Err()
+}
@@ -0,0 +1,124 @@
+package cache
+
+import (
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	. "github.com/jnsoft/jngo/testhelper"
+)
+
+var allPolicies = []Policy{LRU, LFU, ARC, W_TINY_LFU}
+
+// scanTrace mixes a Zipf-distributed hot set with long sequential scans of keys used only once.
+func scanTrace(n int) []uint64 {
+	r := rand.New(rand.NewSource(1))
+	zipf := rand.NewZipf(r, 1.1, 1, 10_000)
+	keys := make([]uint64, 0, n)
+	next := uint64(1 << 32)
+	for len(keys) < n {
+		if r.Intn(1000) == 0 {
+			for i := 0; i < 500 && len(keys) < n; i++ {
+				keys = append(keys, next)
+				next++
+			}
+			continue
+		}
+		keys = append(keys, zipf.Uint64())
+	}
+	return keys
+}
+
+func TestPolicies(t *testing.T) {
+
+	t.Run("Every policy respects capacity", func(t *testing.T) {
+		for _, p := range allPolicies {
+			c, _ := New(Options[uint64, struct{}]{Policy: p, Capacity: 100})
+			for _, key := range scanTrace(20_000) {
+				if _, ok := c.Get(key); !ok {
+					c.Set(key, struct{}{})
+				}
+				if c.Len() > 100 {
+					t.Fatalf("%v: %d entries", p, c.Len())
+				}
+			}
+			c.Delete(1)
+			c.Clear()
+			AssertEqual(t, c.Len(), 0)
+		}
+	})
+
+	t.Run("ARC and W-TinyLFU resist scans", func(t *testing.T) {
+		results := ReplayTrace(scanTrace(200_000), 500, allPolicies...)
+		ratio := make(map[Policy]float64)
+		for _, r := range results {
+			ratio[r.Policy] = r.Stats.HitRatio()
+			t.Logf("%-10v hit ratio %.3f", r.Policy, r.Stats.HitRatio())
+		}
+		AssertTrue(t, ratio[ARC] > ratio[LRU])
+		AssertTrue(t, ratio[W_TINY_LFU] > ratio[LRU])
+	})
+
+	t.Run("ARC adapts to recency", func(t *testing.T) {
+		// a loop slightly larger than the cache defeats LRU but not ARC's frequency list
+		c, _ := New(Options[int, int]{Policy: ARC, Capacity: 4})
+		for round := 0; round < 10; round++ {
+			for k := 0; k < 3; k++ {
+				if _, ok := c.Get(k); !ok {
+					c.Set(k, k)
+				}
+			}
+			c.Set(100+round, 0)
+		}
+		for k := 0; k < 3; k++ {
+			_, ok := c.Peek(k)
+			AssertTrue(t, ok)
+		}
+	})
+
+	t.Run("Load trace", func(t *testing.T) {
+		keys, err := LoadTrace(strings.NewReader("a\n\n b\na\n"))
+		AssertNil(t, err)
+		CollectionAssertEqual(t, keys, []string{"a", "b", "a"})
+		AssertEqual(t, W_TINY_LFU.String(), "W-TinyLFU")
+	})
+}
+
+// BenchmarkTraceReplay reports the hit ratio of each policy. Set CACHE_TRACE to a file with one key
+// per line to replay a recorded trace instead of the synthetic one. The cache holds 5% of the
+// distinct keys.
+func BenchmarkTraceReplay(b *testing.B) {
+	var keys []string
+	if path := os.Getenv("CACHE_TRACE"); path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			b.Fatal(err)
+		}
+		keys, err = LoadTrace(f)
+		f.Close()
+		if err != nil {
+			b.Fatal(err)
+		}
+	} else {
+		for _, k := range scanTrace(100_000) {
+			keys = append(keys, strconv.FormatUint(k, 10))
+		}
+	}
+	distinct := make(map[string]bool)
+	for _, k := range keys {
+		distinct[k] = true
+	}
+	capacity := int64(max(len(distinct)/20, 1))
+
+	for _, p := range allPolicies {
+		b.Run(p.String(), func(b *testing.B) {
+			var result ReplayResult
+			for i := 0; i < b.N; i++ {
+				result = ReplayTrace(keys, capacity, p)[0]
+			}
+			b.ReportMetric(result.Stats.HitRatio()*100, "hit%")
+		})
+	}
+}
@@ -0,0 +1,254 @@
+package genericgraph
+
+import (
+	"iter"
+	"slices"
+
+	"github.com/jnsoft/jngo/graph"
+)
+
+type (
+	// Graph has vertices of any comparable type and a value of type E on every edge, e.g. a
+	// struct of edge attributes. There is at most one edge from u to v, adding it again replaces
+	// the value. Vertices and edges are iterated in insertion order.
+	Graph[V comparable, E any] struct {
+		vertices   map[V]*vertex[V, E]
+		order      []V    // vertices in insertion order, with holes left by removed vertices
+		removed    []bool // removed[i] is set once order[i] has been removed
+		holes      int    // number of removed entries in order, compacted when they are the majority
+		e          int
+		isDirected bool
+	}
+
+	Edge[V comparable, E any] struct {
+		From  V
+		To    V
+		Value E
+	}
+
+	vertex[V comparable, E any] struct {
+		out   []V // neighbours in insertion order
+		value map[V]E
+		in    []V // predecessors in a directed graph, to remove a vertex without a full scan
+		pos   int // index in order
+	}
+)
+
+func New[V comparable, E any](is_directed bool) *Graph[V, E] {
+	return &Graph[V, E]{vertices: make(map[V]*vertex[V, E]), isDirected: is_directed}
+}
+
+func (g *Graph[V, E]) IsDirected() bool {
+	return g.isDirected
+}
+
+// number of vertices
+func (g *Graph[V, E]) NumVertices() int {
+	return len(g.vertices)
+}
+
+// number of edges, an undirected edge counts once
+func (g *Graph[V, E]) NumEdges() int {
+	return g.e
+}
+
+// AddVertex adds v and reports whether it was new.
+func (g *Graph[V, E]) AddVertex(v V) bool {
+	if _, ok := g.vertices[v]; ok {
+		return false
+	}
+	g.vertices[v] = &vertex[V, E]{value: make(map[V]E), pos: len(g.order)}
+	g.order = append(g.order, v)
+	g.removed = append(g.removed, false)
+	return true
+}
+
+func (g *Graph[V, E]) HasVertex(v V) bool {
+	_, ok := g.vertices[v]
+	return ok
+}
+
+// RemoveVertex removes v and its edges and reports whether it existed.
+func (g *Graph[V, E]) RemoveVertex(v V) bool {
+	x, ok := g.vertices[v]
+	if !ok {
+		return false
+	}
+	for _, w := range slices.Clone(x.out) {
+		g.RemoveEdge(v, w)
+	}
+	for _, u := range slices.Clone(x.in) {
+		g.RemoveEdge(u, v)
+	}
+	delete(g.vertices, v)
+	g.removed[x.pos] = true
+	g.holes++
+	if g.holes > len(g.order)/2 {
+		g.compact()
+	}
+	return true
+}
+
+// compact drops the removed entries from order, keeping the insertion order of the rest. It builds
+// new slices, so an iteration over the old ones is not disturbed.
+func (g *Graph[V, E]) compact() {
+	order := make([]V, 0, len(g.vertices))
+	for i, v := range g.order {
+		if !g.removed[i] {
+			g.vertices[v].pos = len(order)
+			order = append(order, v)
+		}
+	}
+	g.order, g.removed, g.holes = order, make([]bool, len(order)), 0
+}
+
+// AddEdge adds an edge from u to v with the given value, adding the vertices if needed. It
+// replaces the value of an existing edge and reports whether the edge was new.
+func (g *Graph[V, E]) AddEdge(u, v V, value E) bool {
+	g.AddVertex(u)
+	g.AddVertex(v)
+	x, y := g.vertices[u], g.vertices[v]
+	_, exists := x.value[v]
+	x.value[v] = value
+	if !g.isDirected {
+		y.value[u] = value
+	}
+	if exists {
+		return false
+	}
+	x.out = append(x.out, v)
+	if g.isDirected {
+		y.in = append(y.in, u)
+	} else if u != v {
+		y.out = append(y.out, u)
+	}
+	g.e++
+	return true
+}
+
+// Edge returns the value of the edge from u to v.
+func (g *Graph[V, E]) Edge(u, v V) (E, bool) {
+	if x, ok := g.vertices[u]; ok {
+		value, ok := x.value[v]
+		return value, ok
+	}
+	var zero E
+	return zero, false
+}
+
+func (g *Graph[V, E]) HasEdge(u, v V) bool {
+	_, ok := g.Edge(u, v)
+	return ok
+}
+
+// RemoveEdge removes the edge from u to v and reports whether it existed.
+func (g *Graph[V, E]) RemoveEdge(u, v V) bool {
+	if !g.HasEdge(u, v) {
+		return false
+	}
+	x, y := g.vertices[u], g.vertices[v]
+	delete(x.value, v)
+	x.out = remove(x.out, v)
+	if g.isDirected {
+		y.in = remove(y.in, u)
+	} else if u != v {
+		delete(y.value, u)
+		y.out = remove(y.out, u)
+	}
+	g.e--
+	return true
+}
+
+// Degree returns the number of edges leaving v.
+func (g *Graph[V, E]) Degree(v V) int {
+	if x, ok := g.vertices[v]; ok {
+		return len(x.out)
+	}
+	return 0
+}
+
+// Vertices iterates over the vertices in insertion order.
+func (g *Graph[V, E]) Vertices() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		order, removed := g.order, g.removed
+		for i, v := range order {
+			if removed[i] {
+				continue
+			}
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// Neighbors iterates over the vertices adjacent to v, the successors in a directed graph.
+func (g *Graph[V, E]) Neighbors(v V) iter.Seq[V] {
+	return func(yield func(V) bool) {
+		x, ok := g.vertices[v]
+		if !ok {
+			return
+		}
+		for _, w := range x.out {
+			if !yield(w) {
+				return
+			}
+		}
+	}
+}
+
+// OutEdges iterates over the neighbours of v with the value of the edge to each.
+func (g *Graph[V, E]) OutEdges(v V) iter.Seq2[V, E] {
+	return func(yield func(V, E) bool) {
+		x, ok := g.vertices[v]
+		if !ok {
+			return
+		}
+		for _, w := range x.out {
+			if !yield(w, x.value[w]) {
+				return
+			}
+		}
+	}
+}
+
+// Edges iterates over every edge once.
+func (g *Graph[V, E]) Edges() iter.Seq[Edge[V, E]] {
+	return func(yield func(Edge[V, E]) bool) {
+		seen := make(map[V]bool) // undirected edges are yielded from the first of their vertices
+		for v := range g.Vertices() {
+			x := g.vertices[v]
+			for _, w := range x.out {
+				if !g.isDirected && seen[w] {
+					continue
+				}
+				if !yield(Edge[V, E]{From: v, To: w, Value: x.value[w]}) {
+					return
+				}
+			}
+			seen[v] = true
+		}
+	}
+}
+
+// Indexed numbers the vertices in insertion order and returns the equivalent graph.Graph, so the
+// int algorithms of the graph package can be used, and the vertex for every number.
+func (g *Graph[V, E]) Indexed() (*graph.Graph, []V) {
+	vertices := slices.Collect(g.Vertices())
+	index := make(map[V]int, len(vertices))
+	for i, v := range vertices {
+		index[v] = i
+	}
+	ig, _ := graph.NewGraph(len(vertices), g.isDirected)
+	for e := range g.Edges() {
+		_ = ig.AddEdge(index[e.From], index[e.To])
+	}
+	return ig, vertices
+}
+
+func remove[V comparable](vs []V, v V) []V {
+	if i := slices.Index(vs, v); i >= 0 {
+		return slices.Delete(vs, i, i+1)
+	}
+	return vs
+}
@@ -0,0 +1,252 @@
+package genericgraph
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/jnsoft/jngo/graph"
+	. "github.com/jnsoft/jngo/testhelper"
+)
+
+type (
+	service struct {
+		Name   string
+		Region string
+	}
+
+	link struct {
+		LatencyMs float64
+		Protocol  string
+	}
+)
+
+const tinyEWD = `8
+15
+4 5 0.35
+5 4 0.35
+4 7 0.37
+5 7 0.28
+7 5 0.28
+5 1 0.32
+0 4 0.38
+0 2 0.26
+7 3 0.39
+1 3 0.29
+2 7 0.34
+6 2 0.40
+3 6 0.52
+6 0 0.58
+6 4 0.93
+`
+
+func TestGraph(t *testing.T) {
+	web := service{"web", "eu"}
+	api := service{"api", "eu"}
+	db := service{"db", "us"}
+	cache := service{"cache", "eu"}
+
+	t.Run("Vertices and edges", func(t *testing.T) {
+		g := New[service, link](true)
+		AssertTrue(t, g.AddEdge(web, api, link{5, "http"}))
+		AssertTrue(t, g.AddEdge(api, db, link{80, "sql"}))
+		AssertTrue(t, g.AddEdge(api, cache, link{1, "redis"}))
+		AssertFalse(t, g.AddEdge(api, db, link{70, "sql"}))
+		AssertFalse(t, g.AddVertex(db))
+		AssertEqual(t, g.NumVertices(), 4)
+		AssertEqual(t, g.NumEdges(), 3)
+
+		l, ok := g.Edge(api, db)
+		AssertTrue(t, ok)
+		AssertEqual(t, l.LatencyMs, 70.0)
+		AssertFalse(t, g.HasEdge(db, api))
+		CollectionAssertEqual(t, slices.Collect(g.Neighbors(api)), []service{db, cache})
+		CollectionAssertEqual(t, slices.Collect(g.Vertices()), []service{web, api, db, cache})
+		AssertEqual(t, g.Degree(api), 2)
+
+		protocols := []string{}
+		for _, l := range g.OutEdges(api) {
+			protocols = append(protocols, l.Protocol)
+		}
+		CollectionAssertEqual(t, protocols, []string{"sql", "redis"})
+
+		AssertTrue(t, g.RemoveVertex(api))
+		AssertFalse(t, g.RemoveVertex(api))
+		AssertEqual(t, g.NumEdges(), 0)
+		AssertEqual(t, g.Degree(web), 0)
+		CollectionAssertEqual(t, slices.Collect(g.Vertices()), []service{web, db, cache})
+		AssertTrue(t, g.AddVertex(api))
+		CollectionAssertEqual(t, slices.Collect(g.Vertices()), []service{web, db, cache, api})
+	})
+
+	t.Run("Undirected", func(t *testing.T) {
+		g := New[string, int](false)
+		g.AddEdge("a", "b", 1)
+		g.AddEdge("b", "c", 2)
+		g.AddEdge("c", "c", 3)
+		AssertFalse(t, g.AddEdge("b", "a", 4))
+		v, _ := g.Edge("a", "b")
+		AssertEqual(t, v, 4)
+		AssertEqual(t, g.NumEdges(), 3)
+		AssertEqual(t, len(slices.Collect(g.Edges())), 3)
+		CollectionAssertEqual(t, slices.Collect(g.Neighbors("b")), []string{"a", "c"})
+
+		AssertTrue(t, g.RemoveEdge("c", "b"))
+		AssertFalse(t, g.HasEdge("b", "c"))
+		AssertTrue(t, g.RemoveVertex("c"))
+		AssertEqual(t, g.NumEdges(), 1)
+	})
+
+	t.Run("Removed vertices keep the insertion order", func(t *testing.T) {
+		g := New[int, struct{}](true)
+		for v := 0; v < 100; v++ {
+			g.AddEdge(v, (v+1)%100, struct{}{})
+		}
+		var want []int
+		for v := 0; v < 100; v++ {
+			if v%3 == 0 {
+				AssertTrue(t, g.RemoveVertex(v))
+			} else {
+				want = append(want, v)
+			}
+		}
+		CollectionAssertEqual(t, slices.Collect(g.Vertices()), want)
+		for v := 1; v < 100; v += 3 {
+			g.RemoveVertex(v)
+		}
+		AssertTrue(t, g.AddVertex(0))
+		want = want[:0]
+		for v := 2; v < 100; v += 3 {
+			want = append(want, v)
+		}
+		CollectionAssertEqual(t, slices.Collect(g.Vertices()), append(want, 0))
+		AssertEqual(t, g.NumVertices(), len(want)+1)
+		AssertEqual(t, g.NumEdges(), 0)
+	})
+
+	t.Run("Indexed", func(t *testing.T) {
+		g := New[string, struct{}](true)
+		g.AddEdge("a", "b", struct{}{})
+		g.AddEdge("b", "a", struct{}{})
+		g.AddEdge("b", "c", struct{}{})
+		ig, vertices := g.Indexed()
+		CollectionAssertEqual(t, vertices, []string{"a", "b", "c"})
+		scc, _ := ig.TarjanSCC()
+		AssertEqual(t, scc.Count(), 2)
+	})
+}
+
+func TestSearch(t *testing.T) {
+
+	t.Run("Generic graph", func(t *testing.T) {
+		g := New[string, float64](false)
+		g.AddEdge("a", "b", 1)
+		g.AddEdge("b", "c", 1)
+		g.AddEdge("a", "c", 5)
+		g.AddEdge("c", "d", 1)
+		g.AddVertex("e")
+
+		bfs := BreadthFirstPaths(g, "a")
+		AssertEqual(t, bfs.DistTo("d"), 2)
+		CollectionAssertEqual(t, bfs.PathTo("d"), []string{"a", "c", "d"})
+		AssertFalse(t, bfs.HasPathTo("e"))
+		AssertEqual(t, bfs.DistTo("e"), -1)
+
+		dfs := DepthFirstPaths(g, "a")
+		CollectionAssertEqual(t, dfs.PathTo("d"), []string{"a", "b", "c", "d"})
+		AssertTrue(t, dfs.PathTo("e") == nil)
+
+		sp, err := Dijkstra(Weighted(g, func(w float64) float64 { return w }), "a")
+		AssertNil(t, err)
+		AssertEqual(t, sp.DistTo("d"), 3.0)
+		CollectionAssertEqual(t, sp.PathTo("d"), []string{"a", "b", "c", "d"})
+		AssertTrue(t, math.IsInf(sp.DistTo("e"), 1))
+
+		g.AddEdge("d", "e", -1)
+		_, err = Dijkstra(Weighted(g, func(w float64) float64 { return w }), "a")
+		AssertTrue(t, errors.Is(err, graph.ErrNegativeWeight))
+	})
+
+	t.Run("Int graphs agree with the graph package", func(t *testing.T) {
+		g, _ := graph.NewGraph(6, false)
+		for _, e := range [][2]int{{0, 5}, {2, 4}, {2, 3}, {1, 2}, {0, 1}, {3, 4}, {3, 5}, {0, 2}} {
+			_ = g.AddEdge(e[0], e[1])
+		}
+		want := g.BreadthFirstPaths(0)
+		bfs := BreadthFirstPaths(FromGraph(g), 0)
+		dfs := DepthFirstPaths(FromGraph(g), 0)
+		wantDFS := g.DepthFirstPaths(0)
+		for v := 0; v < g.V(); v++ {
+			AssertEqual(t, bfs.DistTo(v), want.DistTo(v))
+			CollectionAssertEqual(t, bfs.PathTo(v), want.PathTo(v))
+			CollectionAssertEqual(t, dfs.PathTo(v), wantDFS.PathTo(v))
+		}
+
+		ewd, _ := graph.NewEdgeWeightedGraphFromString(tinyEWD, true)
+		wantSP, _ := ewd.Dijkstra(0)
+		sp, err := Dijkstra(FromEdgeWeightedGraph(ewd), 0)
+		AssertNil(t, err)
+		for v := 0; v < ewd.V(); v++ {
+			AssertTrue(t, math.Abs(sp.DistTo(v)-wantSP.DistTo(v)) < 1e-9)
+			CollectionAssertEqual(t, sp.PathTo(v), wantSP.PathTo(v))
+		}
+		AssertEqual(t, BreadthFirstPaths(FromEdgeWeightedGraph(ewd), 0).DistTo(6), 4)
+
+		// a source out of range has no neighbours
+		outside := BreadthFirstPaths(FromGraph(g), 6)
+		AssertTrue(t, outside.HasPathTo(6))
+		AssertFalse(t, outside.HasPathTo(0))
+		sp, err = Dijkstra(FromEdgeWeightedGraph(ewd), -1)
+		AssertNil(t, err)
+		AssertFalse(t, sp.HasPathTo(0))
+	})
+
+	t.Run("A* on a grid of structs", func(t *testing.T) {
+		type cell struct{ r, c int }
+		n := 15
+		g := New[cell, float64](false)
+		for r := 0; r < n; r++ {
+			for c := 0; c < n; c++ {
+				if c+1 < n && !(c == 7 && r < n-1) { // wall with a gap at the bottom
+					g.AddEdge(cell{r, c}, cell{r, c + 1}, 1)
+				}
+				if r+1 < n {
+					g.AddEdge(cell{r, c}, cell{r + 1, c}, 1)
+				}
+			}
+		}
+		target := cell{0, n - 1}
+		manhattan := func(v cell) float64 { return math.Abs(float64(v.r-target.r)) + math.Abs(float64(v.c-target.c)) }
+		weights := Weighted(g, func(w float64) float64 { return w })
+		astar, err := AStar(weights, cell{0, 0}, target, manhattan)
+		AssertNil(t, err)
+		dijkstra, _ := Dijkstra(weights, cell{0, 0})
+		AssertEqual(t, astar.DistTo(target), dijkstra.DistTo(target))
+		AssertEqual(t, astar.DistTo(target), float64(2*(n-1)+n-1))
+		AssertEqual(t, len(astar.PathTo(target)), int(astar.DistTo(target))+1)
+	})
+
+	t.Run("A* with an inconsistent heuristic", func(t *testing.T) {
+		// b is settled through the direct edge before the shorter path through a is found
+		g := New[string, float64](true)
+		g.AddEdge("s", "a", 1)
+		g.AddEdge("a", "b", 1)
+		g.AddEdge("s", "b", 3)
+		g.AddEdge("b", "t", 10)
+		h := map[string]float64{"s": 12, "a": 11, "b": 0, "t": 0} // never overestimates
+		sp, err := AStar(Weighted(g, func(w float64) float64 { return w }), "s", "t", func(v string) float64 { return h[v] })
+		AssertNil(t, err)
+		AssertEqual(t, sp.DistTo("t"), 12.0)
+		CollectionAssertEqual(t, sp.PathTo("t"), []string{"s", "a", "b", "t"})
+	})
+
+	t.Run("No stack overflow on long paths", func(t *testing.T) {
+		n := 200_000
+		g := New[int, struct{}](true)
+		for v := 0; v+1 < n; v++ {
+			g.AddEdge(v, v+1, struct{}{})
+		}
+		AssertEqual(t, len(DepthFirstPaths(g, 0).PathTo(n-1)), n)
+	})
+}
@@ -0,0 +1,286 @@
+package genericgraph
+
+import (
+	"iter"
+	"math"
+	"slices"
+
+	"github.com/jnsoft/jngo/graph"
+	"github.com/jnsoft/jngo/pqueue"
+	"github.com/jnsoft/jngo/queue"
+	"github.com/jnsoft/jngo/stack"
+)
+
+// The searches below work on anything that can list the neighbours of a vertex: a generic Graph
+// directly, a graph.Graph or graph.EdgeWeightedGraph through FromGraph and FromEdgeWeightedGraph.
+
+type (
+	// Traversable lists the vertices reachable from v over one edge.
+	Traversable[V comparable] interface {
+		Neighbors(v V) iter.Seq[V]
+	}
+
+	// WeightedTraversable also lists the weights of the edges.
+	WeightedTraversable[V comparable] interface {
+		Traversable[V]
+		WeightedNeighbors(v V) iter.Seq2[V, float64]
+	}
+
+	// Paths holds a search tree from a source vertex.
+	Paths[V comparable] struct {
+		edgeTo map[V]V   // edgeTo[v] = previous vertex on the source-v path, the source is its own
+		distTo map[V]int // distTo[v] = number of edges on the source-v path
+		Source V
+	}
+
+	// ShortestPaths holds a weighted shortest path tree from a source vertex.
+	ShortestPaths[V comparable] struct {
+		edgeTo map[V]V
+		distTo map[V]float64
+		Source V
+	}
+
+	weighted[V comparable, E any] struct {
+		g      *Graph[V, E]
+		weight func(E) float64
+	}
+
+	intGraph struct {
+		g *graph.Graph
+	}
+
+	intWeightedGraph struct {
+		g *graph.EdgeWeightedGraph
+	}
+
+	dfsFrame[V comparable] struct {
+		v         V
+		neighbors []V
+	}
+)
+
+// Weighted views g as a weighted graph, taking the weight of each edge from its value.
+func Weighted[V comparable, E any](g *Graph[V, E], weight func(E) float64) WeightedTraversable[V] {
+	return weighted[V, E]{g: g, weight: weight}
+}
+
+// FromGraph adapts a graph.Graph to the generic searches. A vertex outside 0..V-1 has no
+// neighbours, so a search from it finds only the source instead of failing like the graph package
+// does. Check the source against V first when it comes from outside.
+func FromGraph(g *graph.Graph) Traversable[int] {
+	return intGraph{g: g}
+}
+
+// FromEdgeWeightedGraph adapts a graph.EdgeWeightedGraph to the generic searches. As with
+// FromGraph, a vertex outside 0..V-1 has no neighbours.
+func FromEdgeWeightedGraph(g *graph.EdgeWeightedGraph) WeightedTraversable[int] {
+	return intWeightedGraph{g: g}
+}
+
+func (w weighted[V, E]) Neighbors(v V) iter.Seq[V] {
+	return w.g.Neighbors(v)
+}
+
+func (w weighted[V, E]) WeightedNeighbors(v V) iter.Seq2[V, float64] {
+	return func(yield func(V, float64) bool) {
+		for u, e := range w.g.OutEdges(v) {
+			if !yield(u, w.weight(e)) {
+				return
+			}
+		}
+	}
+}
+
+func (ig intGraph) Neighbors(v int) iter.Seq[int] {
+	adj, _ := ig.g.Adj(v) // nil for a vertex out of range
+	return slices.Values(adj)
+}
+
+func (ig intWeightedGraph) Neighbors(v int) iter.Seq[int] {
+	return func(yield func(int) bool) {
+		for w := range ig.WeightedNeighbors(v) {
+			if !yield(w) {
+				return
+			}
+		}
+	}
+}
+
+func (ig intWeightedGraph) WeightedNeighbors(v int) iter.Seq2[int, float64] {
+	return func(yield func(int, float64) bool) {
+		adj, _ := ig.g.Adj(v) // nil for a vertex out of range
+		for _, e := range adj {
+			if !yield(e.Other(v), e.Weight) {
+				return
+			}
+		}
+	}
+}
+
+// --- Breadth-first and depth-first search ---
+
+// BreadthFirstPaths finds the paths with the fewest edges from source.
+func BreadthFirstPaths[V comparable](g Traversable[V], source V) *Paths[V] {
+	p := newPaths(source)
+	q := queue.New[V]()
+	q.Enqueue(source)
+	for !q.IsEmpty() {
+		v := q.Dequeue()
+		for w := range g.Neighbors(v) {
+			if !p.HasPathTo(w) {
+				p.edgeTo[w] = v
+				p.distTo[w] = p.distTo[v] + 1
+				q.Enqueue(w)
+			}
+		}
+	}
+	return p
+}
+
+// DepthFirstPaths finds paths from source by depth-first search. It keeps its own stack, so it
+// works on long paths, and visits vertices in the same order as the recursive version.
+func DepthFirstPaths[V comparable](g Traversable[V], source V) *Paths[V] {
+	p := newPaths(source)
+	frames := []dfsFrame[V]{{v: source, neighbors: slices.Collect(g.Neighbors(source))}}
+	for len(frames) > 0 {
+		top := &frames[len(frames)-1]
+		if len(top.neighbors) == 0 {
+			frames = frames[:len(frames)-1]
+			continue
+		}
+		v, w := top.v, top.neighbors[0]
+		top.neighbors = top.neighbors[1:]
+		if p.HasPathTo(w) {
+			continue
+		}
+		p.edgeTo[w] = v
+		p.distTo[w] = p.distTo[v] + 1
+		frames = append(frames, dfsFrame[V]{v: w, neighbors: slices.Collect(g.Neighbors(w))})
+	}
+	return p
+}
+
+func newPaths[V comparable](source V) *Paths[V] {
+	return &Paths[V]{
+		Source: source,
+		edgeTo: map[V]V{source: source},
+		distTo: map[V]int{source: 0},
+	}
+}
+
+// is there a path from the source to v?
+func (p *Paths[V]) HasPathTo(v V) bool {
+	_, ok := p.distTo[v]
+	return ok
+}
+
+// number of edges on the path from the source to v, -1 if there is none
+func (p *Paths[V]) DistTo(v V) int {
+	if d, ok := p.distTo[v]; ok {
+		return d
+	}
+	return -1
+}
+
+// vertices on the path from the source to v
+func (p *Paths[V]) PathTo(v V) []V {
+	if !p.HasPathTo(v) {
+		return nil
+	}
+	return pathTo(p.edgeTo, p.Source, v)
+}
+
+// --- Shortest paths ---
+
+// Dijkstra computes shortest paths from source. It returns graph.ErrNegativeWeight if it reaches
+// an edge with a negative weight.
+func Dijkstra[V comparable](g WeightedTraversable[V], source V) (*ShortestPaths[V], error) {
+	return search(g, source, nil, nil)
+}
+
+// AStar computes a shortest path from source to target, guided by heuristic(v), an estimate of
+// the distance from v to target that must never exceed the true distance. Only paths to vertices
+// settled before target are final.
+func AStar[V comparable](g WeightedTraversable[V], source, target V, heuristic func(v V) float64) (*ShortestPaths[V], error) {
+	return search(g, source, &target, heuristic)
+}
+
+type searchItem[V comparable] struct {
+	v        V
+	priority float64
+}
+
+// search is Dijkstra, or A* when heuristic is set, stopping at target if it is not nil. Without
+// an indexed queue for arbitrary vertices, stale queue entries are skipped when dequeued. A vertex
+// whose distance improves after it was settled, possible with an inconsistent heuristic, is
+// reopened.
+func search[V comparable](g WeightedTraversable[V], source V, target *V, heuristic func(V) float64) (*ShortestPaths[V], error) {
+	sp := &ShortestPaths[V]{
+		Source: source,
+		edgeTo: map[V]V{source: source},
+		distTo: map[V]float64{source: 0},
+	}
+	priority := func(v V) float64 {
+		if heuristic == nil {
+			return sp.distTo[v]
+		}
+		return sp.distTo[v] + heuristic(v)
+	}
+	done := make(map[V]bool)
+	pq := pqueue.NewPriorityQueue(func(a, b searchItem[V]) bool { return a.priority < b.priority })
+	pq.Enqueue(searchItem[V]{source, priority(source)})
+	for !pq.IsEmpty() {
+		item, _ := pq.Dequeue()
+		v := item.v
+		if done[v] {
+			continue
+		}
+		done[v] = true
+		if target != nil && v == *target {
+			break
+		}
+		for w, weight := range g.WeightedNeighbors(v) {
+			if weight < 0 {
+				return nil, graph.ErrNegativeWeight
+			}
+			if d, ok := sp.distTo[w]; !ok || sp.distTo[v]+weight < d {
+				sp.distTo[w] = sp.distTo[v] + weight
+				sp.edgeTo[w] = v
+				delete(done, w)
+				pq.Enqueue(searchItem[V]{w, priority(w)})
+			}
+		}
+	}
+	return sp, nil
+}
+
+// is there a path from the source to v?
+func (sp *ShortestPaths[V]) HasPathTo(v V) bool {
+	_, ok := sp.distTo[v]
+	return ok
+}
+
+// length of the shortest path from the source to v, +Inf if there is none
+func (sp *ShortestPaths[V]) DistTo(v V) float64 {
+	if d, ok := sp.distTo[v]; ok {
+		return d
+	}
+	return math.Inf(1)
+}
+
+// vertices on the shortest path from the source to v
+func (sp *ShortestPaths[V]) PathTo(v V) []V {
+	if !sp.HasPathTo(v) {
+		return nil
+	}
+	return pathTo(sp.edgeTo, sp.Source, v)
+}
+
+func pathTo[V comparable](edgeTo map[V]V, source, v V) []V {
+	path := stack.New[V]()
+	for ; v != source; v = edgeTo[v] {
+		path.Push(v)
+	}
+	path.Push(source)
+	return path.ToArray()
+}
@@ -0,0 +1,282 @@
+package graph
+
+import "slices"
+
+type (
+	StronglyConnectedComponents struct {
+		id    []int // id[v] = component of v
+		count int
+	}
+
+	// TransitiveClosure answers reachability queries between any two vertices of a digraph.
+	TransitiveClosure struct {
+		scc       *StronglyConnectedComponents
+		reachable [][]uint64 // reachable[c] = bit set of components reachable from component c
+	}
+
+	// Biconnectivity describes the bridges, articulation points and biconnected components of an undirected graph.
+	Biconnectivity struct {
+		bridges      [][2]int
+		articulation []bool
+		components   [][]int
+	}
+)
+
+// --- Strongly connected components ---
+
+// KosarajuSharir finds the strongly connected components with two depth-first searches, the
+// second visiting the vertices in reverse postorder of the reversed digraph.
+func (g *Graph) KosarajuSharir() (*StronglyConnectedComponents, error) {
+	if !g.isDirected {
+		return nil, ErrUndirected
+	}
+	scc := &StronglyConnectedComponents{id: make([]int, g.v)}
+	marked := make([]bool, g.v)
+	for _, s := range g.Reverse().DepthFirstOrder().ReversePost() {
+		if marked[s] {
+			continue
+		}
+		g.dfs(s, marked, dfsVisitor{enter: func(v int) { scc.id[v] = scc.count }})
+		scc.count++
+	}
+	return scc, nil
+}
+
+// TarjanSCC finds the strongly connected components with a single depth-first search.
+// Components are numbered in reverse topological order of the condensation.
+func (g *Graph) TarjanSCC() (*StronglyConnectedComponents, error) {
+	if !g.isDirected {
+		return nil, ErrUndirected
+	}
+	scc := &StronglyConnectedComponents{id: make([]int, g.v)}
+	marked := make([]bool, g.v)
+	pre := make([]int, g.v)
+	low := make([]int, g.v)
+	onStack := make([]bool, g.v)
+	var open []int
+	counter := 0
+	for s := 0; s < g.v; s++ {
+		if marked[s] {
+			continue
+		}
+		g.dfs(s, marked, dfsVisitor{
+			enter: func(v int) {
+				pre[v], low[v] = counter, counter
+				counter++
+				open = append(open, v)
+				onStack[v] = true
+			},
+			back: func(v, w int) {
+				if onStack[w] {
+					low[v] = min(low[v], pre[w])
+				}
+			},
+			leave: func(v, parent int) {
+				if parent != -1 {
+					defer func() { low[parent] = min(low[parent], low[v]) }()
+				}
+				if low[v] != pre[v] {
+					return
+				}
+				for {
+					w := open[len(open)-1]
+					open = open[:len(open)-1]
+					onStack[w] = false
+					scc.id[w] = scc.count
+					if w == v {
+						break
+					}
+				}
+				scc.count++
+			},
+		})
+	}
+	return scc, nil
+}
+
+// number of components
+func (scc *StronglyConnectedComponents) Count() int {
+	return scc.count
+}
+
+// component of v, 0..Count()-1
+func (scc *StronglyConnectedComponents) Id(v int) int {
+	return scc.id[v]
+}
+
+func (scc *StronglyConnectedComponents) StronglyConnected(v, w int) bool {
+	return scc.id[v] == scc.id[w]
+}
+
+// Components returns the vertices of every component.
+func (scc *StronglyConnectedComponents) Components() [][]int {
+	comps := make([][]int, scc.count)
+	for v, c := range scc.id {
+		comps[c] = append(comps[c], v)
+	}
+	return comps
+}
+
+// Condensation returns the DAG with one vertex per component of scc and an edge between two
+// components if any of their vertices are connected in g.
+func (g *Graph) Condensation(scc *StronglyConnectedComponents) *Graph {
+	dag, _ := NewGraph(scc.count, true)
+	seen := make(map[[2]int]bool)
+	for v := 0; v < g.v; v++ {
+		for _, w := range g.adjacencyList[v] {
+			edge := [2]int{scc.id[v], scc.id[w]}
+			if edge[0] != edge[1] && !seen[edge] {
+				seen[edge] = true
+				_ = dag.AddEdge(edge[0], edge[1])
+			}
+		}
+	}
+	return dag
+}
+
+// --- Transitive closure ---
+
+// TransitiveClosure computes reachability on the condensation, so the cost depends on the number
+// of strongly connected components rather than vertices.
+func (g *Graph) TransitiveClosure() (*TransitiveClosure, error) {
+	scc, err := g.TarjanSCC()
+	if err != nil {
+		return nil, err
+	}
+	dag := g.Condensation(scc)
+	words := (scc.count + 63) / 64
+	tc := &TransitiveClosure{scc: scc, reachable: make([][]uint64, scc.count)}
+	// Tarjan numbers components so that edges go from higher to lower ids
+	for c := 0; c < scc.count; c++ {
+		set := make([]uint64, words)
+		set[c/64] |= 1 << (c % 64)
+		for _, d := range dag.adjacencyList[c] {
+			for i, word := range tc.reachable[d] {
+				set[i] |= word
+			}
+		}
+		tc.reachable[c] = set
+	}
+	return tc, nil
+}
+
+// is there a directed path from v to w?
+func (tc *TransitiveClosure) Reachable(v, w int) bool {
+	c, d := tc.scc.id[v], tc.scc.id[w]
+	return tc.reachable[c][d/64]&(1<<(d%64)) != 0
+}
+
+// --- Bridges, articulation points and biconnected components ---
+
+// Biconnectivity finds the bridges, articulation points and biconnected components of an undirected graph.
+func (g *Graph) Biconnectivity() (*Biconnectivity, error) {
+	if g.isDirected {
+		return nil, ErrDirected
+	}
+	b := &Biconnectivity{articulation: make([]bool, g.v)}
+	marked := make([]bool, g.v)
+	pre := make([]int, g.v)
+	low := make([]int, g.v)
+	parentSkipped := make([]bool, g.v) // the tree edge back to the parent is ignored once, parallel edges count
+	parent := make([]int, g.v)
+	children := 0 // tree children of the current root
+	var edges [][2]int
+	counter := 0
+
+	for s := 0; s < g.v; s++ {
+		if marked[s] {
+			continue
+		}
+		children = 0
+		parent[s] = -1
+		g.dfs(s, marked, dfsVisitor{
+			enter: func(v int) {
+				pre[v], low[v] = counter, counter
+				counter++
+			},
+			tree: func(v, w int) {
+				parent[w] = v
+				if v == s {
+					children++
+				}
+				edges = append(edges, [2]int{v, w})
+			},
+			back: func(v, w int) {
+				if w == parent[v] && !parentSkipped[v] {
+					parentSkipped[v] = true
+					return
+				}
+				if pre[w] < pre[v] {
+					low[v] = min(low[v], pre[w])
+					edges = append(edges, [2]int{v, w})
+				}
+			},
+			leave: func(w, v int) {
+				if v == -1 {
+					return
+				}
+				low[v] = min(low[v], low[w])
+				if low[w] > pre[v] {
+					b.bridges = append(b.bridges, [2]int{v, w})
+				}
+				if low[w] >= pre[v] {
+					if v != s {
+						b.articulation[v] = true
+					}
+					b.components = append(b.components, popComponent(&edges, [2]int{v, w}))
+				}
+			},
+		})
+		if children > 1 {
+			b.articulation[s] = true
+		}
+	}
+	return b, nil
+}
+
+// Bridges returns the edges whose removal disconnects the graph, as vertex pairs.
+func (b *Biconnectivity) Bridges() [][2]int {
+	return b.bridges
+}
+
+// ArticulationPoints returns the vertices whose removal disconnects the graph, in increasing order.
+func (b *Biconnectivity) ArticulationPoints() []int {
+	var points []int
+	for v, ok := range b.articulation {
+		if ok {
+			points = append(points, v)
+		}
+	}
+	return points
+}
+
+func (b *Biconnectivity) IsArticulationPoint(v int) bool {
+	return b.articulation[v]
+}
+
+// Components returns the vertices of every biconnected component, each sorted. Articulation
+// points belong to more than one component, isolated vertices to none.
+func (b *Biconnectivity) Components() [][]int {
+	return b.components
+}
+
+// popComponent pops the edges down to and including last and returns their vertices.
+func popComponent(edges *[][2]int, last [2]int) []int {
+	seen := make(map[int]bool)
+	var vertices []int
+	for {
+		e := (*edges)[len(*edges)-1]
+		*edges = (*edges)[:len(*edges)-1]
+		for _, v := range e {
+			if !seen[v] {
+				seen[v] = true
+				vertices = append(vertices, v)
+			}
+		}
+		if e == last {
+			break
+		}
+	}
+	slices.Sort(vertices)
+	return vertices
+}
@@ -0,0 +1,112 @@
+package graph
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	. "github.com/jnsoft/jngo/testhelper"
+)
+
+func TestConnectivity(t *testing.T) {
+
+	t.Run("Strongly connected components", func(t *testing.T) {
+		dg, _ := NewGraphFromString(tinyDG, true)
+		kosaraju, err := dg.KosarajuSharir()
+		AssertNil(t, err)
+		tarjan, err := dg.TarjanSCC()
+		AssertNil(t, err)
+		for _, scc := range []*StronglyConnectedComponents{kosaraju, tarjan} {
+			AssertEqual(t, scc.Count(), 5)
+			AssertTrue(t, scc.StronglyConnected(0, 4))
+			AssertTrue(t, scc.StronglyConnected(9, 12))
+			AssertTrue(t, scc.StronglyConnected(6, 8))
+			AssertFalse(t, scc.StronglyConnected(0, 1))
+			AssertFalse(t, scc.StronglyConnected(6, 7))
+			CollectionAssertEqual(t, scc.Components()[scc.Id(2)], []int{0, 2, 3, 4, 5})
+		}
+		for v := 0; v < dg.V(); v++ {
+			for w := 0; w < dg.V(); w++ {
+				AssertEqual(t, kosaraju.StronglyConnected(v, w), tarjan.StronglyConnected(v, w))
+			}
+		}
+
+		g, _ := fromEdgeList(tinyG, false)
+		_, err = g.KosarajuSharir()
+		AssertTrue(t, errors.Is(err, ErrUndirected))
+		_, err = g.TarjanSCC()
+		AssertTrue(t, errors.Is(err, ErrUndirected))
+	})
+
+	t.Run("Condensation", func(t *testing.T) {
+		dg, _ := NewGraphFromString(tinyDG, true)
+		scc, _ := dg.TarjanSCC()
+		dag := dg.Condensation(scc)
+		AssertEqual(t, dag.V(), 5)
+		cycle, _ := dag.DirectedCycle()
+		AssertTrue(t, cycle == nil)
+		adj, _ := dag.Adj(scc.Id(7))
+		AssertEqual(t, len(adj), 2)
+		AssertTrue(t, contains(adj, scc.Id(6)))
+		AssertTrue(t, contains(adj, scc.Id(9)))
+	})
+
+	t.Run("Transitive closure", func(t *testing.T) {
+		dg, _ := NewGraphFromString(tinyDG, true)
+		tc, err := dg.TransitiveClosure()
+		AssertNil(t, err)
+		for v := 0; v < dg.V(); v++ {
+			dfp := dg.DepthFirstPaths(v)
+			for w := 0; w < dg.V(); w++ {
+				AssertEqual(t, tc.Reachable(v, w), dfp.HasPathTo(w))
+			}
+		}
+		AssertTrue(t, tc.Reachable(7, 1))
+		AssertFalse(t, tc.Reachable(1, 0))
+	})
+
+	t.Run("Bridges and articulation points", func(t *testing.T) {
+		g, _ := fromEdgeList(tinyG, false)
+		b, err := g.Biconnectivity()
+		AssertNil(t, err)
+		bridges := b.Bridges()
+		for i, e := range bridges {
+			bridges[i] = [2]int{min(e[0], e[1]), max(e[0], e[1])}
+		}
+		slices.SortFunc(bridges, func(a, b [2]int) int { return a[0]*100 + a[1] - b[0]*100 - b[1] })
+		CollectionAssertEqual(t, bridges, [][2]int{{0, 1}, {0, 2}, {7, 8}, {9, 10}})
+		CollectionAssertEqual(t, b.ArticulationPoints(), []int{0, 9})
+		AssertTrue(t, b.IsArticulationPoint(9))
+		AssertFalse(t, b.IsArticulationPoint(12))
+
+		comps := b.Components()
+		AssertEqual(t, len(comps), 6)
+		AssertTrue(t, slices.ContainsFunc(comps, func(c []int) bool { return slices.Equal(c, []int{0, 3, 4, 5, 6}) }))
+		AssertTrue(t, slices.ContainsFunc(comps, func(c []int) bool { return slices.Equal(c, []int{9, 11, 12}) }))
+
+		dg, _ := NewGraphFromString(tinyDG, true)
+		_, err = dg.Biconnectivity()
+		AssertTrue(t, errors.Is(err, ErrDirected))
+	})
+
+	t.Run("Parallel edges are not bridges", func(t *testing.T) {
+		g, _ := NewGraph(3, false)
+		_ = g.AddEdge(0, 1)
+		_ = g.AddEdge(0, 1)
+		_ = g.AddEdge(1, 2)
+		b, _ := g.Biconnectivity()
+		CollectionAssertEqual(t, b.Bridges(), [][2]int{{1, 2}})
+		CollectionAssertEqual(t, b.ArticulationPoints(), []int{1})
+		AssertEqual(t, len(b.Components()), 2)
+	})
+
+	t.Run("No stack overflow on long cycles", func(t *testing.T) {
+		n := 1_000_000
+		g, _ := NewGraph(n, true)
+		for v := 0; v < n; v++ {
+			_ = g.AddEdge(v, (v+1)%n)
+		}
+		scc, _ := g.TarjanSCC()
+		AssertEqual(t, scc.Count(), 1)
+	})
+}
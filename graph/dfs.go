@@ -0,0 +1,324 @@
+package graph
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jnsoft/jngo/stack"
+)
+
+// The depth-first searches below keep their own stack instead of recursing, so they work on graphs
+// with millions of vertices. They visit vertices and edges in the same order as the recursive versions.
+
+type (
+	DepthFirstPaths struct {
+		marked []bool // marked[v] = true if v connects to s
+		edgeTo []int  // edgeTo[v] = previous vertex on the s-v path
+		Source int
+	}
+
+	ConnectedComponents struct {
+		id    []int // id[v] = component of v
+		size  []int // size[c] = number of vertices in component c
+		count int
+	}
+
+	DepthFirstOrder struct {
+		pre  []int
+		post []int
+	}
+
+	Bipartite struct {
+		color       []bool
+		oddCycle    []int
+		isBipartite bool
+	}
+
+	// CycleError is returned when an operation needs an acyclic digraph.
+	CycleError struct {
+		Cycle []int
+	}
+
+	// dfsVisitor receives the events of an iterative depth-first search, nil callbacks are skipped
+	dfsVisitor struct {
+		enter func(v int)         // v is reached for the first time
+		tree  func(v, w int)      // w is reached for the first time through v-w
+		back  func(v, w int)      // v-w leads to a vertex that was already reached
+		leave func(v, parent int) // all edges of v are done, parent is -1 for the start vertex
+	}
+
+	dfsFrame struct {
+		v, parent, next int
+	}
+)
+
+var (
+	ErrUndirected = errors.New("operation requires a directed graph")
+	ErrDirected   = errors.New("operation requires an undirected graph")
+)
+
+func (e *CycleError) Error() string {
+	return fmt.Sprintf("graph has a directed cycle: %v", e.Cycle)
+}
+
+// dfs searches from s, skipping vertices that are already marked.
+func (g *Graph) dfs(s int, marked []bool, vis dfsVisitor) {
+	marked[s] = true
+	if vis.enter != nil {
+		vis.enter(s)
+	}
+	frames := []dfsFrame{{v: s, parent: -1}}
+	for len(frames) > 0 {
+		top := &frames[len(frames)-1]
+		adj := g.adjacencyList[top.v]
+		if top.next == len(adj) {
+			frames = frames[:len(frames)-1]
+			if vis.leave != nil {
+				vis.leave(top.v, top.parent)
+			}
+			continue
+		}
+		v, w := top.v, adj[top.next]
+		top.next++
+		if marked[w] {
+			if vis.back != nil {
+				vis.back(v, w)
+			}
+			continue
+		}
+		marked[w] = true
+		if vis.tree != nil {
+			vis.tree(v, w)
+		}
+		if vis.enter != nil {
+			vis.enter(w)
+		}
+		frames = append(frames, dfsFrame{v: w, parent: v})
+	}
+}
+
+// --- Paths ---
+
+func (g *Graph) DepthFirstPaths(source int) *DepthFirstPaths {
+	dfp := &DepthFirstPaths{
+		Source: source,
+		marked: make([]bool, g.v),
+		edgeTo: make([]int, g.v),
+	}
+	g.dfs(source, dfp.marked, dfsVisitor{tree: func(v, w int) { dfp.edgeTo[w] = v }})
+	return dfp
+}
+
+// is there a path s to v?
+func (dfp *DepthFirstPaths) HasPathTo(v int) bool {
+	return dfp.marked[v]
+}
+
+// a path s to v, not necessarily the shortest
+func (dfp *DepthFirstPaths) PathTo(v int) []int {
+	if !dfp.HasPathTo(v) {
+		return nil
+	}
+	path := stack.New[int]()
+	for i := v; i != dfp.Source; i = dfp.edgeTo[i] {
+		path.Push(i)
+	}
+	path.Push(dfp.Source)
+	return path.ToArray()
+}
+
+// --- Connected components ---
+
+func (g *Graph) ConnectedComponents() (*ConnectedComponents, error) {
+	if g.isDirected {
+		return nil, ErrDirected
+	}
+	cc := &ConnectedComponents{id: make([]int, g.v)}
+	marked := make([]bool, g.v)
+	for s := 0; s < g.v; s++ {
+		if marked[s] {
+			continue
+		}
+		cc.size = append(cc.size, 0)
+		g.dfs(s, marked, dfsVisitor{enter: func(v int) {
+			cc.id[v] = cc.count
+			cc.size[cc.count]++
+		}})
+		cc.count++
+	}
+	return cc, nil
+}
+
+// number of components
+func (cc *ConnectedComponents) Count() int {
+	return cc.count
+}
+
+// component of v, 0..Count()-1
+func (cc *ConnectedComponents) Id(v int) int {
+	return cc.id[v]
+}
+
+// number of vertices in the component of v
+func (cc *ConnectedComponents) Size(v int) int {
+	return cc.size[cc.id[v]]
+}
+
+func (cc *ConnectedComponents) Connected(v, w int) bool {
+	return cc.id[v] == cc.id[w]
+}
+
+// Components returns the vertices of every component.
+func (cc *ConnectedComponents) Components() [][]int {
+	comps := make([][]int, cc.count)
+	for v, c := range cc.id {
+		comps[c] = append(comps[c], v)
+	}
+	return comps
+}
+
+// --- Directed cycles and orders ---
+
+// DirectedCycle returns a directed cycle as a vertex list that starts and ends with the same
+// vertex, nil if the digraph is acyclic.
+func (g *Graph) DirectedCycle() ([]int, error) {
+	if !g.isDirected {
+		return nil, ErrUndirected
+	}
+	marked := make([]bool, g.v)
+	onStack := make([]bool, g.v)
+	edgeTo := make([]int, g.v)
+	var cycle []int
+	for s := 0; s < g.v && cycle == nil; s++ {
+		if marked[s] {
+			continue
+		}
+		g.dfs(s, marked, dfsVisitor{
+			enter: func(v int) { onStack[v] = true },
+			tree:  func(v, w int) { edgeTo[w] = v },
+			back: func(v, w int) {
+				if cycle != nil || !onStack[w] {
+					return
+				}
+				path := stack.New[int]()
+				for x := v; x != w; x = edgeTo[x] {
+					path.Push(x)
+				}
+				path.Push(w)
+				cycle = append(path.ToArray(), w)
+			},
+			leave: func(v, _ int) { onStack[v] = false },
+		})
+	}
+	return cycle, nil
+}
+
+// DepthFirstOrder returns the preorder and postorder of a depth-first search over all vertices.
+func (g *Graph) DepthFirstOrder() *DepthFirstOrder {
+	order := &DepthFirstOrder{pre: make([]int, 0, g.v), post: make([]int, 0, g.v)}
+	marked := make([]bool, g.v)
+	for s := 0; s < g.v; s++ {
+		if !marked[s] {
+			g.dfs(s, marked, dfsVisitor{
+				enter: func(v int) { order.pre = append(order.pre, v) },
+				leave: func(v, _ int) { order.post = append(order.post, v) },
+			})
+		}
+	}
+	return order
+}
+
+func (o *DepthFirstOrder) Pre() []int {
+	return o.pre
+}
+
+func (o *DepthFirstOrder) Post() []int {
+	return o.post
+}
+
+func (o *DepthFirstOrder) ReversePost() []int {
+	rev := make([]int, len(o.post))
+	for i, v := range o.post {
+		rev[len(o.post)-1-i] = v
+	}
+	return rev
+}
+
+// TopologicalOrder returns the vertices so that every edge points forward. It returns a
+// *CycleError if the digraph has a cycle.
+func (g *Graph) TopologicalOrder() ([]int, error) {
+	cycle, err := g.DirectedCycle()
+	if err != nil {
+		return nil, err
+	}
+	if cycle != nil {
+		return nil, &CycleError{Cycle: cycle}
+	}
+	return g.DepthFirstOrder().ReversePost(), nil
+}
+
+// --- Bipartite ---
+
+// Bipartite two-colours an undirected graph, or finds an odd-length cycle.
+func (g *Graph) Bipartite() (*Bipartite, error) {
+	if g.isDirected {
+		return nil, ErrDirected
+	}
+	b := &Bipartite{color: make([]bool, g.v), isBipartite: true}
+	marked := make([]bool, g.v)
+	edgeTo := make([]int, g.v)
+	depth := make([]int, g.v)
+	for s := 0; s < g.v && b.isBipartite; s++ {
+		if marked[s] {
+			continue
+		}
+		g.dfs(s, marked, dfsVisitor{
+			tree: func(v, w int) {
+				edgeTo[w] = v
+				depth[w] = depth[v] + 1
+				b.color[w] = !b.color[v]
+			},
+			back: func(v, w int) {
+				if !b.isBipartite || b.color[v] != b.color[w] {
+					return
+				}
+				b.isBipartite = false
+				b.oddCycle = oddCycle(v, w, edgeTo, depth)
+			},
+		})
+	}
+	return b, nil
+}
+
+func (b *Bipartite) IsBipartite() bool {
+	return b.isBipartite
+}
+
+// Color returns the side of v. Only meaningful if the graph is bipartite.
+func (b *Bipartite) Color(v int) bool {
+	return b.color[v]
+}
+
+// OddCycle returns an odd-length cycle starting and ending with the same vertex, nil if the graph is bipartite.
+func (b *Bipartite) OddCycle() []int {
+	return b.oddCycle
+}
+
+// oddCycle closes the tree paths of v and w at their lowest common ancestor.
+func oddCycle(v, w int, edgeTo, depth []int) []int {
+	var fromV, fromW []int
+	for v != w {
+		if depth[v] >= depth[w] {
+			fromV = append(fromV, v)
+			v = edgeTo[v]
+		} else {
+			fromW = append(fromW, w)
+			w = edgeTo[w]
+		}
+	}
+	cycle := append(fromV, v)
+	for i := len(fromW) - 1; i >= 0; i-- {
+		cycle = append(cycle, fromW[i])
+	}
+	return append(cycle, cycle[0])
+}
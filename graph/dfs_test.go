@@ -0,0 +1,212 @@
+package graph
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+
+	. "github.com/jnsoft/jngo/testhelper"
+)
+
+const tinyG = `13
+13
+0 5
+4 3
+0 1
+9 12
+6 4
+5 4
+0 2
+11 12
+9 10
+0 6
+7 8
+9 11
+5 3
+`
+
+const tinyDG = `13
+22
+4 2
+2 3
+3 2
+6 0
+0 1
+2 0
+11 12
+12 9
+9 10
+9 11
+7 9
+10 12
+11 4
+4 3
+3 5
+6 8
+8 6
+5 4
+0 5
+6 4
+6 9
+7 6
+`
+
+const tinyDAG = `13
+15
+2 3
+0 6
+0 1
+2 0
+11 12
+9 12
+9 10
+9 11
+3 5
+8 7
+5 4
+0 5
+6 4
+6 9
+7 6
+`
+
+func TestDepthFirstSearch(t *testing.T) {
+
+	t.Run("Paths", func(t *testing.T) {
+		g, _ := fromEdgeList(tinyG, false)
+		dfp := g.DepthFirstPaths(0)
+		for v := 0; v < 7; v++ {
+			AssertTrue(t, dfp.HasPathTo(v))
+		}
+		AssertFalse(t, dfp.HasPathTo(7))
+		path := dfp.PathTo(3)
+		AssertEqual(t, path[0], 0)
+		AssertEqual(t, path[len(path)-1], 3)
+		for i := 0; i+1 < len(path); i++ {
+			adj, _ := g.Adj(path[i])
+			AssertTrue(t, contains(adj, path[i+1]))
+		}
+	})
+
+	t.Run("Connected components", func(t *testing.T) {
+		g, _ := fromEdgeList(tinyG, false)
+		cc, err := g.ConnectedComponents()
+		AssertNil(t, err)
+		AssertEqual(t, cc.Count(), 3)
+		AssertTrue(t, cc.Connected(0, 4))
+		AssertFalse(t, cc.Connected(0, 7))
+		AssertEqual(t, cc.Size(9), 4)
+		CollectionAssertEqual(t, cc.Components()[1], []int{7, 8})
+
+		dg, _ := NewGraphFromString(tinyDG, true)
+		_, err = dg.ConnectedComponents()
+		AssertTrue(t, errors.Is(err, ErrDirected))
+	})
+
+	t.Run("Directed cycle", func(t *testing.T) {
+		dg, _ := NewGraphFromString(tinyDG, true)
+		cycle, err := dg.DirectedCycle()
+		AssertNil(t, err)
+		AssertTrue(t, len(cycle) > 2)
+		AssertEqual(t, cycle[0], cycle[len(cycle)-1])
+		for i := 0; i+1 < len(cycle); i++ {
+			adj, _ := dg.Adj(cycle[i])
+			AssertTrue(t, contains(adj, cycle[i+1]))
+		}
+
+		dag, _ := NewGraphFromString(tinyDAG, true)
+		cycle, _ = dag.DirectedCycle()
+		AssertTrue(t, cycle == nil)
+	})
+
+	t.Run("Orders and topological sort", func(t *testing.T) {
+		dag, _ := NewGraphFromString(tinyDAG, true)
+		order := dag.DepthFirstOrder()
+		AssertEqual(t, len(order.Pre()), 13)
+		AssertEqual(t, order.Pre()[0], 0)
+		AssertEqual(t, order.Post()[0], 4)
+
+		topo, err := dag.TopologicalOrder()
+		AssertNil(t, err)
+		position := make([]int, dag.V())
+		for i, v := range topo {
+			position[v] = i
+		}
+		for v := 0; v < dag.V(); v++ {
+			adj, _ := dag.Adj(v)
+			for _, w := range adj {
+				AssertTrue(t, position[v] < position[w])
+			}
+		}
+
+		dg, _ := NewGraphFromString(tinyDG, true)
+		_, err = dg.TopologicalOrder()
+		var ce *CycleError
+		AssertTrue(t, errors.As(err, &ce))
+		AssertTrue(t, len(ce.Cycle) > 2)
+	})
+
+	t.Run("Bipartite", func(t *testing.T) {
+		even, _ := NewGraph(6, false)
+		for v := 0; v < 6; v++ {
+			_ = even.AddEdge(v, (v+1)%6)
+		}
+		b, err := even.Bipartite()
+		AssertNil(t, err)
+		AssertTrue(t, b.IsBipartite())
+		AssertTrue(t, b.Color(0) != b.Color(1))
+		AssertTrue(t, b.OddCycle() == nil)
+
+		g, _ := fromEdgeList(tinyG, false)
+		b, _ = g.Bipartite()
+		AssertFalse(t, b.IsBipartite())
+		cycle := b.OddCycle()
+		AssertEqual(t, cycle[0], cycle[len(cycle)-1])
+		AssertEqual(t, (len(cycle)-1)%2, 1)
+		for i := 0; i+1 < len(cycle); i++ {
+			adj, _ := g.Adj(cycle[i])
+			AssertTrue(t, contains(adj, cycle[i+1]))
+		}
+	})
+
+	t.Run("No stack overflow on long paths", func(t *testing.T) {
+		n := 1_000_000
+		g, _ := NewGraph(n, true)
+		for v := 0; v+1 < n; v++ {
+			_ = g.AddEdge(v, v+1)
+		}
+		AssertEqual(t, len(g.DepthFirstPaths(0).PathTo(n-1)), n)
+		topo, err := g.TopologicalOrder()
+		AssertNil(t, err)
+		AssertEqual(t, topo[0], 0)
+	})
+}
+
+// fromEdgeList builds a graph from the vertex count, edge count and edge lines, listing each
+// undirected edge once.
+func fromEdgeList(s string, directed bool) (*Graph, error) {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	v, _ := strconv.Atoi(lines[0])
+	g, err := NewGraph(v, directed)
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range lines[2:] {
+		a, b, err := readEdge(line)
+		if err != nil {
+			return nil, err
+		}
+		_ = g.AddEdge(a, b)
+	}
+	return g, nil
+}
+
+func contains(adj []int, w int) bool {
+	for _, x := range adj {
+		if x == w {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,318 @@
+package graph
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Graphviz DOT support covers what is needed to exchange graphs: node and edge statements with
+// attribute lists, edge chains like a -> b -> c and comments. Subgraphs are not supported.
+
+type dotToken struct {
+	text   string
+	quoted bool // a quoted ID, never a keyword or operator
+	line   int
+}
+
+// WriteDOT writes x as a Graphviz graph or digraph, with weights as a "weight" edge attribute.
+func (x *Interchange) WriteDOT(w io.Writer) error {
+	x.complete()
+	bw := bufio.NewWriter(w)
+	kind, op := "graph", "--"
+	if x.Directed {
+		kind, op = "digraph", "->"
+	}
+	fmt.Fprintf(bw, "%s {\n", kind)
+	for _, n := range x.Nodes {
+		fmt.Fprintf(bw, "  %s;\n", dotID(n))
+	}
+	for _, e := range x.Edges {
+		if x.Weighted {
+			weight := dotID(strconv.FormatFloat(e.Weight, 'g', -1, 64)) // exponents and Inf are quoted
+			fmt.Fprintf(bw, "  %s %s %s [weight=%s];\n", dotID(e.From), op, dotID(e.To), weight)
+		} else {
+			fmt.Fprintf(bw, "  %s %s %s;\n", dotID(e.From), op, dotID(e.To))
+		}
+	}
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
+
+// ParseDOT reads a single Graphviz graph or digraph. Edge weights are taken from the "weight"
+// attribute, other attributes are ignored.
+func ParseDOT(r io.Reader) (*Interchange, error) {
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	tokens, err := dotTokens(string(src))
+	if err != nil {
+		return nil, err
+	}
+	p := &dotParser{tokens: tokens}
+	return p.graph()
+}
+
+func dotID(s string) string {
+	if s != "" && !isDotKeyword(s) && strings.IndexFunc(s, func(r rune) bool { return !isDotIDRune(r) }) < 0 && !unicode.IsDigit(rune(s[0])) {
+		return s
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil && !strings.ContainsAny(s, "eEnN+x") {
+		return s
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
+
+func isDotIDRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func isDotKeyword(s string) bool {
+	switch strings.ToLower(s) {
+	case "graph", "digraph", "node", "edge", "strict", "subgraph":
+		return true
+	}
+	return false
+}
+
+// dotTokens splits DOT source into IDs, quoted IDs and the punctuation { } [ ] ; , = -> --.
+func dotTokens(src string) ([]dotToken, error) {
+	var tokens []dotToken
+	line := 1
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case strings.HasPrefix(src[i:], "//") || (c == '#' && (i == 0 || src[i-1] == '\n')):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated comment", line)
+			}
+			line += strings.Count(src[i:i+2+end], "\n")
+			i += end + 4
+		case strings.HasPrefix(src[i:], "->") || strings.HasPrefix(src[i:], "--"):
+			tokens = append(tokens, dotToken{text: src[i : i+2], line: line})
+			i += 2
+		case strings.ContainsRune("{}[];,=", rune(c)):
+			tokens = append(tokens, dotToken{text: string(c), line: line})
+			i++
+		case c == '"':
+			var sb strings.Builder
+			start := line
+			for i++; i < len(src) && src[i] != '"'; i++ {
+				if src[i] == '\\' && i+1 < len(src) {
+					i++
+					switch src[i] {
+					case '"', '\\':
+						sb.WriteByte(src[i])
+					case 'n':
+						sb.WriteByte('\n')
+					case '\n':
+						line++ // line continuation
+					default:
+						sb.WriteByte('\\')
+						sb.WriteByte(src[i])
+					}
+					continue
+				}
+				if src[i] == '\n' {
+					line++
+				}
+				sb.WriteByte(src[i])
+			}
+			if i == len(src) {
+				return nil, fmt.Errorf("line %d: unterminated string", start)
+			}
+			i++
+			tokens = append(tokens, dotToken{text: sb.String(), quoted: true, line: start})
+		default:
+			j := i
+			if c == '-' || c == '.' || (c >= '0' && c <= '9') {
+				// numeral
+				for j++; j < len(src) && (src[j] == '.' || (src[j] >= '0' && src[j] <= '9')); j++ {
+				}
+			} else {
+				for j < len(src) {
+					r := rune(src[j])
+					if r >= 0x80 || isDotIDRune(r) {
+						j++
+						continue
+					}
+					break
+				}
+			}
+			if j == i {
+				return nil, fmt.Errorf("line %d: unexpected character %q", line, c)
+			}
+			tokens = append(tokens, dotToken{text: src[i:j], line: line})
+			i = j
+		}
+	}
+	return tokens, nil
+}
+
+type dotParser struct {
+	tokens []dotToken
+	pos    int
+	x      *Interchange
+}
+
+func (p *dotParser) peek() (dotToken, bool) {
+	if p.pos == len(p.tokens) {
+		return dotToken{}, false
+	}
+	return p.tokens[p.pos], true
+}
+
+// accept consumes the next token if it is the unquoted text s.
+func (p *dotParser) accept(s string) bool {
+	t, ok := p.peek()
+	if ok && !t.quoted && strings.EqualFold(t.text, s) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *dotParser) expect(s string) error {
+	if p.accept(s) {
+		return nil
+	}
+	return p.errorf("expected %q", s)
+}
+
+func (p *dotParser) errorf(format string, args ...any) error {
+	t, ok := p.peek()
+	if !ok {
+		return fmt.Errorf("unexpected end of input: "+format, args...)
+	}
+	return fmt.Errorf("line %d near %q: "+format, append([]any{t.line, t.text}, args...)...)
+}
+
+// id consumes an ID, quoted or not.
+func (p *dotParser) id() (string, error) {
+	t, ok := p.peek()
+	if !ok || (!t.quoted && (strings.ContainsAny(t.text, "{}[];,=") || t.text == "->" || t.text == "--")) {
+		return "", p.errorf("expected an ID")
+	}
+	p.pos++
+	return t.text, nil
+}
+
+func (p *dotParser) graph() (*Interchange, error) {
+	p.accept("strict")
+	directed := false
+	switch {
+	case p.accept("digraph"):
+		directed = true
+	case p.accept("graph"):
+	default:
+		return nil, p.errorf("expected graph or digraph")
+	}
+	p.x = NewInterchange(directed, false)
+	if !p.accept("{") {
+		if _, err := p.id(); err != nil {
+			return nil, err
+		}
+		if err := p.expect("{"); err != nil {
+			return nil, err
+		}
+	}
+	for !p.accept("}") {
+		if err := p.statement(); err != nil {
+			return nil, err
+		}
+		p.accept(";")
+	}
+	if _, ok := p.peek(); ok {
+		return nil, p.errorf("unexpected input after graph")
+	}
+	return p.x, nil
+}
+
+func (p *dotParser) statement() error {
+	if p.accept("subgraph") || p.accept("{") {
+		return p.errorf("subgraphs are not supported")
+	}
+	if p.accept("graph") || p.accept("node") || p.accept("edge") {
+		_, err := p.attributes()
+		return err
+	}
+	first, err := p.id()
+	if err != nil {
+		return err
+	}
+	if p.accept("=") { // graph attribute
+		_, err := p.id()
+		return err
+	}
+	chain := []string{first}
+	for {
+		op := "--"
+		if p.x.Directed {
+			op = "->"
+		}
+		if !p.accept(op) {
+			break
+		}
+		next, err := p.id()
+		if err != nil {
+			return err
+		}
+		chain = append(chain, next)
+	}
+	attrs, err := p.attributes()
+	if err != nil {
+		return err
+	}
+	if len(chain) == 1 {
+		p.x.AddNode(first)
+		return nil
+	}
+	weight := DEFAULT_WEIGHT
+	if s, ok := attrs["weight"]; ok {
+		if weight, err = strconv.ParseFloat(s, 64); err != nil {
+			return fmt.Errorf("invalid weight %q on edge %s-%s", s, chain[0], chain[1])
+		}
+		p.x.Weighted = true
+	}
+	for i := 0; i+1 < len(chain); i++ {
+		p.x.AddEdge(chain[i], chain[i+1], weight)
+	}
+	return nil
+}
+
+// attributes consumes any number of [a=b, c=d; ...] lists.
+func (p *dotParser) attributes() (map[string]string, error) {
+	attrs := make(map[string]string)
+	for p.accept("[") {
+		for !p.accept("]") {
+			key, err := p.id()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("="); err != nil {
+				return nil, err
+			}
+			if attrs[key], err = p.id(); err != nil {
+				return nil, err
+			}
+			if !p.accept(",") {
+				p.accept(";")
+			}
+		}
+	}
+	return attrs, nil
+}
@@ -0,0 +1,330 @@
+package graph
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/jnsoft/jngo/queue"
+	"github.com/jnsoft/jngo/stack"
+	"github.com/jnsoft/jngo/stringhelper"
+)
+
+const FLOW_EPSILON = 1e-11 // residual capacities below this are treated as zero
+
+type (
+	// FlowEdge is a directed edge with a capacity and the flow currently sent along it.
+	FlowEdge struct {
+		From     int
+		To       int
+		Capacity float64
+		Flow     float64
+	}
+
+	// FlowNetwork is a directed graph of flow edges. Every edge is listed with both endpoints so
+	// the residual network can be walked in both directions.
+	FlowNetwork struct {
+		v             int
+		e             int
+		adjacencyList [][]*FlowEdge
+	}
+
+	// MaxFlow holds the value of a maximum flow and the source side of the matching minimum cut.
+	// The flow on each edge is left in the network.
+	MaxFlow struct {
+		network *FlowNetwork
+		value   float64
+		inCut   []bool // inCut[v] = v is reachable from the source in the residual network
+		Source  int
+		Sink    int
+	}
+)
+
+func NewFlowNetwork(v int) (*FlowNetwork, error) {
+	if v < 0 {
+		return nil, errors.New("number of vertices must be nonnegative")
+	}
+	return &FlowNetwork{v: v, adjacencyList: make([][]*FlowEdge, v)}, nil
+}
+
+// NewFlowNetworkFromString reads the vertex count, the edge count and one "v w capacity" line per edge.
+func NewFlowNetworkFromString(s string) (*FlowNetwork, error) {
+	lines := stringhelper.ToLines(s)
+	if len(lines) < 2 {
+		return nil, fmt.Errorf("invalid input: no vertex or edge count specified")
+	}
+	v, err := strconv.Atoi(strings.TrimSpace(lines[0]))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse vertex count: %v", err)
+	}
+	e, err := strconv.Atoi(strings.TrimSpace(lines[1]))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse edge count: %v", err)
+	}
+
+	network, err := NewFlowNetwork(v)
+	if err != nil {
+		return nil, err
+	}
+	for i := 2; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i]) == "" {
+			continue
+		}
+		edge, err := readWeightedEdge(lines[i])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse edge: %v", err)
+		}
+		if err := network.AddEdge(edge.From, edge.To, edge.Weight); err != nil {
+			return nil, fmt.Errorf("failed to add edge: %v", err)
+		}
+	}
+	if network.e != e {
+		return nil, fmt.Errorf("wrong number of edges, want %v, got %v", e, network.e)
+	}
+	return network, nil
+}
+
+// Other returns the endpoint of the edge that is not v.
+func (e *FlowEdge) Other(v int) int {
+	if v == e.From {
+		return e.To
+	}
+	return e.From
+}
+
+// ResidualCapacityTo returns how much more flow can be sent towards v, by pushing forward flow
+// to To or cancelling flow back to From.
+func (e *FlowEdge) ResidualCapacityTo(v int) float64 {
+	if v == e.From {
+		return e.Flow
+	}
+	return e.Capacity - e.Flow
+}
+
+func (e *FlowEdge) addResidualFlowTo(v int, delta float64) {
+	if v == e.From {
+		e.Flow -= delta
+	} else {
+		e.Flow += delta
+	}
+}
+
+func (e *FlowEdge) String() string {
+	return fmt.Sprintf("%d->%d %v/%v", e.From, e.To, e.Flow, e.Capacity)
+}
+
+func (g *FlowNetwork) V() int {
+	return g.v
+}
+
+func (g *FlowNetwork) E() int {
+	return g.e
+}
+
+func (g *FlowNetwork) AddEdge(from, to int, capacity float64) error {
+	if from < 0 || from >= g.v || to < 0 || to >= g.v {
+		return errors.New("vertex out of bounds")
+	}
+	if capacity < 0 || math.IsNaN(capacity) {
+		return fmt.Errorf("capacity must be nonnegative: %v", capacity)
+	}
+	e := &FlowEdge{From: from, To: to, Capacity: capacity}
+	g.e++
+	g.adjacencyList[from] = append(g.adjacencyList[from], e)
+	if from != to {
+		g.adjacencyList[to] = append(g.adjacencyList[to], e)
+	}
+	return nil
+}
+
+// Adj returns the edges entering and leaving v.
+func (g *FlowNetwork) Adj(v int) ([]*FlowEdge, error) {
+	if v < 0 || v >= g.v {
+		return nil, errors.New("vertex out of bounds")
+	}
+	return g.adjacencyList[v], nil
+}
+
+// Edges returns every edge once.
+func (g *FlowNetwork) Edges() []*FlowEdge {
+	edges := make([]*FlowEdge, 0, g.e)
+	for v := 0; v < g.v; v++ {
+		for _, e := range g.adjacencyList[v] {
+			if e.From == v {
+				edges = append(edges, e)
+			}
+		}
+	}
+	return edges
+}
+
+// --- Ford-Fulkerson ---
+
+// FordFulkerson computes a maximum flow from s to t by augmenting along paths found with a
+// depth-first search. It is fast on most inputs but may need many augmentations.
+func (g *FlowNetwork) FordFulkerson(s, t int) (*MaxFlow, error) {
+	return g.augmentingPaths(s, t, false)
+}
+
+// EdmondsKarp is Ford-Fulkerson with shortest augmenting paths, which bounds the number of
+// augmentations by V*E/2.
+func (g *FlowNetwork) EdmondsKarp(s, t int) (*MaxFlow, error) {
+	return g.augmentingPaths(s, t, true)
+}
+
+func (g *FlowNetwork) augmentingPaths(s, t int, shortest bool) (*MaxFlow, error) {
+	mf, err := g.newMaxFlow(s, t)
+	if err != nil {
+		return nil, err
+	}
+	edgeTo := make([]*FlowEdge, g.v)
+	for g.findPath(s, t, edgeTo, mf.inCut, shortest) {
+		bottleneck := math.Inf(1)
+		for v := t; v != s; v = edgeTo[v].Other(v) {
+			bottleneck = min(bottleneck, edgeTo[v].ResidualCapacityTo(v))
+		}
+		for v := t; v != s; v = edgeTo[v].Other(v) {
+			edgeTo[v].addResidualFlowTo(v, bottleneck)
+		}
+		mf.value += bottleneck
+	}
+	return mf, nil
+}
+
+// findPath searches the residual network from s, breadth-first if shortest is set. It leaves the
+// reached vertices in marked and reports whether t was among them.
+func (g *FlowNetwork) findPath(s, t int, edgeTo []*FlowEdge, marked []bool, shortest bool) bool {
+	clear(marked)
+	marked[s] = true
+	var next func() int
+	var push func(int)
+	if shortest {
+		q := queue.New[int]()
+		next, push = q.Dequeue, q.Enqueue
+	} else {
+		st := stack.New[int]()
+		next, push = st.Pop, st.Push
+	}
+	push(s)
+	for pending := 1; pending > 0 && !marked[t]; pending-- {
+		v := next()
+		for _, e := range g.adjacencyList[v] {
+			w := e.Other(v)
+			if !marked[w] && e.ResidualCapacityTo(w) > FLOW_EPSILON {
+				edgeTo[w] = e
+				marked[w] = true
+				push(w)
+				pending++
+			}
+		}
+	}
+	return marked[t]
+}
+
+// --- Dinic ---
+
+// Dinic computes a maximum flow from s to t with blocking flows on the level graph, in O(V^2*E)
+// and much faster on unit-capacity networks.
+func (g *FlowNetwork) Dinic(s, t int) (*MaxFlow, error) {
+	mf, err := g.newMaxFlow(s, t)
+	if err != nil {
+		return nil, err
+	}
+	level := make([]int, g.v)
+	next := make([]int, g.v) // next[v] = first edge of v that may still carry flow in this phase
+	for g.levels(s, level); level[t] >= 0; g.levels(s, level) {
+		clear(next)
+		for {
+			pushed := g.blockingFlow(s, t, math.Inf(1), level, next)
+			if pushed <= FLOW_EPSILON {
+				break
+			}
+			mf.value += pushed
+		}
+	}
+	// the last level search reached exactly the source side of the minimum cut
+	for v := range level {
+		mf.inCut[v] = level[v] >= 0
+	}
+	return mf, nil
+}
+
+// levels sets the breadth-first distance from s in the residual network, -1 if unreachable.
+func (g *FlowNetwork) levels(s int, level []int) {
+	for v := range level {
+		level[v] = -1
+	}
+	level[s] = 0
+	q := queue.New[int]()
+	q.Enqueue(s)
+	for !q.IsEmpty() {
+		v := q.Dequeue()
+		for _, e := range g.adjacencyList[v] {
+			w := e.Other(v)
+			if level[w] < 0 && e.ResidualCapacityTo(w) > FLOW_EPSILON {
+				level[w] = level[v] + 1
+				q.Enqueue(w)
+			}
+		}
+	}
+}
+
+// blockingFlow pushes up to limit units from v to t along edges that go one level up.
+func (g *FlowNetwork) blockingFlow(v, t int, limit float64, level, next []int) float64 {
+	if v == t {
+		return limit
+	}
+	for ; next[v] < len(g.adjacencyList[v]); next[v]++ {
+		e := g.adjacencyList[v][next[v]]
+		w := e.Other(v)
+		residual := e.ResidualCapacityTo(w)
+		if level[w] != level[v]+1 || residual <= FLOW_EPSILON {
+			continue
+		}
+		if pushed := g.blockingFlow(w, t, min(limit, residual), level, next); pushed > FLOW_EPSILON {
+			e.addResidualFlowTo(w, pushed)
+			return pushed
+		}
+	}
+	return 0
+}
+
+// --- Result ---
+
+// Value returns the total flow from the source to the sink.
+func (mf *MaxFlow) Value() float64 {
+	return mf.value
+}
+
+// InCut reports whether v is on the source side of the minimum cut.
+func (mf *MaxFlow) InCut(v int) bool {
+	return mf.inCut[v]
+}
+
+// MinCut returns the edges from the source side to the sink side of the minimum cut. Their
+// capacities sum to the value of the flow.
+func (mf *MaxFlow) MinCut() []*FlowEdge {
+	var cut []*FlowEdge
+	for _, e := range mf.network.Edges() {
+		if mf.inCut[e.From] && !mf.inCut[e.To] {
+			cut = append(cut, e)
+		}
+	}
+	return cut
+}
+
+// newMaxFlow validates s and t and clears the flow left by an earlier run.
+func (g *FlowNetwork) newMaxFlow(s, t int) (*MaxFlow, error) {
+	if s < 0 || s >= g.v || t < 0 || t >= g.v {
+		return nil, errors.New("vertex out of bounds")
+	}
+	if s == t {
+		return nil, errors.New("source and sink must differ")
+	}
+	for _, e := range g.Edges() {
+		e.Flow = 0
+	}
+	return &MaxFlow{network: g, inCut: make([]bool, g.v), Source: s, Sink: t}, nil
+}
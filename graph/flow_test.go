@@ -0,0 +1,105 @@
+package graph
+
+import (
+	"math/rand"
+	"testing"
+
+	. "github.com/jnsoft/jngo/testhelper"
+)
+
+const tinyFN = `6
+8
+0 1 2.0
+0 2 3.0
+1 3 3.0
+1 4 1.0
+2 3 1.0
+2 4 1.0
+3 5 2.0
+4 5 3.0
+`
+
+func TestMaxFlow(t *testing.T) {
+	algorithms := map[string]func(*FlowNetwork, int, int) (*MaxFlow, error){
+		"Ford-Fulkerson": (*FlowNetwork).FordFulkerson,
+		"Edmonds-Karp":   (*FlowNetwork).EdmondsKarp,
+		"Dinic":          (*FlowNetwork).Dinic,
+	}
+
+	for name, maxFlow := range algorithms {
+		t.Run(name, func(t *testing.T) {
+			g, err := NewFlowNetworkFromString(tinyFN)
+			AssertNil(t, err)
+			mf, err := maxFlow(g, 0, 5)
+			AssertNil(t, err)
+			AssertTrue(t, approx(mf.Value(), 4))
+			checkFlow(t, g, mf)
+
+			for v := 0; v < g.V(); v++ {
+				AssertEqual(t, mf.InCut(v), v == 0 || v == 2)
+			}
+			cut := 0.0
+			for _, e := range mf.MinCut() {
+				cut += e.Capacity
+			}
+			AssertTrue(t, approx(cut, 4))
+
+			_, err = maxFlow(g, 1, 1)
+			AssertNotEqual(t, err, nil)
+			_, err = maxFlow(g, 0, 6)
+			AssertNotEqual(t, err, nil)
+		})
+	}
+
+	t.Run("Random networks agree", func(t *testing.T) {
+		rnd := rand.New(rand.NewSource(7))
+		for round := 0; round < 20; round++ {
+			v := 2 + rnd.Intn(30)
+			g, _ := NewFlowNetwork(v)
+			for i := 0; i < 4*v; i++ {
+				_ = g.AddEdge(rnd.Intn(v), rnd.Intn(v), float64(rnd.Intn(10)))
+			}
+			want, _ := g.EdmondsKarp(0, v-1)
+			value := want.Value()
+			for _, maxFlow := range algorithms {
+				mf, _ := maxFlow(g, 0, v-1)
+				AssertTrue(t, approx(mf.Value(), value))
+				checkFlow(t, g, mf)
+			}
+		}
+	})
+
+	t.Run("Invalid capacity", func(t *testing.T) {
+		g, _ := NewFlowNetwork(2)
+		AssertNotEqual(t, g.AddEdge(0, 1, -1), nil)
+		AssertNotEqual(t, g.AddEdge(0, 2, 1), nil)
+	})
+}
+
+// checkFlow verifies the capacity and conservation constraints and that the cut matches the value.
+func checkFlow(t *testing.T, g *FlowNetwork, mf *MaxFlow) {
+	t.Helper()
+	excess := make([]float64, g.V())
+	for _, e := range g.Edges() {
+		AssertTrue(t, e.Flow >= -FLOW_EPSILON && e.Flow <= e.Capacity+FLOW_EPSILON)
+		excess[e.From] -= e.Flow
+		excess[e.To] += e.Flow
+	}
+	for v := range excess {
+		switch v {
+		case mf.Source:
+			AssertTrue(t, approx(excess[v], -mf.Value()))
+		case mf.Sink:
+			AssertTrue(t, approx(excess[v], mf.Value()))
+		default:
+			AssertTrue(t, approx(excess[v], 0))
+		}
+	}
+	AssertTrue(t, mf.InCut(mf.Source))
+	AssertFalse(t, mf.InCut(mf.Sink))
+	cut := 0.0
+	for _, e := range mf.MinCut() {
+		cut += e.Capacity
+	}
+	AssertTrue(t, approx(cut, mf.Value()))
+}
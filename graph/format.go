@@ -0,0 +1,498 @@
+package graph
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// DEFAULT_WEIGHT is the weight of edges read without one.
+const DEFAULT_WEIGHT = 1.0
+
+type (
+	// Interchange is the format-neutral form of a graph that the DOT, GraphML, edge list and JSON
+	// readers and writers work on. Vertices are named, edges refer to them by name.
+	Interchange struct {
+		Directed bool
+		Weighted bool // write edge weights
+		Nodes    []string
+		Edges    []InterchangeEdge
+		index    map[string]int
+	}
+
+	InterchangeEdge struct {
+		From, To string
+		Weight   float64
+	}
+)
+
+func NewInterchange(directed, weighted bool) *Interchange {
+	return &Interchange{Directed: directed, Weighted: weighted, index: make(map[string]int)}
+}
+
+// AddNode adds a vertex unless it exists and returns its position in Nodes.
+func (x *Interchange) AddNode(name string) int {
+	if x.index == nil {
+		x.index = make(map[string]int, len(x.Nodes))
+		for i, n := range x.Nodes {
+			x.index[n] = i
+		}
+	}
+	if i, ok := x.index[name]; ok {
+		return i
+	}
+	x.index[name] = len(x.Nodes)
+	x.Nodes = append(x.Nodes, name)
+	return len(x.Nodes) - 1
+}
+
+// AddEdge adds an edge, adding its endpoints as vertices if needed.
+func (x *Interchange) AddEdge(from, to string, weight float64) {
+	x.AddNode(from)
+	x.AddNode(to)
+	x.Edges = append(x.Edges, InterchangeEdge{From: from, To: to, Weight: weight})
+}
+
+// complete adds the endpoints of edges that were appended to Edges directly.
+func (x *Interchange) complete() {
+	for _, e := range x.Edges {
+		x.AddNode(e.From)
+		x.AddNode(e.To)
+	}
+}
+
+// --- Conversions ---
+
+// Interchange converts g, naming the vertices with name or with their numbers if name is nil.
+// Undirected edges are listed once.
+func (g *Graph) Interchange(name func(v int) string) *Interchange {
+	if name == nil {
+		name = strconv.Itoa
+	}
+	x := NewInterchange(g.isDirected, false)
+	for v := 0; v < g.v; v++ {
+		x.AddNode(name(v))
+	}
+	for v := 0; v < g.v; v++ {
+		selfLoop := false
+		for _, w := range g.adjacencyList[v] {
+			// an undirected self-loop is listed twice in the adjacency list of v
+			if w == v && !g.isDirected {
+				selfLoop = !selfLoop
+				if !selfLoop {
+					continue
+				}
+			}
+			if g.isDirected || v <= w {
+				x.AddEdge(name(v), name(w), DEFAULT_WEIGHT)
+			}
+		}
+	}
+	return x
+}
+
+// Interchange converts g like Graph.Interchange, keeping the weights.
+func (g *EdgeWeightedGraph) Interchange(name func(v int) string) *Interchange {
+	if name == nil {
+		name = strconv.Itoa
+	}
+	x := NewInterchange(g.isDirected, true)
+	for v := 0; v < g.v; v++ {
+		x.AddNode(name(v))
+	}
+	for _, e := range g.Edges() {
+		x.AddEdge(name(e.From), name(e.To), e.Weight)
+	}
+	return x
+}
+
+// Graph converts x to a Graph. Vertex names must be nonnegative integers, the graph has as many
+// vertices as the largest one plus one.
+func (x *Interchange) Graph() (*Graph, error) {
+	v, err := x.numberedVertices()
+	if err != nil {
+		return nil, err
+	}
+	g, err := NewGraph(v, x.Directed)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range x.Edges {
+		from, _ := strconv.Atoi(e.From)
+		to, _ := strconv.Atoi(e.To)
+		if err := g.AddEdge(from, to); err != nil {
+			return nil, err
+		}
+	}
+	return g, nil
+}
+
+// EdgeWeightedGraph converts x to an EdgeWeightedGraph, numbering vertices like Graph.
+func (x *Interchange) EdgeWeightedGraph() (*EdgeWeightedGraph, error) {
+	v, err := x.numberedVertices()
+	if err != nil {
+		return nil, err
+	}
+	g, err := NewEdgeWeightedGraph(v, x.Directed)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range x.Edges {
+		from, _ := strconv.Atoi(e.From)
+		to, _ := strconv.Atoi(e.To)
+		if err := g.AddEdge(Edge{From: from, To: to, Weight: e.Weight}); err != nil {
+			return nil, err
+		}
+	}
+	return g, nil
+}
+
+// numberedVertices returns the number of vertices for the integer names of x. Names may skip
+// isolated vertices, but not more than there are edges, so a stray huge name is an error rather
+// than a huge graph.
+func (x *Interchange) numberedVertices() (int, error) {
+	names := make(map[string]bool, len(x.Nodes))
+	for _, n := range x.Nodes {
+		names[n] = true
+	}
+	for _, e := range x.Edges {
+		names[e.From] = true
+		names[e.To] = true
+	}
+	limit := len(names) + len(x.Edges)
+	v := 0
+	check := func(name string) error {
+		n, err := strconv.Atoi(name)
+		if err != nil || n < 0 {
+			return fmt.Errorf("vertex name is not a nonnegative integer: %q", name)
+		}
+		if n >= limit {
+			return fmt.Errorf("vertex name %q is too large for %d vertices and %d edges", name, len(names), len(x.Edges))
+		}
+		v = max(v, n+1)
+		return nil
+	}
+	for _, n := range x.Nodes {
+		if err := check(n); err != nil {
+			return 0, err
+		}
+	}
+	for _, e := range x.Edges {
+		if err := check(e.From); err != nil {
+			return 0, err
+		}
+		if err := check(e.To); err != nil {
+			return 0, err
+		}
+	}
+	return v, nil
+}
+
+// --- Edge list ---
+
+// WriteEdgeList writes one "from to [weight]" line per edge and one line per isolated vertex.
+// Names with spaces or quotes are quoted.
+func (x *Interchange) WriteEdgeList(w io.Writer) error {
+	x.complete()
+	bw := bufio.NewWriter(w)
+	used := make([]bool, len(x.Nodes))
+	for _, e := range x.Edges {
+		used[x.AddNode(e.From)], used[x.AddNode(e.To)] = true, true
+		if x.Weighted {
+			fmt.Fprintf(bw, "%s %s %v\n", quoteField(e.From), quoteField(e.To), e.Weight)
+		} else {
+			fmt.Fprintf(bw, "%s %s\n", quoteField(e.From), quoteField(e.To))
+		}
+	}
+	for i, n := range x.Nodes {
+		if !used[i] {
+			fmt.Fprintf(bw, "%s\n", quoteField(n))
+		}
+	}
+	return bw.Flush()
+}
+
+// ParseEdgeList reads "from to [weight]" lines. A line with a single name adds an isolated
+// vertex, empty lines and lines starting with # are skipped.
+func ParseEdgeList(r io.Reader, directed bool) (*Interchange, error) {
+	x := NewInterchange(directed, false)
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields, err := splitFields(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		switch len(fields) {
+		case 1:
+			x.AddNode(fields[0])
+		case 2:
+			x.AddEdge(fields[0], fields[1], DEFAULT_WEIGHT)
+		case 3:
+			weight, err := strconv.ParseFloat(fields[2], 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid weight %q", line, fields[2])
+			}
+			x.Weighted = true
+			x.AddEdge(fields[0], fields[1], weight)
+		default:
+			return nil, fmt.Errorf("line %d: expected from, to and optional weight", line)
+		}
+	}
+	return x, scanner.Err()
+}
+
+func quoteField(s string) string {
+	if s == "" || strings.ContainsFunc(s, func(r rune) bool { return unicode.IsSpace(r) || r == '"' || r == '#' }) {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// splitFields splits on whitespace, keeping quoted fields together.
+func splitFields(s string) ([]string, error) {
+	var fields []string
+	for s = strings.TrimSpace(s); s != ""; s = strings.TrimSpace(s) {
+		if s[0] == '"' {
+			quoted, err := strconv.QuotedPrefix(s)
+			if err != nil {
+				return nil, fmt.Errorf("unterminated quote: %s", s)
+			}
+			field, _ := strconv.Unquote(quoted)
+			fields = append(fields, field)
+			s = s[len(quoted):]
+			continue
+		}
+		end := strings.IndexFunc(s, unicode.IsSpace)
+		if end < 0 {
+			end = len(s)
+		}
+		fields = append(fields, s[:end])
+		s = s[end:]
+	}
+	return fields, nil
+}
+
+// --- JSON ---
+
+type (
+	jsonGraph struct {
+		Directed  bool             `json:"directed"`
+		Nodes     []string         `json:"nodes"`
+		Adjacency [][]jsonNeighbor `json:"adjacency"` // Adjacency[i] = edges from Nodes[i]
+	}
+
+	jsonNeighbor struct {
+		To     string   `json:"to"`
+		Weight *float64 `json:"weight,omitempty"`
+	}
+)
+
+// WriteJSON writes an object with the vertex names and, for each vertex, the edges leaving it.
+// Undirected edges are listed once, under the vertex they were added from.
+func (x *Interchange) WriteJSON(w io.Writer) error {
+	x.complete()
+	doc := jsonGraph{Directed: x.Directed, Nodes: x.Nodes, Adjacency: make([][]jsonNeighbor, len(x.Nodes))}
+	if doc.Nodes == nil {
+		doc.Nodes = []string{}
+	}
+	for i := range doc.Adjacency {
+		doc.Adjacency[i] = []jsonNeighbor{}
+	}
+	for _, e := range x.Edges {
+		n := jsonNeighbor{To: e.To}
+		if x.Weighted {
+			n.Weight = &e.Weight
+		}
+		from := x.AddNode(e.From)
+		doc.Adjacency[from] = append(doc.Adjacency[from], n)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+// ParseJSON reads the format written by WriteJSON.
+func ParseJSON(r io.Reader) (*Interchange, error) {
+	var doc jsonGraph
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON graph: %v", err)
+	}
+	if len(doc.Adjacency) > len(doc.Nodes) {
+		return nil, errors.New("adjacency has more entries than nodes")
+	}
+	x := NewInterchange(doc.Directed, false)
+	for _, n := range doc.Nodes {
+		x.AddNode(n)
+	}
+	for i, neighbors := range doc.Adjacency {
+		for _, n := range neighbors {
+			weight := DEFAULT_WEIGHT
+			if n.Weight != nil {
+				x.Weighted = true
+				weight = *n.Weight
+			}
+			x.AddEdge(doc.Nodes[i], n.To, weight)
+		}
+	}
+	return x, nil
+}
+
+// --- GraphML ---
+
+const GRAPHML_NAMESPACE = "http://graphml.graphdrawing.org/xmlns"
+
+type (
+	graphML struct {
+		XMLName xml.Name     `xml:"graphml"`
+		Xmlns   string       `xml:"xmlns,attr,omitempty"`
+		Keys    []graphMLKey `xml:"key"`
+		Graph   struct {
+			ID          string        `xml:"id,attr,omitempty"`
+			EdgeDefault string        `xml:"edgedefault,attr"`
+			Nodes       []graphMLNode `xml:"node"`
+			Edges       []graphMLEdge `xml:"edge"`
+		} `xml:"graph"`
+	}
+
+	graphMLKey struct {
+		ID       string `xml:"id,attr"`
+		For      string `xml:"for,attr"`
+		AttrName string `xml:"attr.name,attr"`
+		AttrType string `xml:"attr.type,attr"`
+	}
+
+	graphMLNode struct {
+		ID string `xml:"id,attr"`
+	}
+
+	graphMLEdge struct {
+		Source string        `xml:"source,attr"`
+		Target string        `xml:"target,attr"`
+		Data   []graphMLData `xml:"data"`
+	}
+
+	graphMLData struct {
+		Key   string `xml:"key,attr"`
+		Value string `xml:",chardata"`
+	}
+)
+
+// WriteGraphML writes x as a GraphML document, with the weights as a "weight" edge attribute.
+func (x *Interchange) WriteGraphML(w io.Writer) error {
+	x.complete()
+	doc := graphML{Xmlns: GRAPHML_NAMESPACE}
+	doc.Graph.ID = "G"
+	doc.Graph.EdgeDefault = "undirected"
+	if x.Directed {
+		doc.Graph.EdgeDefault = "directed"
+	}
+	if x.Weighted {
+		doc.Keys = []graphMLKey{{ID: "weight", For: "edge", AttrName: "weight", AttrType: "double"}}
+	}
+	for _, n := range x.Nodes {
+		doc.Graph.Nodes = append(doc.Graph.Nodes, graphMLNode{ID: n})
+	}
+	for _, e := range x.Edges {
+		edge := graphMLEdge{Source: e.From, Target: e.To}
+		if x.Weighted {
+			edge.Data = []graphMLData{{Key: "weight", Value: strconv.FormatFloat(e.Weight, 'g', -1, 64)}}
+		}
+		doc.Graph.Edges = append(doc.Graph.Edges, edge)
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// ParseGraphML reads the first graph of a GraphML document. Edge weights are taken from the edge
+// attribute named "weight", other attributes are ignored.
+func ParseGraphML(r io.Reader) (*Interchange, error) {
+	var doc graphML
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to parse GraphML: %v", err)
+	}
+	weightKey := ""
+	for _, k := range doc.Keys {
+		if k.AttrName == "weight" && (k.For == "edge" || k.For == "all") {
+			weightKey = k.ID
+		}
+	}
+	x := NewInterchange(doc.Graph.EdgeDefault == "directed", false)
+	for _, n := range doc.Graph.Nodes {
+		x.AddNode(n.ID)
+	}
+	for _, e := range doc.Graph.Edges {
+		weight := DEFAULT_WEIGHT
+		for _, d := range e.Data {
+			if weightKey == "" || d.Key != weightKey {
+				continue
+			}
+			w, err := strconv.ParseFloat(strings.TrimSpace(d.Value), 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid weight on edge %s-%s: %q", e.Source, e.Target, d.Value)
+			}
+			x.Weighted = true
+			weight = w
+		}
+		x.AddEdge(e.Source, e.Target, weight)
+	}
+	return x, nil
+}
+
+// --- Graph shortcuts ---
+
+func (g *Graph) WriteDOT(w io.Writer) error {
+	return g.Interchange(nil).WriteDOT(w)
+}
+
+func (g *Graph) WriteGraphML(w io.Writer) error {
+	return g.Interchange(nil).WriteGraphML(w)
+}
+
+func (g *Graph) WriteEdgeList(w io.Writer) error {
+	return g.Interchange(nil).WriteEdgeList(w)
+}
+
+func (g *Graph) WriteJSON(w io.Writer) error {
+	return g.Interchange(nil).WriteJSON(w)
+}
+
+func ReadDOT(r io.Reader) (*Graph, error) {
+	return toGraph(ParseDOT(r))
+}
+
+func ReadGraphML(r io.Reader) (*Graph, error) {
+	return toGraph(ParseGraphML(r))
+}
+
+func ReadEdgeList(r io.Reader, is_directed bool) (*Graph, error) {
+	return toGraph(ParseEdgeList(r, is_directed))
+}
+
+func ReadJSON(r io.Reader) (*Graph, error) {
+	return toGraph(ParseJSON(r))
+}
+
+func toGraph(x *Interchange, err error) (*Graph, error) {
+	if err != nil {
+		return nil, err
+	}
+	return x.Graph()
+}
@@ -0,0 +1,211 @@
+package graph
+
+import (
+	"bytes"
+	"cmp"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	. "github.com/jnsoft/jngo/testhelper"
+)
+
+func TestFormats(t *testing.T) {
+	writers := map[string]func(*Interchange, *bytes.Buffer) error{
+		"DOT":       func(x *Interchange, b *bytes.Buffer) error { return x.WriteDOT(b) },
+		"GraphML":   func(x *Interchange, b *bytes.Buffer) error { return x.WriteGraphML(b) },
+		"Edge list": func(x *Interchange, b *bytes.Buffer) error { return x.WriteEdgeList(b) },
+		"JSON":      func(x *Interchange, b *bytes.Buffer) error { return x.WriteJSON(b) },
+	}
+	parsers := map[string]func(io.Reader, bool) (*Interchange, error){
+		"DOT":       func(r io.Reader, _ bool) (*Interchange, error) { return ParseDOT(r) },
+		"GraphML":   func(r io.Reader, _ bool) (*Interchange, error) { return ParseGraphML(r) },
+		"Edge list": ParseEdgeList,
+		"JSON":      func(r io.Reader, _ bool) (*Interchange, error) { return ParseJSON(r) },
+	}
+
+	for name, write := range writers {
+		parse := parsers[name]
+
+		t.Run(name+" round trip", func(t *testing.T) {
+			undirected, _ := fromEdgeList(tinyG, false)
+			_ = undirected.AddEdge(3, 3)
+			directed, _ := NewGraphFromString(tinyDG, true)
+			for _, g := range []*Graph{undirected, directed} {
+				var buf bytes.Buffer
+				AssertNil(t, write(g.Interchange(nil), &buf))
+				x, err := parse(&buf, g.IsDirected())
+				AssertNil(t, err)
+				g2, err := x.Graph()
+				AssertNil(t, err)
+				AssertEqual(t, g2.V(), g.V())
+				AssertEqual(t, g2.E(), g.E())
+				AssertEqual(t, g2.IsDirected(), g.IsDirected())
+				CollectionAssertEqual(t, edgeSet(g2), edgeSet(g))
+			}
+		})
+
+		t.Run(name+" weights and names", func(t *testing.T) {
+			g, _ := NewEdgeWeightedGraphFromString(tinyEWD, true)
+			var buf bytes.Buffer
+			AssertNil(t, write(g.Interchange(nil), &buf))
+			x, err := parse(&buf, true)
+			AssertNil(t, err)
+			AssertTrue(t, x.Weighted)
+			g2, _ := x.EdgeWeightedGraph()
+			AssertEqual(t, g2.String(), g.String())
+
+			names := NewInterchange(false, false)
+			names.AddNode("lonely")
+			names.AddEdge(`say "hi"`, "New York", DEFAULT_WEIGHT)
+			names.AddEdge("graph", "-1.5", DEFAULT_WEIGHT)
+			buf.Reset()
+			AssertNil(t, write(names, &buf))
+			x, err = parse(&buf, false)
+			AssertNil(t, err)
+			AssertFalse(t, x.Weighted)
+			slices.Sort(x.Nodes)
+			CollectionAssertEqual(t, x.Nodes, []string{"-1.5", "New York", "graph", "lonely", `say "hi"`})
+			AssertEqual(t, x.Edges[0], InterchangeEdge{`say "hi"`, "New York", DEFAULT_WEIGHT})
+		})
+
+		t.Run(name+" extreme weights", func(t *testing.T) {
+			weights := []float64{1e-300, 5e-324, 1.5e300, -2.5e-7, 0.1}
+			x := NewInterchange(true, true)
+			for i, w := range weights {
+				x.AddEdge("0", strconv.Itoa(i+1), w)
+			}
+			var buf bytes.Buffer
+			AssertNil(t, write(x, &buf))
+			x2, err := parse(&buf, true)
+			AssertNil(t, err)
+			AssertEqual(t, len(x2.Edges), len(weights))
+			for i, e := range x2.Edges {
+				AssertEqual(t, e.Weight, weights[i])
+			}
+		})
+	}
+
+	t.Run("DOT infinite weights", func(t *testing.T) {
+		x := NewInterchange(true, true)
+		x.AddEdge("a", "b", math.Inf(1))
+		x.AddEdge("b", "c", math.Inf(-1))
+		var buf bytes.Buffer
+		AssertNil(t, x.WriteDOT(&buf))
+		x2, err := ParseDOT(&buf)
+		AssertNil(t, err)
+		AssertTrue(t, math.IsInf(x2.Edges[0].Weight, 1))
+		AssertTrue(t, math.IsInf(x2.Edges[1].Weight, -1))
+	})
+
+	t.Run("Parse DOT", func(t *testing.T) {
+		src := `/* dependencies */
+strict digraph deps {
+	graph [rankdir=LR];
+	node [shape=box]
+	// a chain
+	0 -> 1 -> 2 [weight=2.5, color="red"];
+	"3"; 4
+	rankdir = TB
+# preprocessor line
+	2 -> "0"
+}`
+		x, err := ParseDOT(strings.NewReader(src))
+		AssertNil(t, err)
+		AssertTrue(t, x.Directed)
+		AssertTrue(t, x.Weighted)
+		CollectionAssertEqual(t, x.Nodes, []string{"0", "1", "2", "3", "4"})
+		CollectionAssertEqual(t, x.Edges, []InterchangeEdge{{"0", "1", 2.5}, {"1", "2", 2.5}, {"2", "0", DEFAULT_WEIGHT}})
+
+		for _, bad := range []string{
+			"digraph { a -- b }",
+			"graph { subgraph s { a } }",
+			"graph { a -- }",
+			`graph { "a }`,
+			"graph { a [weight=x] -- b }",
+			"graph { a -- b [weight=x] }",
+			"tree { a }",
+			"graph { a } b",
+		} {
+			_, err := ParseDOT(strings.NewReader(bad))
+			AssertNotEqual(t, err, nil)
+		}
+	})
+
+	t.Run("Parse GraphML", func(t *testing.T) {
+		src := `<?xml version="1.0" encoding="UTF-8"?>
+<graphml xmlns="http://graphml.graphdrawing.org/xmlns">
+  <key id="d0" for="node" attr.name="color" attr.type="string"/>
+  <key id="d1" for="edge" attr.name="weight" attr.type="double"/>
+  <graph id="G" edgedefault="undirected">
+    <node id="n0"><data key="d0">green</data></node>
+    <node id="n1"/>
+    <node id="n2"/>
+    <edge source="n0" target="n2"><data key="d1">1.5</data></edge>
+    <edge source="n1" target="n2"/>
+  </graph>
+</graphml>`
+		x, err := ParseGraphML(strings.NewReader(src))
+		AssertNil(t, err)
+		AssertFalse(t, x.Directed)
+		CollectionAssertEqual(t, x.Edges, []InterchangeEdge{{"n0", "n2", 1.5}, {"n1", "n2", DEFAULT_WEIGHT}})
+		_, err = x.Graph()
+		AssertNotEqual(t, err, nil)
+	})
+
+	t.Run("Graph shortcuts", func(t *testing.T) {
+		g, _ := NewGraph(3, true)
+		_ = g.AddEdge(0, 2)
+		var buf bytes.Buffer
+		AssertNil(t, g.WriteDOT(&buf))
+		AssertEqual(t, buf.String(), "digraph {\n  0;\n  1;\n  2;\n  0 -> 2;\n}\n")
+		g2, err := ReadDOT(&buf)
+		AssertNil(t, err)
+		AssertTrue(t, g2.Equals(g))
+
+		buf.Reset()
+		AssertNil(t, g.WriteEdgeList(&buf))
+		AssertEqual(t, buf.String(), "0 2\n1\n")
+		g2, _ = ReadEdgeList(&buf, true)
+		AssertTrue(t, g2.Equals(g))
+
+		buf.Reset()
+		AssertNil(t, g.WriteJSON(&buf))
+		g2, _ = ReadJSON(&buf)
+		AssertTrue(t, g2.Equals(g))
+
+		buf.Reset()
+		AssertNil(t, g.WriteGraphML(&buf))
+		g2, _ = ReadGraphML(&buf)
+		AssertTrue(t, g2.Equals(g))
+
+		_, err = ReadEdgeList(strings.NewReader("0 1 2 3\n"), false)
+		AssertNotEqual(t, err, nil)
+		_, err = ReadEdgeList(strings.NewReader("0 x\n"), false)
+		AssertNotEqual(t, err, nil)
+		_, err = ReadEdgeList(strings.NewReader("0 99999999999999\n"), false)
+		AssertNotEqual(t, err, nil)
+		_, err = ReadEdgeList(strings.NewReader("0 9223372036854775807\n"), false)
+		AssertNotEqual(t, err, nil)
+		_, err = ReadJSON(strings.NewReader(`{"nodes": ["0"], "adjacency": [[], []]}`))
+		AssertNotEqual(t, err, nil)
+	})
+}
+
+// edgeSet returns the edges of g sorted, undirected ones with the smaller vertex first.
+func edgeSet(g *Graph) [][2]int {
+	var edges [][2]int
+	for _, e := range g.Interchange(nil).Edges {
+		v, _ := strconv.Atoi(e.From)
+		w, _ := strconv.Atoi(e.To)
+		if !g.IsDirected() && v > w {
+			v, w = w, v
+		}
+		edges = append(edges, [2]int{v, w})
+	}
+	slices.SortFunc(edges, func(a, b [2]int) int { return cmp.Or(cmp.Compare(a[0], b[0]), cmp.Compare(a[1], b[1])) })
+	return edges
+}
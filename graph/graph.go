@@ -112,6 +112,14 @@ func readEdge(e string) (v, w int, err error) {
 	return v, w, nil
 }
 
+func (g *Graph) V() int {
+	return g.v
+}
+
+func (g *Graph) E() int {
+	return g.e
+}
+
 func (g *Graph) Adj(v int) ([]int, error) {
 	if v < 0 || v >= g.v {
 		return nil, errors.New("vertex out of bounds")
@@ -0,0 +1,239 @@
+package graph
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/jnsoft/jngo/queue"
+)
+
+var ErrNotBipartite = errors.New("graph is not bipartite")
+
+type (
+	// Matching is a set of edges of an undirected graph without common vertices.
+	Matching struct {
+		mate []int // mate[v] = vertex matched to v, -1 if v is free
+		size int
+	}
+
+	// Assignment maps each row of a cost matrix to a distinct column.
+	Assignment struct {
+		columns []int // columns[r] = column assigned to row r
+		cost    float64
+	}
+)
+
+// --- Hopcroft-Karp ---
+
+// HopcroftKarp finds a maximum matching of a bipartite graph in O(E*sqrt(V)). Each phase
+// augments along a maximal set of vertex-disjoint shortest alternating paths.
+func (g *Graph) HopcroftKarp() (*Matching, error) {
+	b, err := g.Bipartite()
+	if err != nil {
+		return nil, err
+	}
+	if !b.IsBipartite() {
+		return nil, ErrNotBipartite
+	}
+	m := &Matching{mate: make([]int, g.v)}
+	for v := range m.mate {
+		m.mate[v] = -1
+	}
+	var left []int // the side the alternating paths start from
+	for v := 0; v < g.v; v++ {
+		if !b.Color(v) {
+			left = append(left, v)
+		}
+	}
+
+	dist := make([]int, g.v) // dist[v] = length of the shortest alternating path to the left vertex v
+	next := make([]int, g.v) // next[v] = first edge of v not yet tried in this phase
+	for free := m.layers(g, left, dist); free != math.MaxInt; free = m.layers(g, left, dist) {
+		clear(next)
+		for _, v := range left {
+			if m.mate[v] == -1 && m.augment(g, v, free, dist, next) {
+				m.size++
+			}
+		}
+	}
+	return m, nil
+}
+
+// layers runs a breadth-first search from the free left vertices, alternating between unmatched
+// and matched edges. It stops at the first layer with an edge to a free right vertex and returns
+// that layer, math.MaxInt if there is none, so a phase only augments along shortest paths.
+func (m *Matching) layers(g *Graph, left []int, dist []int) int {
+	q := queue.New[int]()
+	for _, v := range left {
+		if m.mate[v] == -1 {
+			dist[v] = 0
+			q.Enqueue(v)
+		} else {
+			dist[v] = math.MaxInt
+		}
+	}
+	free := math.MaxInt
+	for !q.IsEmpty() {
+		v := q.Dequeue()
+		if dist[v] > free {
+			break
+		}
+		for _, w := range g.adjacencyList[v] {
+			u := m.mate[w]
+			if u == -1 {
+				free = dist[v]
+			} else if dist[u] == math.MaxInt {
+				dist[u] = dist[v] + 1
+				q.Enqueue(u)
+			}
+		}
+	}
+	return free
+}
+
+// augment looks for an alternating path from the left vertex v to a free right vertex that
+// follows the layers and ends in layer free, and flips it.
+func (m *Matching) augment(g *Graph, v, free int, dist, next []int) bool {
+	for ; next[v] < len(g.adjacencyList[v]); next[v]++ {
+		w := g.adjacencyList[v][next[v]]
+		u := m.mate[w]
+		if (u == -1 && dist[v] == free) || (u != -1 && dist[u] == dist[v]+1 && m.augment(g, u, free, dist, next)) {
+			m.mate[v], m.mate[w] = w, v
+			return true
+		}
+	}
+	dist[v] = math.MaxInt // dead end for the rest of the phase
+	return false
+}
+
+// number of matched edges
+func (m *Matching) Size() int {
+	return m.size
+}
+
+// Mate returns the vertex matched to v, -1 if v is free.
+func (m *Matching) Mate(v int) int {
+	return m.mate[v]
+}
+
+func (m *Matching) IsMatched(v int) bool {
+	return m.mate[v] != -1
+}
+
+// IsPerfect reports whether every vertex is matched.
+func (m *Matching) IsPerfect() bool {
+	return 2*m.size == len(m.mate)
+}
+
+// Edges returns the matched edges as vertex pairs, the smaller vertex first.
+func (m *Matching) Edges() [][2]int {
+	edges := make([][2]int, 0, m.size)
+	for v, w := range m.mate {
+		if v < w {
+			edges = append(edges, [2]int{v, w})
+		}
+	}
+	return edges
+}
+
+// --- Hungarian algorithm ---
+
+// HungarianAssignment assigns every row of cost to a distinct column so that the total cost is
+// minimal, in O(n^2*m) for n rows and m >= n columns. Use +Inf for forbidden pairs.
+func HungarianAssignment(cost [][]float64) (*Assignment, error) {
+	n := len(cost)
+	if n == 0 {
+		return &Assignment{}, nil
+	}
+	m := len(cost[0])
+	for r, row := range cost {
+		if len(row) != m {
+			return nil, fmt.Errorf("row %d has %d columns, want %d", r, len(row), m)
+		}
+		for _, c := range row {
+			if math.IsNaN(c) || math.IsInf(c, -1) {
+				return nil, fmt.Errorf("invalid cost in row %d: %v", r, c)
+			}
+		}
+	}
+	if n > m {
+		return nil, fmt.Errorf("more rows than columns: %d > %d", n, m)
+	}
+
+	// potentials u (rows) and v (columns) keep cost[r][c] - u[r] - v[c] >= 0, with equality on the
+	// assigned pairs. Rows and columns are 1-based, column 0 is a virtual column for the new row.
+	u := make([]float64, n+1)
+	v := make([]float64, m+1)
+	rowOf := make([]int, m+1) // rowOf[c] = row assigned to column c, 0 if none
+	way := make([]int, m+1)   // way[c] = previous column on the alternating path to c
+	minSlack := make([]float64, m+1)
+	used := make([]bool, m+1)
+
+	for r := 1; r <= n; r++ {
+		rowOf[0] = r
+		c0 := 0
+		for c := range minSlack {
+			minSlack[c] = math.Inf(1)
+			used[c] = false
+		}
+		for rowOf[c0] != 0 {
+			used[c0] = true
+			r0, delta, c1 := rowOf[c0], math.Inf(1), -1
+			for c := 1; c <= m; c++ {
+				if used[c] {
+					continue
+				}
+				if slack := cost[r0-1][c-1] - u[r0] - v[c]; slack < minSlack[c] {
+					minSlack[c] = slack
+					way[c] = c0
+				}
+				if minSlack[c] < delta {
+					delta, c1 = minSlack[c], c
+				}
+			}
+			if c1 == -1 || math.IsInf(delta, 1) {
+				return nil, fmt.Errorf("row %d cannot be assigned a column with finite cost", r-1)
+			}
+			for c := 0; c <= m; c++ {
+				if used[c] {
+					u[rowOf[c]] += delta
+					v[c] -= delta
+				} else {
+					minSlack[c] -= delta
+				}
+			}
+			c0 = c1
+		}
+		// flip the alternating path back to the virtual column
+		for c0 != 0 {
+			c1 := way[c0]
+			rowOf[c0] = rowOf[c1]
+			c0 = c1
+		}
+	}
+
+	a := &Assignment{columns: make([]int, n)}
+	for c := 1; c <= m; c++ {
+		if rowOf[c] != 0 {
+			a.columns[rowOf[c]-1] = c - 1
+			a.cost += cost[rowOf[c]-1][c-1]
+		}
+	}
+	return a, nil
+}
+
+// Columns returns the column assigned to each row.
+func (a *Assignment) Columns() []int {
+	return a.columns
+}
+
+// Column returns the column assigned to row r.
+func (a *Assignment) Column(r int) int {
+	return a.columns[r]
+}
+
+// Cost returns the total cost of the assignment.
+func (a *Assignment) Cost() float64 {
+	return a.cost
+}
@@ -0,0 +1,125 @@
+package graph
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+
+	. "github.com/jnsoft/jngo/testhelper"
+)
+
+func TestMatching(t *testing.T) {
+
+	t.Run("Hopcroft-Karp", func(t *testing.T) {
+		// workers 0..3, jobs 4..7
+		g, _ := NewGraph(8, false)
+		for _, e := range [][2]int{{0, 4}, {0, 5}, {1, 4}, {2, 5}, {2, 6}, {3, 6}, {3, 7}} {
+			_ = g.AddEdge(e[0], e[1])
+		}
+		m, err := g.HopcroftKarp()
+		AssertNil(t, err)
+		AssertEqual(t, m.Size(), 4)
+		AssertTrue(t, m.IsPerfect())
+		AssertEqual(t, m.Mate(1), 4)
+		AssertEqual(t, m.Mate(4), 1)
+		for _, e := range m.Edges() {
+			adj, _ := g.Adj(e[0])
+			AssertTrue(t, contains(adj, e[1]))
+		}
+
+		odd, _ := fromEdgeList(tinyG, false)
+		_, err = odd.HopcroftKarp()
+		AssertTrue(t, errors.Is(err, ErrNotBipartite))
+	})
+
+	t.Run("Hopcroft-Karp agrees with max flow", func(t *testing.T) {
+		rnd := rand.New(rand.NewSource(3))
+		for round := 0; round < 20; round++ {
+			l, r := 1+rnd.Intn(20), 1+rnd.Intn(20)
+			g, _ := NewGraph(l+r, false)
+			fn, _ := NewFlowNetwork(l + r + 2)
+			source, sink := l+r, l+r+1
+			for v := 0; v < l; v++ {
+				_ = fn.AddEdge(source, v, 1)
+			}
+			for w := l; w < l+r; w++ {
+				_ = fn.AddEdge(w, sink, 1)
+			}
+			for i := 0; i < 2*(l+r); i++ {
+				v, w := rnd.Intn(l), l+rnd.Intn(r)
+				_ = g.AddEdge(v, w)
+				_ = fn.AddEdge(v, w, 1)
+			}
+			m, _ := g.HopcroftKarp()
+			mf, _ := fn.Dinic(source, sink)
+			AssertEqual(t, float64(m.Size()), mf.Value())
+			matched := make(map[int]bool)
+			for _, e := range m.Edges() {
+				AssertFalse(t, matched[e[0]] || matched[e[1]])
+				matched[e[0]], matched[e[1]] = true, true
+			}
+		}
+	})
+
+	t.Run("Hungarian", func(t *testing.T) {
+		cost := [][]float64{
+			{9, 2, 7, 8},
+			{6, 4, 3, 7},
+			{5, 8, 1, 8},
+			{7, 6, 9, 4},
+		}
+		a, err := HungarianAssignment(cost)
+		AssertNil(t, err)
+		AssertTrue(t, approx(a.Cost(), 13))
+		CollectionAssertEqual(t, a.Columns(), []int{1, 0, 2, 3})
+
+		rect, err := HungarianAssignment([][]float64{{3, 1, 9}, {1, 2, 9}})
+		AssertNil(t, err)
+		AssertTrue(t, approx(rect.Cost(), 2))
+		AssertEqual(t, rect.Column(0), 1)
+
+		inf := math.Inf(1)
+		forbidden, err := HungarianAssignment([][]float64{{inf, 1}, {1, 5}})
+		AssertNil(t, err)
+		AssertTrue(t, approx(forbidden.Cost(), 2))
+
+		_, err = HungarianAssignment([][]float64{{inf, inf}, {1, 5}})
+		AssertNotEqual(t, err, nil)
+		_, err = HungarianAssignment([][]float64{{1, 2}, {1}})
+		AssertNotEqual(t, err, nil)
+		_, err = HungarianAssignment([][]float64{{1}, {2}})
+		AssertNotEqual(t, err, nil)
+	})
+
+	t.Run("Hungarian agrees with brute force", func(t *testing.T) {
+		rnd := rand.New(rand.NewSource(5))
+		for round := 0; round < 50; round++ {
+			n := 1 + rnd.Intn(6)
+			cost := make([][]float64, n)
+			for r := range cost {
+				cost[r] = make([]float64, n)
+				for c := range cost[r] {
+					cost[r][c] = float64(rnd.Intn(100)) - 20
+				}
+			}
+			a, _ := HungarianAssignment(cost)
+			AssertTrue(t, approx(a.Cost(), bruteForceAssignment(cost, 0, make([]bool, n))))
+		}
+	})
+}
+
+func bruteForceAssignment(cost [][]float64, r int, used []bool) float64 {
+	if r == len(cost) {
+		return 0
+	}
+	best := math.Inf(1)
+	for c, u := range used {
+		if !u {
+			used[c] = true
+			best = min(best, cost[r][c]+bruteForceAssignment(cost, r+1, used))
+			used[c] = false
+		}
+	}
+	return best
+}
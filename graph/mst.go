@@ -0,0 +1,135 @@
+package graph
+
+import (
+	"cmp"
+	"math"
+	"slices"
+
+	"github.com/jnsoft/jngo/pqueue"
+	"github.com/jnsoft/jngo/unionfind"
+)
+
+// MinimumSpanningTree holds a minimum spanning forest, one tree per connected component.
+type MinimumSpanningTree struct {
+	edges  []Edge
+	weight float64
+}
+
+// Kruskal adds the edges in increasing order of weight, skipping those that would close a cycle.
+func (g *EdgeWeightedGraph) Kruskal() (*MinimumSpanningTree, error) {
+	if g.isDirected {
+		return nil, ErrDirected
+	}
+	edges := g.Edges()
+	slices.SortStableFunc(edges, func(a, b Edge) int { return cmp.Compare(a.Weight, b.Weight) })
+	uf, _ := unionfind.NewUnionFind(g.v)
+	mst := &MinimumSpanningTree{}
+	for _, e := range edges {
+		if len(mst.edges) == g.v-1 {
+			break
+		}
+		if merged, _ := uf.Union(e.From, e.To); merged {
+			mst.add(e)
+		}
+	}
+	return mst, nil
+}
+
+// LazyPrim grows the tree from each unvisited vertex, keeping all crossing edges in a priority
+// queue and discarding the stale ones when they are dequeued.
+func (g *EdgeWeightedGraph) LazyPrim() (*MinimumSpanningTree, error) {
+	if g.isDirected {
+		return nil, ErrDirected
+	}
+	mst := &MinimumSpanningTree{}
+	marked := make([]bool, g.v)
+	pq := pqueue.NewPriorityQueue(func(a, b Edge) bool { return a.Weight < b.Weight })
+	visit := func(v int) {
+		marked[v] = true
+		for _, e := range g.adjacencyList[v] {
+			if !marked[e.Other(v)] {
+				pq.Enqueue(e)
+			}
+		}
+	}
+	for s := 0; s < g.v; s++ {
+		if marked[s] {
+			continue
+		}
+		visit(s)
+		for !pq.IsEmpty() {
+			e, _ := pq.Dequeue()
+			v, w := e.From, e.To
+			if marked[v] && marked[w] {
+				continue
+			}
+			mst.add(e)
+			if !marked[v] {
+				visit(v)
+			}
+			if !marked[w] {
+				visit(w)
+			}
+		}
+	}
+	return mst, nil
+}
+
+// EagerPrim keeps only the lightest edge to each vertex outside the tree in an indexed priority
+// queue, so the queue never holds more than V entries.
+func (g *EdgeWeightedGraph) EagerPrim() (*MinimumSpanningTree, error) {
+	if g.isDirected {
+		return nil, ErrDirected
+	}
+	edgeTo := make([]Edge, g.v)    // edgeTo[v] = lightest edge from the tree to v
+	distTo := make([]float64, g.v) // distTo[v] = weight of edgeTo[v]
+	marked := make([]bool, g.v)
+	for v := range distTo {
+		distTo[v] = math.Inf(1)
+	}
+	mst := &MinimumSpanningTree{}
+	pq := pqueue.NewIndexedPriorityQueue(g.v, func(a, b float64) bool { return a < b })
+	for s := 0; s < g.v; s++ {
+		if marked[s] {
+			continue
+		}
+		distTo[s] = 0
+		_ = pq.Insert(s, 0)
+		for !pq.IsEmpty() {
+			v, _, _ := pq.Dequeue()
+			marked[v] = true
+			if v != s {
+				mst.add(edgeTo[v])
+			}
+			for _, e := range g.adjacencyList[v] {
+				w := e.Other(v)
+				if marked[w] || e.Weight >= distTo[w] {
+					continue
+				}
+				edgeTo[w] = e
+				distTo[w] = e.Weight
+				if pq.Contains(w) {
+					_ = pq.DecreaseKey(w, e.Weight)
+				} else {
+					_ = pq.Insert(w, e.Weight)
+				}
+			}
+		}
+	}
+	return mst, nil
+}
+
+// Edges returns the edges of the spanning forest in the order they were added.
+func (mst *MinimumSpanningTree) Edges() []Edge {
+	return mst.edges
+}
+
+// Weight returns the sum of the edge weights.
+func (mst *MinimumSpanningTree) Weight() float64 {
+	return mst.weight
+}
+
+func (mst *MinimumSpanningTree) add(e Edge) {
+	mst.edges = append(mst.edges, e)
+	mst.weight += e.Weight
+}
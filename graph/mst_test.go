@@ -0,0 +1,102 @@
+package graph
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+
+	. "github.com/jnsoft/jngo/testhelper"
+)
+
+const tinyEWG = `8
+16
+4 5 0.35
+4 7 0.37
+5 7 0.28
+0 7 0.16
+1 5 0.32
+0 4 0.38
+2 3 0.17
+1 7 0.19
+0 2 0.26
+1 2 0.36
+1 3 0.29
+2 7 0.34
+6 2 0.40
+3 6 0.52
+6 0 0.58
+6 4 0.93
+`
+
+func TestMinimumSpanningTree(t *testing.T) {
+	algorithms := map[string]func(*EdgeWeightedGraph) (*MinimumSpanningTree, error){
+		"Kruskal":    (*EdgeWeightedGraph).Kruskal,
+		"Lazy Prim":  (*EdgeWeightedGraph).LazyPrim,
+		"Eager Prim": (*EdgeWeightedGraph).EagerPrim,
+	}
+
+	for name, mst := range algorithms {
+		t.Run(name, func(t *testing.T) {
+			g, _ := NewEdgeWeightedGraphFromString(tinyEWG, false)
+			tree, err := mst(g)
+			AssertNil(t, err)
+			AssertTrue(t, approx(tree.Weight(), 1.81))
+			AssertEqual(t, len(tree.Edges()), 7)
+			for _, e := range []Edge{{0, 7, 0.16}, {2, 3, 0.17}, {1, 7, 0.19}, {0, 2, 0.26}, {5, 7, 0.28}, {4, 5, 0.35}, {6, 2, 0.40}} {
+				AssertTrue(t, containsEdge(tree.Edges(), e))
+			}
+
+			dg, _ := NewEdgeWeightedGraphFromString(tinyEWG, true)
+			_, err = mst(dg)
+			AssertTrue(t, errors.Is(err, ErrDirected))
+		})
+	}
+
+	t.Run("Spanning forest", func(t *testing.T) {
+		g, _ := NewEdgeWeightedGraph(5, false)
+		_ = g.AddEdge(Edge{0, 1, 1})
+		_ = g.AddEdge(Edge{1, 2, 2})
+		_ = g.AddEdge(Edge{0, 2, 3})
+		_ = g.AddEdge(Edge{3, 4, 4})
+		for _, mst := range algorithms {
+			tree, _ := mst(g)
+			AssertEqual(t, len(tree.Edges()), 3)
+			AssertTrue(t, approx(tree.Weight(), 7))
+		}
+	})
+
+	t.Run("Medium random graphs agree", func(t *testing.T) {
+		g, _ := NewEdgeWeightedGraphFromString(randomEWG(250, 1273), false)
+		kruskal, _ := g.Kruskal()
+		AssertEqual(t, len(kruskal.Edges()), g.V()-1)
+		for _, mst := range algorithms {
+			tree, _ := mst(g)
+			AssertTrue(t, approx(tree.Weight(), kruskal.Weight()))
+		}
+	})
+}
+
+// randomEWG returns a connected graph in the tinyEWG format, a random spanning tree plus extra edges.
+func randomEWG(v, e int) string {
+	rnd := rand.New(rand.NewSource(1))
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%d\n%d\n", v, e))
+	for w := 1; w < v; w++ {
+		sb.WriteString(fmt.Sprintf("%d %d %.5f\n", rnd.Intn(w), w, rnd.Float64()))
+	}
+	for i := v - 1; i < e; i++ {
+		sb.WriteString(fmt.Sprintf("%d %d %.5f\n", rnd.Intn(v), rnd.Intn(v), rnd.Float64()))
+	}
+	return sb.String()
+}
+
+func containsEdge(edges []Edge, e Edge) bool {
+	for _, x := range edges {
+		if x == e || (x.From == e.To && x.To == e.From && x.Weight == e.Weight) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,300 @@
+package graph
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/jnsoft/jngo/pqueue"
+	"github.com/jnsoft/jngo/stack"
+)
+
+var (
+	ErrNegativeWeight = errors.New("graph has a negative edge weight")
+	ErrNegativeCycle  = errors.New("graph has a negative cycle reachable from the source")
+)
+
+type (
+	// ShortestPaths holds a single-source shortest path tree.
+	ShortestPaths struct {
+		distTo []float64 // distTo[v] = length of the shortest s-v path, +Inf if there is none
+		edgeTo []Edge    // edgeTo[v] = last edge on the shortest s-v path, From is -1 if there is none
+		cycle  []Edge    // negative cycle found by Bellman-Ford
+		Source int
+	}
+
+	// AllPairsShortestPaths holds the shortest path lengths between all pairs of vertices.
+	AllPairsShortestPaths struct {
+		dist          [][]float64
+		next          [][]int // next[s][t] = vertex after s on the shortest s-t path, -1 if there is none
+		negativeCycle bool
+	}
+)
+
+// Dijkstra computes shortest paths from source. All weights must be nonnegative.
+func (g *EdgeWeightedGraph) Dijkstra(source int) (*ShortestPaths, error) {
+	return g.search(source, -1, nil)
+}
+
+// AStar computes a shortest path from source to target, guided by heuristic(v), an estimate of
+// the distance from v to target that must never exceed the true distance. Only paths to vertices
+// settled before target are final.
+func (g *EdgeWeightedGraph) AStar(source, target int, heuristic func(v int) float64) (*ShortestPaths, error) {
+	if target < 0 || target >= g.v {
+		return nil, fmt.Errorf("vertex out of bounds: %d", target)
+	}
+	return g.search(source, target, heuristic)
+}
+
+// BellmanFord computes shortest paths from source with any weights. If a negative cycle is
+// reachable from source it returns ErrNegativeCycle together with the paths, whose NegativeCycle
+// method returns the cycle. Vertices reachable from the cycle have no shortest path, their
+// distance is -Inf.
+func (g *EdgeWeightedGraph) BellmanFord(source int) (*ShortestPaths, error) {
+	sp, err := g.newShortestPaths(source)
+	if err != nil {
+		return nil, err
+	}
+	relax := func() int {
+		last := -1 // last vertex whose distance changed
+		for v := 0; v < g.v; v++ {
+			if math.IsInf(sp.distTo[v], 1) {
+				continue
+			}
+			for _, e := range g.outgoing(v) {
+				if d := sp.distTo[v] + e.Weight; d < sp.distTo[e.To] {
+					sp.distTo[e.To] = d
+					sp.edgeTo[e.To] = e
+					last = e.To
+				}
+			}
+		}
+		return last
+	}
+
+	for pass := 0; pass < g.v-1; pass++ {
+		if relax() == -1 {
+			return sp, nil
+		}
+	}
+	// an edge that still relaxes after V-1 passes means a negative cycle, and V steps back along the
+	// tree from the vertex it reached are on the cycle
+	if last := relax(); last != -1 {
+		sp.cycle = sp.cycleFrom(last)
+		g.markNegativeCycles(sp)
+		return sp, ErrNegativeCycle
+	}
+	return sp, nil
+}
+
+// FloydWarshall computes the shortest paths between all pairs of vertices in O(V^3).
+func (g *EdgeWeightedGraph) FloydWarshall() *AllPairsShortestPaths {
+	n := g.v
+	ap := &AllPairsShortestPaths{dist: make([][]float64, n), next: make([][]int, n)}
+	for s := 0; s < n; s++ {
+		ap.dist[s] = make([]float64, n)
+		ap.next[s] = make([]int, n)
+		for t := 0; t < n; t++ {
+			ap.dist[s][t] = math.Inf(1)
+			ap.next[s][t] = -1
+		}
+		ap.dist[s][s] = 0
+		ap.next[s][s] = s
+	}
+	for v := 0; v < n; v++ {
+		for _, e := range g.outgoing(v) {
+			if e.Weight < ap.dist[v][e.To] {
+				ap.dist[v][e.To] = e.Weight
+				ap.next[v][e.To] = e.To
+			}
+		}
+	}
+
+	for k := 0; k < n; k++ {
+		for s := 0; s < n; s++ {
+			if math.IsInf(ap.dist[s][k], 1) {
+				continue
+			}
+			for t := 0; t < n; t++ {
+				if d := ap.dist[s][k] + ap.dist[k][t]; d < ap.dist[s][t] {
+					ap.dist[s][t] = d
+					ap.next[s][t] = ap.next[s][k]
+				}
+			}
+		}
+	}
+	for v := 0; v < n; v++ {
+		if ap.dist[v][v] < 0 {
+			ap.negativeCycle = true
+		}
+	}
+	return ap
+}
+
+// is there a shortest path from the source to v?
+func (sp *ShortestPaths) HasPathTo(v int) bool {
+	return !math.IsInf(sp.distTo[v], 0)
+}
+
+// length of the shortest path from the source to v, +Inf if there is none, -Inf if v is
+// reachable from a negative cycle
+func (sp *ShortestPaths) DistTo(v int) float64 {
+	return sp.distTo[v]
+}
+
+// vertices on the shortest path from the source to v
+func (sp *ShortestPaths) PathTo(v int) []int {
+	if !sp.HasPathTo(v) {
+		return nil
+	}
+	path := stack.New[int]()
+	for i := v; i != sp.Source; i = sp.edgeTo[i].From {
+		path.Push(i)
+	}
+	path.Push(sp.Source)
+	return path.ToArray()
+}
+
+// edges on the shortest path from the source to v
+func (sp *ShortestPaths) EdgesTo(v int) []Edge {
+	if !sp.HasPathTo(v) {
+		return nil
+	}
+	path := stack.New[Edge]()
+	for i := v; i != sp.Source; i = sp.edgeTo[i].From {
+		path.Push(sp.edgeTo[i])
+	}
+	return path.ToArray()
+}
+
+// NegativeCycle returns the negative cycle found by BellmanFord, nil if there is none.
+func (sp *ShortestPaths) NegativeCycle() []Edge {
+	return sp.cycle
+}
+
+func (ap *AllPairsShortestPaths) HasNegativeCycle() bool {
+	return ap.negativeCycle
+}
+
+func (ap *AllPairsShortestPaths) HasPath(s, t int) bool {
+	return !math.IsInf(ap.dist[s][t], 1)
+}
+
+// Dist returns the length of the shortest s-t path, +Inf if there is none.
+func (ap *AllPairsShortestPaths) Dist(s, t int) float64 {
+	return ap.dist[s][t]
+}
+
+// Path returns the vertices on the shortest s-t path. Paths are undefined with negative cycles.
+func (ap *AllPairsShortestPaths) Path(s, t int) []int {
+	if !ap.HasPath(s, t) || ap.negativeCycle {
+		return nil
+	}
+	path := []int{s}
+	for v := s; v != t; {
+		v = ap.next[v][t]
+		path = append(path, v)
+	}
+	return path
+}
+
+// helpers
+
+func (g *EdgeWeightedGraph) newShortestPaths(source int) (*ShortestPaths, error) {
+	if source < 0 || source >= g.v {
+		return nil, fmt.Errorf("vertex out of bounds: %d", source)
+	}
+	sp := &ShortestPaths{
+		Source: source,
+		distTo: make([]float64, g.v),
+		edgeTo: make([]Edge, g.v),
+	}
+	for v := 0; v < g.v; v++ {
+		sp.distTo[v] = math.Inf(1)
+		sp.edgeTo[v] = Edge{From: -1, To: v}
+	}
+	sp.distTo[source] = 0
+	return sp, nil
+}
+
+// markNegativeCycles sets the distance of every vertex reachable from a negative cycle to -Inf
+// and drops its tree edge, so paths are never followed around the cycle.
+func (g *EdgeWeightedGraph) markNegativeCycles(sp *ShortestPaths) {
+	for changed := true; changed; {
+		changed = false
+		for v := 0; v < g.v; v++ {
+			if math.IsInf(sp.distTo[v], 1) {
+				continue
+			}
+			for _, e := range g.outgoing(v) {
+				w := e.To
+				if math.IsInf(sp.distTo[w], -1) {
+					continue
+				}
+				if math.IsInf(sp.distTo[v], -1) || sp.distTo[v]+e.Weight < sp.distTo[w] {
+					sp.distTo[w] = math.Inf(-1)
+					sp.edgeTo[w] = Edge{From: -1, To: w}
+					changed = true
+				}
+			}
+		}
+	}
+}
+
+// cycleFrom returns the cycle in the edgeTo pointers that the walk back from v ends on.
+func (sp *ShortestPaths) cycleFrom(v int) []Edge {
+	for range sp.edgeTo {
+		v = sp.edgeTo[v].From
+	}
+	cycle := stack.New[Edge]()
+	for e := sp.edgeTo[v]; ; e = sp.edgeTo[e.From] {
+		cycle.Push(e)
+		if e.From == v {
+			break
+		}
+	}
+	return cycle.ToArray()
+}
+
+// search is Dijkstra, or A* when heuristic is set, stopping at target if it is not -1.
+func (g *EdgeWeightedGraph) search(source, target int, heuristic func(int) float64) (*ShortestPaths, error) {
+	sp, err := g.newShortestPaths(source)
+	if err != nil {
+		return nil, err
+	}
+	for v := 0; v < g.v; v++ {
+		for _, e := range g.adjacencyList[v] {
+			if e.Weight < 0 {
+				return nil, ErrNegativeWeight
+			}
+		}
+	}
+	priority := func(v int) float64 {
+		if heuristic == nil {
+			return sp.distTo[v]
+		}
+		return sp.distTo[v] + heuristic(v)
+	}
+
+	pq := pqueue.NewIndexedPriorityQueue[float64](g.v, func(a, b float64) bool { return a < b })
+	_ = pq.Insert(source, priority(source))
+	for !pq.IsEmpty() {
+		v, _, _ := pq.Dequeue()
+		if v == target {
+			break
+		}
+		for _, e := range g.outgoing(v) {
+			w := e.To
+			if d := sp.distTo[v] + e.Weight; d < sp.distTo[w] {
+				sp.distTo[w] = d
+				sp.edgeTo[w] = e
+				if pq.Contains(w) {
+					_ = pq.DecreaseKey(w, priority(w))
+				} else {
+					_ = pq.Insert(w, priority(w))
+				}
+			}
+		}
+	}
+	return sp, nil
+}
@@ -0,0 +1,233 @@
+package graph
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+
+	. "github.com/jnsoft/jngo/testhelper"
+)
+
+const tinyEWD = `8
+15
+4 5 0.35
+5 4 0.35
+4 7 0.37
+5 7 0.28
+7 5 0.28
+5 1 0.32
+0 4 0.38
+0 2 0.26
+7 3 0.39
+1 3 0.29
+2 7 0.34
+6 2 0.40
+3 6 0.52
+6 0 0.58
+6 4 0.93
+`
+
+const tinyEWDn = `8
+15
+4 5 0.35
+5 4 0.35
+4 7 0.37
+5 7 0.28
+7 5 0.28
+5 1 0.32
+0 4 0.38
+0 2 0.26
+7 3 0.39
+1 3 0.29
+2 7 0.34
+6 2 -1.20
+3 6 0.52
+6 0 -1.40
+6 4 -1.25
+`
+
+const tinyEWDnc = `8
+15
+4 5 0.35
+5 4 -0.66
+4 7 0.37
+5 7 0.28
+7 5 0.28
+5 1 0.32
+0 4 0.38
+0 2 0.26
+7 3 0.39
+1 3 0.29
+2 7 0.34
+6 2 0.40
+3 6 0.52
+6 0 0.58
+6 4 0.93
+`
+
+func approx(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestShortestPaths(t *testing.T) {
+	// distances from 0 in tinyEWD
+	want := []float64{0, 1.05, 0.26, 0.99, 0.38, 0.73, 1.51, 0.60}
+
+	t.Run("Parse", func(t *testing.T) {
+		g, err := NewEdgeWeightedGraphFromString(tinyEWD, true)
+		AssertNil(t, err)
+		AssertEqual(t, g.V(), 8)
+		AssertEqual(t, g.E(), 15)
+		g2, err := NewEdgeWeightedGraphFromString(g.String(), true)
+		AssertNil(t, err)
+		AssertEqual(t, g2.String(), g.String())
+
+		_, err = NewEdgeWeightedGraphFromString("2\n2\n0 1 0.5\n", false)
+		AssertTrue(t, err != nil)
+	})
+
+	t.Run("Dijkstra", func(t *testing.T) {
+		g, _ := NewEdgeWeightedGraphFromString(tinyEWD, true)
+		sp, err := g.Dijkstra(0)
+		AssertNil(t, err)
+		for v, d := range want {
+			AssertTrue(t, approx(sp.DistTo(v), d))
+		}
+		CollectionAssertEqual(t, sp.PathTo(6), []int{0, 2, 7, 3, 6})
+		edges := sp.EdgesTo(6)
+		AssertEqual(t, len(edges), 4)
+		AssertEqual(t, edges[0], Edge{0, 2, 0.26})
+
+		neg, _ := NewEdgeWeightedGraphFromString(tinyEWDn, true)
+		_, err = neg.Dijkstra(0)
+		AssertTrue(t, errors.Is(err, ErrNegativeWeight))
+	})
+
+	t.Run("Undirected and unreachable", func(t *testing.T) {
+		g, _ := NewEdgeWeightedGraph(4, false)
+		_ = g.AddEdge(Edge{0, 1, 2})
+		_ = g.AddEdge(Edge{2, 1, 1})
+		sp, _ := g.Dijkstra(2)
+		CollectionAssertEqual(t, sp.PathTo(0), []int{2, 1, 0})
+		AssertTrue(t, approx(sp.DistTo(0), 3))
+		AssertFalse(t, sp.HasPathTo(3))
+		AssertTrue(t, sp.PathTo(3) == nil)
+		AssertEqual(t, len(g.Edges()), 2)
+	})
+
+	t.Run("Bellman-Ford", func(t *testing.T) {
+		g, _ := NewEdgeWeightedGraphFromString(tinyEWD, true)
+		sp, err := g.BellmanFord(0)
+		AssertNil(t, err)
+		for v, d := range want {
+			AssertTrue(t, approx(sp.DistTo(v), d))
+		}
+
+		neg, _ := NewEdgeWeightedGraphFromString(tinyEWDn, true)
+		sp, err = neg.BellmanFord(0)
+		AssertNil(t, err)
+		AssertTrue(t, approx(sp.DistTo(6), 1.51))
+		AssertTrue(t, approx(sp.DistTo(4), 0.26))
+		CollectionAssertEqual(t, sp.PathTo(4), []int{0, 2, 7, 3, 6, 4})
+
+		cyc, _ := NewEdgeWeightedGraphFromString(tinyEWDnc, true)
+		sp, err = cyc.BellmanFord(0)
+		AssertTrue(t, errors.Is(err, ErrNegativeCycle))
+		cycle := sp.NegativeCycle()
+		AssertEqual(t, len(cycle), 2)
+		weight := 0.0
+		for i, e := range cycle {
+			weight += e.Weight
+			AssertEqual(t, e.To, cycle[(i+1)%len(cycle)].From)
+		}
+		AssertTrue(t, weight < 0)
+
+		// paths through the cycle do not exist
+		loop, _ := NewEdgeWeightedGraph(4, true)
+		_ = loop.AddEdge(Edge{0, 1, 1})
+		_ = loop.AddEdge(Edge{1, 2, -3})
+		_ = loop.AddEdge(Edge{2, 1, 1})
+		_ = loop.AddEdge(Edge{0, 3, 2})
+		sp, err = loop.BellmanFord(0)
+		AssertTrue(t, errors.Is(err, ErrNegativeCycle))
+		for _, v := range []int{1, 2} {
+			AssertFalse(t, sp.HasPathTo(v))
+			AssertTrue(t, math.IsInf(sp.DistTo(v), -1))
+			AssertTrue(t, sp.PathTo(v) == nil)
+			AssertTrue(t, sp.EdgesTo(v) == nil)
+		}
+		CollectionAssertEqual(t, sp.PathTo(3), []int{0, 3})
+		AssertTrue(t, approx(sp.DistTo(3), 2))
+	})
+
+	t.Run("Bellman-Ford finds the negative cycle", func(t *testing.T) {
+		r := rand.New(rand.NewSource(1))
+		found := 0
+		for i := 0; i < 500; i++ {
+			// a ring keeps every vertex reachable from 0, so any negative cycle must be found
+			n := 2 + r.Intn(10)
+			g, _ := NewEdgeWeightedGraph(n, true)
+			for v := 0; v < n; v++ {
+				_ = g.AddEdge(Edge{v, (v + 1) % n, 1 + r.Float64()})
+			}
+			for j := r.Intn(2 * n); j > 0; j-- {
+				_ = g.AddEdge(Edge{r.Intn(n), r.Intn(n), 2*r.Float64() - 1.5})
+			}
+			sp, err := g.BellmanFord(0)
+			AssertEqual(t, errors.Is(err, ErrNegativeCycle), g.FloydWarshall().HasNegativeCycle())
+			if err == nil {
+				continue
+			}
+			found++
+			cycle := sp.NegativeCycle()
+			AssertTrue(t, len(cycle) > 0)
+			weight := 0.0
+			for k, e := range cycle {
+				weight += e.Weight
+				AssertEqual(t, e.To, cycle[(k+1)%len(cycle)].From)
+			}
+			AssertTrue(t, weight < 0)
+		}
+		AssertTrue(t, found > 0)
+	})
+
+	t.Run("A*", func(t *testing.T) {
+		// grid with unit weights, Manhattan distance is admissible
+		n := 20
+		g, _ := NewEdgeWeightedGraph(n*n, false)
+		for r := 0; r < n; r++ {
+			for c := 0; c < n; c++ {
+				if c+1 < n {
+					_ = g.AddEdge(Edge{r*n + c, r*n + c + 1, 1 + rand.Float64()})
+				}
+				if r+1 < n {
+					_ = g.AddEdge(Edge{r*n + c, (r+1)*n + c, 1 + rand.Float64()})
+				}
+			}
+		}
+		target := n*n - 1
+		manhattan := func(v int) float64 { return float64(n-1-v/n) + float64(n-1-v%n) }
+		astar, err := g.AStar(0, target, manhattan)
+		AssertNil(t, err)
+		dijkstra, _ := g.Dijkstra(0)
+		AssertTrue(t, approx(astar.DistTo(target), dijkstra.DistTo(target)))
+		CollectionAssertEqual(t, astar.PathTo(target), dijkstra.PathTo(target))
+	})
+
+	t.Run("Floyd-Warshall", func(t *testing.T) {
+		g, _ := NewEdgeWeightedGraphFromString(tinyEWDn, true)
+		ap := g.FloydWarshall()
+		AssertFalse(t, ap.HasNegativeCycle())
+		for s := 0; s < g.V(); s++ {
+			sp, _ := g.BellmanFord(s)
+			for v := 0; v < g.V(); v++ {
+				AssertTrue(t, approx(ap.Dist(s, v), sp.DistTo(v)))
+			}
+		}
+		CollectionAssertEqual(t, ap.Path(0, 4), []int{0, 2, 7, 3, 6, 4})
+
+		cyc, _ := NewEdgeWeightedGraphFromString(tinyEWDnc, true)
+		AssertTrue(t, cyc.FloydWarshall().HasNegativeCycle())
+	})
+}
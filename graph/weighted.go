@@ -0,0 +1,187 @@
+package graph
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/jnsoft/jngo/stringhelper"
+)
+
+type (
+	// Edge is a weighted edge. In an undirected graph From and To are interchangeable, use Other.
+	Edge struct {
+		From   int
+		To     int
+		Weight float64
+	}
+
+	// EdgeWeightedGraph is a graph with float64 edge weights, directed or undirected like Graph.
+	EdgeWeightedGraph struct {
+		v             int
+		e             int
+		adjacencyList [][]Edge
+		isDirected    bool
+	}
+)
+
+func NewEdgeWeightedGraph(v int, is_directed bool) (*EdgeWeightedGraph, error) {
+	if v < 0 {
+		return nil, errors.New("number of vertices must be nonnegative")
+	}
+	g := &EdgeWeightedGraph{
+		v:             v,
+		adjacencyList: make([][]Edge, v),
+		isDirected:    is_directed,
+	}
+	for i := 0; i < v; i++ {
+		g.adjacencyList[i] = []Edge{}
+	}
+	return g, nil
+}
+
+// NewEdgeWeightedDigraph is NewEdgeWeightedGraph(v, true).
+func NewEdgeWeightedDigraph(v int) (*EdgeWeightedGraph, error) {
+	return NewEdgeWeightedGraph(v, true)
+}
+
+// NewEdgeWeightedGraphFromString reads the vertex count, the edge count and one "v w weight" line per edge.
+func NewEdgeWeightedGraphFromString(g string, is_directed bool) (*EdgeWeightedGraph, error) {
+	lines := stringhelper.ToLines(g)
+	if len(lines) < 2 {
+		return nil, fmt.Errorf("invalid input: no vertex or edge count specified")
+	}
+	v, err := strconv.Atoi(strings.TrimSpace(lines[0]))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse vertex count: %v", err)
+	}
+	e, err := strconv.Atoi(strings.TrimSpace(lines[1]))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse edge count: %v", err)
+	}
+
+	graph, err := NewEdgeWeightedGraph(v, is_directed)
+	if err != nil {
+		return nil, err
+	}
+	for i := 2; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i]) == "" {
+			continue
+		}
+		edge, err := readWeightedEdge(lines[i])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse edge: %v", err)
+		}
+		if err := graph.AddEdge(edge); err != nil {
+			return nil, fmt.Errorf("failed to add edge: %v", err)
+		}
+	}
+	if graph.e != e {
+		return nil, fmt.Errorf("wrong number of edges, want %v, got %v", e, graph.e)
+	}
+	return graph, nil
+}
+
+func readWeightedEdge(e string) (Edge, error) {
+	re := regexp.MustCompile(`\s+`)
+	vs := strings.Split(strings.TrimSpace(re.ReplaceAllString(e, " ")), " ")
+	if len(vs) < 3 {
+		return Edge{}, fmt.Errorf("invalid edge format")
+	}
+	v, err1 := strconv.Atoi(vs[0])
+	w, err2 := strconv.Atoi(vs[1])
+	weight, err3 := strconv.ParseFloat(vs[2], 64)
+	if err1 != nil || err2 != nil || err3 != nil {
+		return Edge{}, fmt.Errorf("failed to parse edge: %s", e)
+	}
+	return Edge{From: v, To: w, Weight: weight}, nil
+}
+
+// Either returns one endpoint of the edge.
+func (e Edge) Either() int {
+	return e.From
+}
+
+// Other returns the endpoint of the edge that is not v.
+func (e Edge) Other(v int) int {
+	if v == e.From {
+		return e.To
+	}
+	return e.From
+}
+
+func (e Edge) String() string {
+	return fmt.Sprintf("%d-%d %.5f", e.From, e.To, e.Weight)
+}
+
+func (g *EdgeWeightedGraph) V() int {
+	return g.v
+}
+
+func (g *EdgeWeightedGraph) E() int {
+	return g.e
+}
+
+func (g *EdgeWeightedGraph) IsDirected() bool {
+	return g.isDirected
+}
+
+func (g *EdgeWeightedGraph) AddEdge(e Edge) error {
+	if e.From < 0 || e.From >= g.v || e.To < 0 || e.To >= g.v {
+		return errors.New("vertex out of bounds")
+	}
+	g.e++
+	g.adjacencyList[e.From] = append(g.adjacencyList[e.From], e)
+	if !g.isDirected && e.From != e.To {
+		g.adjacencyList[e.To] = append(g.adjacencyList[e.To], e)
+	}
+	return nil
+}
+
+// Adj returns the edges incident to v, the edges leaving v in a directed graph.
+func (g *EdgeWeightedGraph) Adj(v int) ([]Edge, error) {
+	if v < 0 || v >= g.v {
+		return nil, errors.New("vertex out of bounds")
+	}
+	return g.adjacencyList[v], nil
+}
+
+// Edges returns every edge once.
+func (g *EdgeWeightedGraph) Edges() []Edge {
+	edges := make([]Edge, 0, g.e)
+	for v := 0; v < g.v; v++ {
+		for _, e := range g.adjacencyList[v] {
+			if g.isDirected || e.From == v {
+				edges = append(edges, e)
+			}
+		}
+	}
+	return edges
+}
+
+func (g *EdgeWeightedGraph) Degree(v int) int {
+	return len(g.adjacencyList[v])
+}
+
+func (g *EdgeWeightedGraph) String() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%d\n%d\n", g.v, g.e))
+	for _, e := range g.Edges() {
+		sb.WriteString(fmt.Sprintf("%d %d %v\n", e.From, e.To, e.Weight))
+	}
+	return sb.String()
+}
+
+// outgoing returns the edges of v oriented away from v, so undirected edges can be used as directed ones.
+func (g *EdgeWeightedGraph) outgoing(v int) []Edge {
+	if g.isDirected {
+		return g.adjacencyList[v]
+	}
+	out := make([]Edge, len(g.adjacencyList[v]))
+	for i, e := range g.adjacencyList[v] {
+		out[i] = Edge{From: v, To: e.Other(v), Weight: e.Weight}
+	}
+	return out
+}
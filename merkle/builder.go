@@ -0,0 +1,374 @@
+package merkle
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+
+	"github.com/jnsoft/jngo/parallell"
+)
+
+const (
+	DEFAULT_CHUNK_SIZE = 64 * 1024
+	MAX_NAME_LENGTH    = 255 // longest hash function name accepted when reading a tree
+)
+
+// FlatMerkleTree keeps every level as a flat array of hashes, Levels[0] are the leaves and the
+// last level holds the root. Levels are padded the same way as NewMerkleTree, so both produce
+// the same root for the same leaves.
+type FlatMerkleTree struct {
+	HashFunction HashFunction
+	Levels       [][][]byte
+}
+
+type indexedChunk struct {
+	index int
+	data  []byte
+}
+
+// NewFlatMerkleTree builds the tree from already hashed leaves using up to workers goroutines.
+func NewFlatMerkleTree(leafHashes [][]byte, hf HashFunction, workers int) (*FlatMerkleTree, error) {
+	if len(leafHashes) == 0 {
+		return nil, errors.New("no leaves")
+	}
+	workers = workerCount(workers)
+
+	level := append([][]byte(nil), leafHashes...)
+	if len(level)%2 != 0 {
+		level = append(level, level[len(level)-1])
+	}
+	levels := [][][]byte{level}
+	for len(level) > 1 {
+		if len(level)%2 != 0 {
+			level = append(level, level[len(level)-1])
+			levels[len(levels)-1] = level
+		}
+		next := make([][]byte, len(level)/2)
+		parallell.LimitedParallelRun(splitRange(len(next), workers), workers, func(r [2]int) {
+			for i := r[0]; i < r[1]; i++ {
+				next[i] = hashPair(hf, level[2*i], level[2*i+1])
+			}
+		})
+		levels = append(levels, next)
+		level = next
+	}
+	return &FlatMerkleTree{HashFunction: hf, Levels: levels}, nil
+}
+
+// BuildFromReader splits r into chunkSize byte leaves and hashes them in parallel.
+func BuildFromReader(r io.Reader, chunkSize int, hf HashFunction, workers int) (*FlatMerkleTree, error) {
+	if chunkSize <= 0 {
+		chunkSize = DEFAULT_CHUNK_SIZE
+	}
+	workers = workerCount(workers)
+
+	chunks := make(chan indexedChunk, workers)
+	var readErr error
+	go func() {
+		defer close(chunks)
+		br := bufio.NewReaderSize(r, chunkSize)
+		for i := 0; ; i++ {
+			buf := make([]byte, chunkSize)
+			n, err := io.ReadFull(br, buf)
+			if n > 0 {
+				chunks <- indexedChunk{i, buf[:n]}
+			}
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return
+			}
+			if err != nil {
+				readErr = err
+				return
+			}
+		}
+	}()
+
+	leaves := hashChunks(chunks, hf, workers)
+	if readErr != nil {
+		return nil, readErr
+	}
+	return NewFlatMerkleTree(leaves, hf, workers)
+}
+
+// BuildFromDir hashes every regular file below dir as one leaf, in lexical path order.
+// The returned paths are relative to dir and line up with the leaves.
+func BuildFromDir(dir string, hf HashFunction, workers int) (*FlatMerkleTree, []string, error) {
+	var paths []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			rel, err := filepath.Rel(dir, path)
+			if err != nil {
+				return err
+			}
+			paths = append(paths, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	sort.Strings(paths)
+	workers = workerCount(workers)
+
+	leaves := make([][]byte, len(paths))
+	errs := make([]error, len(paths))
+	parallell.LimitedParallelRun(splitRange(len(paths), workers), workers, func(r [2]int) {
+		for i := r[0]; i < r[1]; i++ {
+			data, err := os.ReadFile(filepath.Join(dir, paths[i]))
+			if err != nil {
+				errs[i] = err
+				continue
+			}
+			leaves[i] = hf.Hash(data)
+		}
+	})
+	if err := errors.Join(errs...); err != nil {
+		return nil, nil, err
+	}
+
+	tree, err := NewFlatMerkleTree(leaves, hf, workers)
+	if err != nil {
+		return nil, nil, err
+	}
+	return tree, paths, nil
+}
+
+func (t *FlatMerkleTree) Root() []byte {
+	return t.Levels[len(t.Levels)-1][0]
+}
+
+// LeafCount includes the padding leaf added for an odd number of leaves.
+func (t *FlatMerkleTree) LeafCount() int {
+	return len(t.Levels[0])
+}
+
+// GenerateProof reads one sibling per level, the result verifies with VerifyProof.
+func (t *FlatMerkleTree) GenerateProof(index int) (*MerkleProof, error) {
+	if index < 0 || index >= t.LeafCount() {
+		return nil, errors.New("index out of range")
+	}
+	path := make([]ProofItem, 0, len(t.Levels)-1)
+	pos := index
+	for _, level := range t.Levels[:len(t.Levels)-1] {
+		if pos%2 == 0 {
+			path = append(path, ProofItem{Hash: level[pos+1], Position: POSITION_RIGHT})
+		} else {
+			path = append(path, ProofItem{Hash: level[pos-1], Position: POSITION_LEFT})
+		}
+		pos /= 2
+	}
+	return &MerkleProof{Path: path, Index: index}, nil
+}
+
+// WriteTo stores the level data as name | hash size | level count | (hash count | hashes)*.
+func (t *FlatMerkleTree) WriteTo(w io.Writer) (int64, error) {
+	bw := bufio.NewWriter(w)
+	var written int64
+	write := func(b []byte) error {
+		n, err := bw.Write(b)
+		written += int64(n)
+		return err
+	}
+
+	name := t.HashFunction.Name()
+	header := binary.AppendUvarint(nil, uint64(len(name)))
+	header = append(header, name...)
+	header = binary.AppendUvarint(header, uint64(len(t.Root())))
+	header = binary.AppendUvarint(header, uint64(len(t.Levels)))
+	if err := write(header); err != nil {
+		return written, err
+	}
+	for _, level := range t.Levels {
+		if err := write(binary.AppendUvarint(nil, uint64(len(level)))); err != nil {
+			return written, err
+		}
+		for _, h := range level {
+			if err := write(h); err != nil {
+				return written, err
+			}
+		}
+	}
+	return written, bw.Flush()
+}
+
+func ReadFlatMerkleTree(r io.Reader) (*FlatMerkleTree, error) {
+	br := bufio.NewReader(r)
+	n, err := binary.ReadUvarint(br)
+	if err != nil {
+		return nil, err
+	}
+	if n > MAX_NAME_LENGTH {
+		return nil, fmt.Errorf("invalid hash function name length %d", n)
+	}
+	name := make([]byte, n)
+	if _, err := io.ReadFull(br, name); err != nil {
+		return nil, err
+	}
+	hf, err := HashFunctionByName(string(name))
+	if err != nil {
+		return nil, err
+	}
+	size, err := binary.ReadUvarint(br)
+	if err != nil {
+		return nil, err
+	}
+	if int(size) != len(hf.Hash(nil)) {
+		return nil, fmt.Errorf("hash size %d does not match %s", size, hf.Name())
+	}
+	count, err := binary.ReadUvarint(br)
+	if err != nil {
+		return nil, err
+	}
+	if count == 0 || count > 64 {
+		return nil, fmt.Errorf("invalid level count %d", count)
+	}
+
+	t := &FlatMerkleTree{HashFunction: hf, Levels: make([][][]byte, count)}
+	for i := range t.Levels {
+		hashes, err := binary.ReadUvarint(br)
+		if err != nil {
+			return nil, err
+		}
+		if want := levelSize(t.Levels[:i]); i > 0 && hashes != uint64(want) {
+			return nil, fmt.Errorf("level %d has %d hashes, want %d", i, hashes, want)
+		}
+		buf := make([]byte, 0, size)
+		for j := uint64(0); j < hashes; j++ {
+			buf = buf[:size]
+			if _, err := io.ReadFull(br, buf); err != nil {
+				return nil, err
+			}
+			t.Levels[i] = append(t.Levels[i], append([]byte(nil), buf...))
+		}
+	}
+	if len(t.Levels[count-1]) != 1 {
+		return nil, errors.New("last level must hold the root")
+	}
+	if err := t.check(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *FlatMerkleTree) Save(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := t.WriteTo(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func LoadFlatMerkleTree(path string) (*FlatMerkleTree, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadFlatMerkleTree(f)
+}
+
+// helpers
+
+// levelSize returns the number of hashes the level above the given ones must hold: a parent
+// for each pair, padded to an even count unless it is the root.
+func levelSize(below [][][]byte) int {
+	if len(below) == 0 {
+		return 0
+	}
+	parents := len(below[len(below)-1]) / 2
+	if parents > 1 && parents%2 != 0 {
+		parents++
+	}
+	return parents
+}
+
+// check recomputes every level from the one below, so a corrupt tree is rejected instead of
+// making GenerateProof read past a level.
+func (t *FlatMerkleTree) check() error {
+	for i, level := range t.Levels {
+		if i < len(t.Levels)-1 && (len(level) < 2 || len(level)%2 != 0) {
+			return fmt.Errorf("level %d has %d hashes, want an even number", i, len(level))
+		}
+		if i == 0 {
+			continue
+		}
+		below := t.Levels[i-1]
+		for j, h := range level {
+			if 2*j+1 >= len(below) {
+				// padding repeats the last parent
+				if !bytes.Equal(h, level[j-1]) {
+					return fmt.Errorf("invalid padding at level %d", i)
+				}
+				continue
+			}
+			if !bytes.Equal(h, hashPair(t.HashFunction, below[2*j], below[2*j+1])) {
+				return fmt.Errorf("hash mismatch at level %d index %d", i, j)
+			}
+		}
+	}
+	return nil
+}
+
+func hashChunks(chunks <-chan indexedChunk, hf HashFunction, workers int) [][]byte {
+	var (
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		leaves [][]byte
+	)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for c := range chunks {
+				h := hf.Hash(c.data)
+				mu.Lock()
+				for len(leaves) <= c.index {
+					leaves = append(leaves, nil)
+				}
+				leaves[c.index] = h
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return leaves
+}
+
+// splitRange cuts [0, n) into at most parts half-open ranges.
+func splitRange(n, parts int) [][2]int {
+	if n == 0 {
+		return nil
+	}
+	if parts > n {
+		parts = n
+	}
+	size := (n + parts - 1) / parts
+	ranges := make([][2]int, 0, parts)
+	for start := 0; start < n; start += size {
+		ranges = append(ranges, [2]int{start, min(start+size, n)})
+	}
+	return ranges
+}
+
+func workerCount(workers int) int {
+	if workers <= 0 {
+		return runtime.NumCPU()
+	}
+	return workers
+}
@@ -0,0 +1,114 @@
+package merkle
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jnsoft/jngo/misc"
+	. "github.com/jnsoft/jngo/testhelper"
+)
+
+func TestFlatMerkleTree(t *testing.T) {
+	hf := SHA256Hash{}
+
+	t.Run("Same root as NewMerkleTree", func(t *testing.T) {
+		for n := 1; n <= 33; n++ {
+			data := make([][]byte, n)
+			hashes := make([][]byte, n)
+			for i := range data {
+				data[i] = []byte{byte(i), byte(n)}
+				hashes[i] = hf.Hash(data[i])
+			}
+			flat, err := NewFlatMerkleTree(hashes, hf, 4)
+			AssertNil(t, err)
+			tree := NewMerkleTree(data, hf)
+			if !bytes.Equal(flat.Root(), tree.Root.Hash) {
+				t.Fatalf("root mismatch for %d leaves", n)
+			}
+			for i := range data {
+				proof, err := flat.GenerateProof(i)
+				AssertNil(t, err)
+				AssertTrue(t, VerifyProof(data[i], proof, flat.Root(), hf))
+			}
+		}
+	})
+
+	t.Run("Build from reader", func(t *testing.T) {
+		data := misc.GetRandomBytes(10_000)
+		flat, err := BuildFromReader(bytes.NewReader(data), 1000, hf, 3)
+		AssertNil(t, err)
+		AssertEqual(t, flat.LeafCount(), 10)
+
+		chunks := make([][]byte, 0, 10)
+		for i := 0; i < len(data); i += 1000 {
+			chunks = append(chunks, data[i:min(i+1000, len(data))])
+		}
+		AssertTrue(t, bytes.Equal(flat.Root(), NewMerkleTree(chunks, hf).Root.Hash))
+
+		proof, _ := flat.GenerateProof(7)
+		AssertTrue(t, VerifyProof(chunks[7], proof, flat.Root(), hf))
+
+		_, err = BuildFromReader(bytes.NewReader(nil), 1000, hf, 3)
+		AssertTrue(t, err != nil)
+	})
+
+	t.Run("Build from dir", func(t *testing.T) {
+		dir := t.TempDir()
+		_ = os.MkdirAll(filepath.Join(dir, "sub"), 0o755)
+		_ = os.WriteFile(filepath.Join(dir, "b.txt"), []byte("bbb"), 0o644)
+		_ = os.WriteFile(filepath.Join(dir, "a.txt"), []byte("aaa"), 0o644)
+		_ = os.WriteFile(filepath.Join(dir, "sub", "c.txt"), []byte("ccc"), 0o644)
+
+		flat, paths, err := BuildFromDir(dir, hf, 2)
+		AssertNil(t, err)
+		CollectionAssertEqual(t, paths, []string{"a.txt", "b.txt", filepath.Join("sub", "c.txt")})
+		expected := NewMerkleTree([][]byte{[]byte("aaa"), []byte("bbb"), []byte("ccc")}, hf)
+		AssertTrue(t, bytes.Equal(flat.Root(), expected.Root.Hash))
+	})
+
+	t.Run("Save and load", func(t *testing.T) {
+		flat, _ := BuildFromReader(bytes.NewReader(misc.GetRandomBytes(5000)), 512, SHA3_256Hash{}, 0)
+		path := filepath.Join(t.TempDir(), "tree.bin")
+		AssertNil(t, flat.Save(path))
+
+		loaded, err := LoadFlatMerkleTree(path)
+		AssertNil(t, err)
+		AssertEqual(t, loaded.HashFunction.Name(), "SHA3-256")
+		AssertTrue(t, bytes.Equal(loaded.Root(), flat.Root()))
+		AssertEqual(t, len(loaded.Levels), len(flat.Levels))
+
+		var buf bytes.Buffer
+		_, _ = flat.WriteTo(&buf)
+		_, err = ReadFlatMerkleTree(bytes.NewReader(buf.Bytes()[:buf.Len()-5]))
+		AssertTrue(t, err != nil)
+	})
+
+	t.Run("Load corrupt tree", func(t *testing.T) {
+		flat, _ := BuildFromReader(bytes.NewReader(misc.GetRandomBytes(5000)), 512, SHA256Hash{}, 0)
+		var buf bytes.Buffer
+		_, _ = flat.WriteTo(&buf)
+		data := buf.Bytes()
+
+		// flipped bit in the root
+		corrupt := bytes.Clone(data)
+		corrupt[len(corrupt)-1] ^= 1
+		_, err := ReadFlatMerkleTree(bytes.NewReader(corrupt))
+		AssertTrue(t, err != nil)
+
+		// a level too short for GenerateProof
+		short := &FlatMerkleTree{HashFunction: flat.HashFunction, Levels: [][][]byte{flat.Levels[0], {flat.Root()}}}
+		buf.Reset()
+		_, _ = short.WriteTo(&buf)
+		_, err = ReadFlatMerkleTree(&buf)
+		AssertTrue(t, err != nil)
+
+		// huge name length
+		var header [binary.MaxVarintLen64]byte
+		n := binary.PutUvarint(header[:], 1<<62)
+		_, err = ReadFlatMerkleTree(bytes.NewReader(header[:n]))
+		AssertTrue(t, err != nil)
+	})
+}
@@ -0,0 +1,327 @@
+package merkle
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Wire formats for proofs. The binary layout is
+//
+//	kind (1 byte) | len(name) (1 byte) | name | hash size (uvarint) | body
+//
+// single proof body: index | path length | direction bitfield | sibling hashes
+// multiproof body:   leaf count | index count | indices | hash count | hashes
+//
+// Integers are uvarints, a set bit in the direction bitfield means the sibling is on the left.
+
+const (
+	proofKindSingle byte = 1
+	proofKindMulti  byte = 2
+)
+
+type proofJSON struct {
+	Algorithm  string   `json:"algorithm"`
+	Index      int      `json:"index"`
+	Directions string   `json:"directions"` // hex encoded bitfield
+	Path       []string `json:"path"`
+}
+
+type multiProofJSON struct {
+	Algorithm string   `json:"algorithm"`
+	LeafCount int      `json:"leafCount"`
+	Indices   []int    `json:"indices"`
+	Hashes    []string `json:"hashes"`
+}
+
+// EncodeProof serializes a proof together with the name of its hash function.
+func EncodeProof(proof *MerkleProof, hf HashFunction) ([]byte, error) {
+	size, err := proofHashSize(proof.Path, hf)
+	if err != nil {
+		return nil, err
+	}
+	buf, err := encodeHeader(proofKindSingle, hf, size)
+	if err != nil {
+		return nil, err
+	}
+	buf = binary.AppendUvarint(buf, uint64(proof.Index))
+	buf = binary.AppendUvarint(buf, uint64(len(proof.Path)))
+	buf = append(buf, directionBits(proof.Path)...)
+	for _, item := range proof.Path {
+		buf = append(buf, item.Hash...)
+	}
+	return buf, nil
+}
+
+// DecodeProof parses the output of EncodeProof and returns the proof and its hash function.
+func DecodeProof(data []byte) (*MerkleProof, HashFunction, error) {
+	r, hf, size, err := decodeHeader(data, proofKindSingle)
+	if err != nil {
+		return nil, nil, err
+	}
+	index, err := r.uvarint()
+	if err != nil {
+		return nil, nil, err
+	}
+	n, err := r.uvarint()
+	if err != nil {
+		return nil, nil, err
+	}
+	if n > uint64(len(r.data)) {
+		return nil, nil, errors.New("truncated proof")
+	}
+	bits, err := r.next((int(n) + 7) / 8)
+	if err != nil {
+		return nil, nil, err
+	}
+	proof := &MerkleProof{Index: int(index), Path: make([]ProofItem, n)}
+	for i := range proof.Path {
+		h, err := r.next(size)
+		if err != nil {
+			return nil, nil, err
+		}
+		proof.Path[i] = ProofItem{Hash: h, Position: positionFromBit(bitAt(bits, i))}
+	}
+	if len(r.data) != 0 {
+		return nil, nil, errors.New("trailing bytes after proof")
+	}
+	return proof, hf, nil
+}
+
+// EncodeMultiProof serializes a multiproof together with the name of its hash function.
+func EncodeMultiProof(proof *MerkleMultiProof, hf HashFunction) ([]byte, error) {
+	size := len(hf.Hash(nil))
+	for _, h := range proof.Hashes {
+		if len(h) != size {
+			return nil, fmt.Errorf("hash length %d does not match %s", len(h), hf.Name())
+		}
+	}
+	buf, err := encodeHeader(proofKindMulti, hf, size)
+	if err != nil {
+		return nil, err
+	}
+	buf = binary.AppendUvarint(buf, uint64(proof.LeafCount))
+	buf = binary.AppendUvarint(buf, uint64(len(proof.Indices)))
+	for _, ix := range proof.Indices {
+		buf = binary.AppendUvarint(buf, uint64(ix))
+	}
+	buf = binary.AppendUvarint(buf, uint64(len(proof.Hashes)))
+	for _, h := range proof.Hashes {
+		buf = append(buf, h...)
+	}
+	return buf, nil
+}
+
+// DecodeMultiProof parses the output of EncodeMultiProof.
+func DecodeMultiProof(data []byte) (*MerkleMultiProof, HashFunction, error) {
+	r, hf, size, err := decodeHeader(data, proofKindMulti)
+	if err != nil {
+		return nil, nil, err
+	}
+	leafCount, err := r.uvarint()
+	if err != nil {
+		return nil, nil, err
+	}
+	n, err := r.uvarint()
+	if err != nil {
+		return nil, nil, err
+	}
+	if n > uint64(len(r.data)) {
+		return nil, nil, errors.New("truncated proof")
+	}
+	proof := &MerkleMultiProof{LeafCount: int(leafCount), Indices: make([]int, n)}
+	for i := range proof.Indices {
+		ix, err := r.uvarint()
+		if err != nil {
+			return nil, nil, err
+		}
+		proof.Indices[i] = int(ix)
+	}
+	n, err = r.uvarint()
+	if err != nil {
+		return nil, nil, err
+	}
+	if n > uint64(len(r.data))/uint64(size) || n*uint64(size) != uint64(len(r.data)) {
+		return nil, nil, errors.New("invalid number of hashes")
+	}
+	proof.Hashes = make([][]byte, n)
+	for i := range proof.Hashes {
+		proof.Hashes[i], _ = r.next(size)
+	}
+	return proof, hf, nil
+}
+
+// MarshalProofJSON encodes a proof as JSON with hex encoded hashes.
+func MarshalProofJSON(proof *MerkleProof, hf HashFunction) ([]byte, error) {
+	if _, err := proofHashSize(proof.Path, hf); err != nil {
+		return nil, err
+	}
+	pj := proofJSON{
+		Algorithm:  hf.Name(),
+		Index:      proof.Index,
+		Directions: hex.EncodeToString(directionBits(proof.Path)),
+		Path:       make([]string, len(proof.Path)),
+	}
+	for i, item := range proof.Path {
+		pj.Path[i] = hex.EncodeToString(item.Hash)
+	}
+	return json.Marshal(pj)
+}
+
+func UnmarshalProofJSON(data []byte) (*MerkleProof, HashFunction, error) {
+	var pj proofJSON
+	if err := json.Unmarshal(data, &pj); err != nil {
+		return nil, nil, err
+	}
+	hf, err := HashFunctionByName(pj.Algorithm)
+	if err != nil {
+		return nil, nil, err
+	}
+	bits, err := hex.DecodeString(pj.Directions)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid directions: %v", err)
+	}
+	if len(bits) != (len(pj.Path)+7)/8 {
+		return nil, nil, errors.New("directions do not match path length")
+	}
+	proof := &MerkleProof{Index: pj.Index, Path: make([]ProofItem, len(pj.Path))}
+	for i, s := range pj.Path {
+		h, err := decodeHashHex(s, hf)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid hash at %d: %v", i, err)
+		}
+		proof.Path[i] = ProofItem{Hash: h, Position: positionFromBit(bitAt(bits, i))}
+	}
+	return proof, hf, nil
+}
+
+func MarshalMultiProofJSON(proof *MerkleMultiProof, hf HashFunction) ([]byte, error) {
+	mj := multiProofJSON{
+		Algorithm: hf.Name(),
+		LeafCount: proof.LeafCount,
+		Indices:   proof.Indices,
+		Hashes:    make([]string, len(proof.Hashes)),
+	}
+	for i, h := range proof.Hashes {
+		mj.Hashes[i] = hex.EncodeToString(h)
+	}
+	return json.Marshal(mj)
+}
+
+func UnmarshalMultiProofJSON(data []byte) (*MerkleMultiProof, HashFunction, error) {
+	var mj multiProofJSON
+	if err := json.Unmarshal(data, &mj); err != nil {
+		return nil, nil, err
+	}
+	hf, err := HashFunctionByName(mj.Algorithm)
+	if err != nil {
+		return nil, nil, err
+	}
+	proof := &MerkleMultiProof{LeafCount: mj.LeafCount, Indices: mj.Indices, Hashes: make([][]byte, len(mj.Hashes))}
+	for i, s := range mj.Hashes {
+		if proof.Hashes[i], err = decodeHashHex(s, hf); err != nil {
+			return nil, nil, fmt.Errorf("invalid hash at %d: %v", i, err)
+		}
+	}
+	return proof, hf, nil
+}
+
+// helpers
+
+type byteReader struct {
+	data []byte
+}
+
+func (r *byteReader) uvarint() (uint64, error) {
+	v, n := binary.Uvarint(r.data)
+	if n <= 0 {
+		return 0, errors.New("invalid varint in proof")
+	}
+	r.data = r.data[n:]
+	return v, nil
+}
+
+func (r *byteReader) next(n int) ([]byte, error) {
+	if n < 0 || n > len(r.data) {
+		return nil, errors.New("truncated proof")
+	}
+	b := append([]byte(nil), r.data[:n]...)
+	r.data = r.data[n:]
+	return b, nil
+}
+
+func encodeHeader(kind byte, hf HashFunction, size int) ([]byte, error) {
+	name := hf.Name()
+	if len(name) > 255 {
+		return nil, errors.New("hash function name too long")
+	}
+	buf := []byte{kind, byte(len(name))}
+	buf = append(buf, name...)
+	return binary.AppendUvarint(buf, uint64(size)), nil
+}
+
+func decodeHeader(data []byte, kind byte) (*byteReader, HashFunction, int, error) {
+	if len(data) < 2 {
+		return nil, nil, 0, errors.New("truncated proof")
+	}
+	if data[0] != kind {
+		return nil, nil, 0, fmt.Errorf("unexpected proof kind %d", data[0])
+	}
+	r := &byteReader{data: data[2:]}
+	name, err := r.next(int(data[1]))
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	hf, err := HashFunctionByName(string(name))
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	size, err := r.uvarint()
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	if int(size) != len(hf.Hash(nil)) {
+		return nil, nil, 0, fmt.Errorf("hash size %d does not match %s", size, hf.Name())
+	}
+	return r, hf, int(size), nil
+}
+
+func decodeHashHex(s string, hf HashFunction) ([]byte, error) {
+	h, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(h) != len(hf.Hash(nil)) {
+		return nil, fmt.Errorf("hash length %d does not match %s", len(h), hf.Name())
+	}
+	return h, nil
+}
+
+func proofHashSize(path []ProofItem, hf HashFunction) (int, error) {
+	size := len(hf.Hash(nil))
+	for _, item := range path {
+		if len(item.Hash) != size {
+			return 0, fmt.Errorf("hash length %d does not match %s", len(item.Hash), hf.Name())
+		}
+	}
+	return size, nil
+}
+
+func directionBits(path []ProofItem) []byte {
+	bits := make([]byte, (len(path)+7)/8)
+	for i, item := range path {
+		if item.Position == POSITION_LEFT {
+			bits[i/8] |= 1 << (7 - i%8)
+		}
+	}
+	return bits
+}
+
+func positionFromBit(b byte) string {
+	if b == 1 {
+		return POSITION_LEFT
+	}
+	return POSITION_RIGHT
+}
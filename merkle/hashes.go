@@ -3,6 +3,7 @@ package merkle
 import (
 	"crypto/sha256"
 	"crypto/sha3"
+	"fmt"
 )
 
 type HashFunction interface {
@@ -31,3 +32,14 @@ func (h SHA3_256Hash) Hash(data []byte) []byte {
 func (h SHA3_256Hash) Name() string {
 	return "SHA3-256"
 }
+
+// HashFunctionByName returns the hash function matching HashFunction.Name().
+func HashFunctionByName(name string) (HashFunction, error) {
+	switch name {
+	case SHA256Hash{}.Name():
+		return SHA256Hash{}, nil
+	case SHA3_256Hash{}.Name():
+		return SHA3_256Hash{}, nil
+	}
+	return nil, fmt.Errorf("unknown hash function: %s", name)
+}
@@ -0,0 +1,115 @@
+package merkle
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+)
+
+// MerkleMultiProof proves several leaves at once, sharing siblings between paths.
+// Hashes holds the siblings that cannot be derived from the proven leaves, level by level.
+type MerkleMultiProof struct {
+	Indices   []int // sorted, unique leaf indices
+	LeafCount int
+	Hashes    [][]byte
+}
+
+// GenerateMultiProof builds a proof for the given leaf indices. Indices are sorted and deduplicated,
+// the leaf data passed to VerifyMultiProof must follow proof.Indices.
+func (t *MerkleTree) GenerateMultiProof(indices []int) (*MerkleMultiProof, error) {
+	if len(indices) == 0 {
+		return nil, errors.New("no indices")
+	}
+	idx := slices.Clone(indices)
+	slices.Sort(idx)
+	idx = slices.Compact(idx)
+	if idx[0] < 0 || idx[len(idx)-1] >= len(t.Leaves) {
+		return nil, errors.New("index out of range")
+	}
+
+	level := make([][]byte, len(t.Leaves))
+	for i, leaf := range t.Leaves {
+		level[i] = leaf.Hash
+	}
+
+	proof := &MerkleMultiProof{Indices: idx, LeafCount: len(t.Leaves)}
+	known := idx
+	for len(level) > 1 {
+		padded := len(level)%2 != 0
+		if padded {
+			level = append(level, level[len(level)-1])
+		}
+		var next []int
+		for i := 0; i < len(known); i++ {
+			k := known[i]
+			sib := k ^ 1
+			if i+1 < len(known) && known[i+1] == sib {
+				i++ // both children known
+			} else if !(padded && sib == len(level)-1) {
+				proof.Hashes = append(proof.Hashes, level[sib])
+			}
+			next = append(next, k/2)
+		}
+		parents := make([][]byte, len(level)/2)
+		for i := range parents {
+			parents[i] = hashPair(t.HashFunction, level[2*i], level[2*i+1])
+		}
+		level = parents
+		known = next
+	}
+	return proof, nil
+}
+
+// VerifyMultiProof checks that leafData, in proof.Indices order, are leaves of the tree with rootHash.
+func VerifyMultiProof(leafData [][]byte, proof *MerkleMultiProof, rootHash []byte, hf HashFunction) bool {
+	if proof == nil || len(leafData) != len(proof.Indices) || len(leafData) == 0 {
+		return false
+	}
+	known := make([]int, len(proof.Indices))
+	hashes := make([][]byte, len(proof.Indices))
+	for i, ix := range proof.Indices {
+		if ix < 0 || ix >= proof.LeafCount || (i > 0 && ix <= proof.Indices[i-1]) {
+			return false
+		}
+		known[i] = ix
+		hashes[i] = hf.Hash(leafData[i])
+	}
+
+	width := proof.LeafCount
+	next := 0
+	for width > 1 {
+		padded := width%2 != 0
+		if padded {
+			width++
+		}
+		var nextKnown []int
+		var nextHashes [][]byte
+		for i := 0; i < len(known); i++ {
+			k, own := known[i], hashes[i]
+			sib := k ^ 1
+			var sibHash []byte
+			if i+1 < len(known) && known[i+1] == sib {
+				sibHash = hashes[i+1]
+				i++
+			} else if padded && sib == width-1 {
+				sibHash = own
+			} else {
+				if next >= len(proof.Hashes) {
+					return false
+				}
+				sibHash = proof.Hashes[next]
+				next++
+			}
+			if k%2 == 0 {
+				nextHashes = append(nextHashes, hashPair(hf, own, sibHash))
+			} else {
+				nextHashes = append(nextHashes, hashPair(hf, sibHash, own))
+			}
+			nextKnown = append(nextKnown, k/2)
+		}
+		known = nextKnown
+		hashes = nextHashes
+		width /= 2
+	}
+	return next == len(proof.Hashes) && bytes.Equal(hashes[0], rootHash)
+}
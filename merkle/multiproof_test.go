@@ -0,0 +1,135 @@
+package merkle
+
+import (
+	"encoding/binary"
+	"fmt"
+	"testing"
+
+	. "github.com/jnsoft/jngo/testhelper"
+)
+
+func TestMultiProof(t *testing.T) {
+	hf := SHA256Hash{}
+
+	t.Run("All index subsets", func(t *testing.T) {
+		for n := 1; n <= 7; n++ {
+			data := make([][]byte, n)
+			for i := range data {
+				data[i] = []byte(fmt.Sprintf("leaf-%d", i))
+			}
+			tree := NewMerkleTree(data, hf)
+			leaves := len(tree.Leaves)
+			for mask := 1; mask < 1<<leaves; mask++ {
+				var idx []int
+				for i := 0; i < leaves; i++ {
+					if mask&(1<<i) != 0 {
+						idx = append(idx, i)
+					}
+				}
+				proof, err := tree.GenerateMultiProof(idx)
+				AssertNil(t, err)
+				leafData := make([][]byte, len(proof.Indices))
+				for i, ix := range proof.Indices {
+					leafData[i] = data[min(ix, n-1)]
+				}
+				if !VerifyMultiProof(leafData, proof, tree.Root.Hash, hf) {
+					t.Fatalf("multiproof failed for n=%d indices=%v", n, idx)
+				}
+			}
+		}
+	})
+
+	t.Run("Shares siblings", func(t *testing.T) {
+		data := [][]byte{[]byte("1"), []byte("2"), []byte("3"), []byte("4")}
+		tree := NewMerkleTree(data, hf)
+		proof, _ := tree.GenerateMultiProof([]int{0, 1, 1})
+		AssertEqual(t, len(proof.Indices), 2)
+		AssertEqual(t, len(proof.Hashes), 1)
+	})
+
+	t.Run("Rejects wrong data", func(t *testing.T) {
+		data := [][]byte{[]byte("1"), []byte("2"), []byte("3"), []byte("4")}
+		tree := NewMerkleTree(data, hf)
+		proof, _ := tree.GenerateMultiProof([]int{0, 3})
+		AssertFalse(t, VerifyMultiProof([][]byte{[]byte("1"), []byte("x")}, proof, tree.Root.Hash, hf))
+		AssertFalse(t, VerifyMultiProof([][]byte{[]byte("1")}, proof, tree.Root.Hash, hf))
+
+		_, err := tree.GenerateMultiProof([]int{4})
+		AssertTrue(t, err != nil)
+	})
+}
+
+func TestProofEncoding(t *testing.T) {
+	data := [][]byte{[]byte("a"), []byte("b"), []byte("c"), []byte("d"), []byte("e")}
+
+	for _, hf := range []HashFunction{SHA256Hash{}, SHA3_256Hash{}} {
+		tree := NewMerkleTree(data, hf)
+
+		t.Run("Binary "+hf.Name(), func(t *testing.T) {
+			proof, _ := tree.GenerateProof(3)
+			enc, err := EncodeProof(proof, hf)
+			AssertNil(t, err)
+			dec, dhf, err := DecodeProof(enc)
+			AssertNil(t, err)
+			AssertEqual(t, dhf.Name(), hf.Name())
+			AssertEqual(t, dec.Index, 3)
+			AssertTrue(t, VerifyProof(data[3], dec, tree.Root.Hash, dhf))
+
+			_, _, err = DecodeProof(enc[:len(enc)-1])
+			AssertTrue(t, err != nil)
+		})
+
+		t.Run("JSON "+hf.Name(), func(t *testing.T) {
+			proof, _ := tree.GenerateProof(1)
+			enc, err := MarshalProofJSON(proof, hf)
+			AssertNil(t, err)
+			dec, dhf, err := UnmarshalProofJSON(enc)
+			AssertNil(t, err)
+			AssertTrue(t, VerifyProof(data[1], dec, tree.Root.Hash, dhf))
+		})
+
+		t.Run("Multiproof "+hf.Name(), func(t *testing.T) {
+			proof, _ := tree.GenerateMultiProof([]int{0, 2, 4})
+			leafData := [][]byte{data[0], data[2], data[4]}
+
+			enc, err := EncodeMultiProof(proof, hf)
+			AssertNil(t, err)
+			dec, dhf, err := DecodeMultiProof(enc)
+			AssertNil(t, err)
+			AssertTrue(t, VerifyMultiProof(leafData, dec, tree.Root.Hash, dhf))
+
+			js, err := MarshalMultiProofJSON(proof, hf)
+			AssertNil(t, err)
+			dec, dhf, err = UnmarshalMultiProofJSON(js)
+			AssertNil(t, err)
+			AssertTrue(t, VerifyMultiProof(leafData, dec, tree.Root.Hash, dhf))
+		})
+	}
+
+	t.Run("Unknown algorithm", func(t *testing.T) {
+		_, err := HashFunctionByName("MD5")
+		AssertTrue(t, err != nil)
+		_, _, err = UnmarshalProofJSON([]byte(`{"algorithm":"MD5","index":0,"directions":"","path":[]}`))
+		AssertTrue(t, err != nil)
+	})
+
+	t.Run("Malformed input", func(t *testing.T) {
+		hf := SHA256Hash{}
+		tree := NewMerkleTree([][]byte{[]byte("a"), []byte("b"), []byte("c")}, hf)
+		proof, _ := tree.GenerateMultiProof([]int{0, 2})
+		enc, _ := EncodeMultiProof(proof, hf)
+
+		// a hash count of 1<<59+1 times the hash size wraps around to the 32 bytes that follow
+		header := enc[:2+len(hf.Name())+1]
+		crafted := append(append([]byte{}, header...), 3, 0) // leaf count, no indices
+		crafted = binary.AppendUvarint(crafted, 1<<59+1)
+		crafted = append(crafted, make([]byte, 32)...)
+		_, _, err := DecodeMultiProof(crafted)
+		AssertTrue(t, err != nil)
+
+		_, _, err = UnmarshalMultiProofJSON([]byte(`{"algorithm":"SHA256","leafCount":5,"indices":[0],"hashes":["abcd"]}`))
+		AssertTrue(t, err != nil)
+		_, _, err = UnmarshalProofJSON([]byte(`{"algorithm":"SHA256","index":0,"directions":"00","path":["abcd"]}`))
+		AssertTrue(t, err != nil)
+	})
+}
@@ -0,0 +1,501 @@
+package merkle
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Merkle Patricia trie over hex nibbles of the key. Nodes are immutable and stored by hash in a
+// NodeStore, so every root ever produced stays readable with OpenPatriciaTrie.
+
+const (
+	nodeLeaf      byte = 0
+	nodeExtension byte = 1
+	nodeBranch    byte = 2
+)
+
+type PatriciaTrie struct {
+	HashFunction HashFunction
+	store        NodeStore
+	root         []byte // nil for the empty trie
+}
+
+type patriciaNode struct {
+	kind     byte
+	path     []byte // nibbles, leaf and extension only
+	value    []byte // leaf and branch only
+	child    []byte // extension only
+	children [16][]byte
+}
+
+func NewPatriciaTrie(store NodeStore, hf HashFunction) *PatriciaTrie {
+	return &PatriciaTrie{HashFunction: hf, store: store}
+}
+
+// OpenPatriciaTrie opens the trie with the given root hash in store.
+func OpenPatriciaTrie(root []byte, store NodeStore, hf HashFunction) (*PatriciaTrie, error) {
+	t := NewPatriciaTrie(store, hf)
+	if bytes.Equal(root, t.emptyRoot()) {
+		return t, nil
+	}
+	if _, err := store.Get(root); err != nil {
+		return nil, err
+	}
+	t.root = append([]byte(nil), root...)
+	return t, nil
+}
+
+// RootHash returns the hash of the root node, or the hash of no data for the empty trie.
+func (t *PatriciaTrie) RootHash() []byte {
+	if t.root == nil {
+		return t.emptyRoot()
+	}
+	return t.root
+}
+
+func (t *PatriciaTrie) IsEmpty() bool {
+	return t.root == nil
+}
+
+func (t *PatriciaTrie) Get(key []byte) ([]byte, bool, error) {
+	path := toNibbles(key)
+	h := t.root
+	for h != nil {
+		n, err := t.load(h)
+		if err != nil {
+			return nil, false, err
+		}
+		switch n.kind {
+		case nodeLeaf:
+			if bytes.Equal(n.path, path) {
+				return n.value, true, nil
+			}
+			return nil, false, nil
+		case nodeExtension:
+			if !bytes.HasPrefix(path, n.path) {
+				return nil, false, nil
+			}
+			path = path[len(n.path):]
+			h = n.child
+		case nodeBranch:
+			if len(path) == 0 {
+				return n.value, n.value != nil, nil
+			}
+			h = n.children[path[0]]
+			path = path[1:]
+		}
+	}
+	return nil, false, nil
+}
+
+// Put inserts or replaces the value for key. An empty value deletes the key.
+func (t *PatriciaTrie) Put(key, value []byte) error {
+	if len(value) == 0 {
+		_, err := t.Delete(key)
+		return err
+	}
+	root, err := t.insert(t.root, toNibbles(key), append([]byte(nil), value...))
+	if err != nil {
+		return err
+	}
+	t.root = root
+	return nil
+}
+
+// Delete removes key and reports whether it was present.
+func (t *PatriciaTrie) Delete(key []byte) (bool, error) {
+	root, found, err := t.delete(t.root, toNibbles(key))
+	if err != nil || !found {
+		return false, err
+	}
+	t.root = root
+	return true, nil
+}
+
+// GenerateProof returns the encoded nodes on the path from the root towards key.
+// The proof shows either the value of key or that key is absent.
+func (t *PatriciaTrie) GenerateProof(key []byte) ([][]byte, error) {
+	var proof [][]byte
+	path := toNibbles(key)
+	h := t.root
+	for h != nil {
+		data, err := t.store.Get(h)
+		if err != nil {
+			return nil, err
+		}
+		proof = append(proof, data)
+		n, err := decodePatriciaNode(data)
+		if err != nil {
+			return nil, err
+		}
+		h = nil
+		switch n.kind {
+		case nodeExtension:
+			if bytes.HasPrefix(path, n.path) {
+				path = path[len(n.path):]
+				h = n.child
+			}
+		case nodeBranch:
+			if len(path) > 0 {
+				h = n.children[path[0]]
+				path = path[1:]
+			}
+		}
+	}
+	return proof, nil
+}
+
+// VerifyPatriciaProof checks proof against root and returns the value of key,
+// or nil if the proof shows that key is absent.
+func VerifyPatriciaProof(root, key []byte, proof [][]byte, hf HashFunction) ([]byte, error) {
+	if len(proof) == 0 {
+		if bytes.Equal(root, hf.Hash(nil)) {
+			return nil, nil
+		}
+		return nil, errors.New("empty proof for non-empty trie")
+	}
+	path := toNibbles(key)
+	expected := root
+	for i, data := range proof {
+		if !bytes.Equal(hf.Hash(data), expected) {
+			return nil, fmt.Errorf("proof node %d does not match its hash", i)
+		}
+		n, err := decodePatriciaNode(data)
+		if err != nil {
+			return nil, err
+		}
+		last := i == len(proof)-1
+		var next []byte
+		var value []byte
+		switch n.kind {
+		case nodeLeaf:
+			if bytes.Equal(n.path, path) {
+				value = n.value
+			}
+		case nodeExtension:
+			if bytes.HasPrefix(path, n.path) {
+				path = path[len(n.path):]
+				next = n.child
+			}
+		case nodeBranch:
+			if len(path) == 0 {
+				value = n.value
+			} else {
+				next = n.children[path[0]]
+				path = path[1:]
+			}
+		}
+		if next == nil {
+			if !last {
+				return nil, errors.New("proof has extra nodes")
+			}
+			return value, nil
+		}
+		if last {
+			return nil, errors.New("proof is incomplete")
+		}
+		expected = next
+	}
+	return nil, errors.New("proof is incomplete")
+}
+
+func (t *PatriciaTrie) insert(h, path, value []byte) ([]byte, error) {
+	if h == nil {
+		return t.save(&patriciaNode{kind: nodeLeaf, path: path, value: value})
+	}
+	n, err := t.load(h)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.kind {
+	case nodeLeaf:
+		if bytes.Equal(n.path, path) {
+			return t.save(&patriciaNode{kind: nodeLeaf, path: path, value: value})
+		}
+		c := commonPrefix(n.path, path)
+		b := &patriciaNode{kind: nodeBranch}
+		if err := t.attach(b, n.path[c:], n.value); err != nil {
+			return nil, err
+		}
+		if err := t.attach(b, path[c:], value); err != nil {
+			return nil, err
+		}
+		return t.saveWithPrefix(path[:c], b)
+
+	case nodeExtension:
+		c := commonPrefix(n.path, path)
+		if c == len(n.path) {
+			child, err := t.insert(n.child, path[c:], value)
+			if err != nil {
+				return nil, err
+			}
+			return t.save(&patriciaNode{kind: nodeExtension, path: n.path, child: child})
+		}
+		b := &patriciaNode{kind: nodeBranch}
+		rest := n.path[c:]
+		if len(rest) == 1 {
+			b.children[rest[0]] = n.child
+		} else {
+			ext, err := t.save(&patriciaNode{kind: nodeExtension, path: rest[1:], child: n.child})
+			if err != nil {
+				return nil, err
+			}
+			b.children[rest[0]] = ext
+		}
+		if err := t.attach(b, path[c:], value); err != nil {
+			return nil, err
+		}
+		return t.saveWithPrefix(path[:c], b)
+
+	default:
+		if len(path) == 0 {
+			n.value = value
+		} else {
+			child, err := t.insert(n.children[path[0]], path[1:], value)
+			if err != nil {
+				return nil, err
+			}
+			n.children[path[0]] = child
+		}
+		return t.save(n)
+	}
+}
+
+func (t *PatriciaTrie) delete(h, path []byte) ([]byte, bool, error) {
+	if h == nil {
+		return nil, false, nil
+	}
+	n, err := t.load(h)
+	if err != nil {
+		return nil, false, err
+	}
+
+	switch n.kind {
+	case nodeLeaf:
+		if bytes.Equal(n.path, path) {
+			return nil, true, nil
+		}
+		return h, false, nil
+
+	case nodeExtension:
+		if !bytes.HasPrefix(path, n.path) {
+			return h, false, nil
+		}
+		child, found, err := t.delete(n.child, path[len(n.path):])
+		if err != nil || !found {
+			return h, found, err
+		}
+		if child == nil {
+			return nil, true, nil
+		}
+		h, err = t.mergePrefix(n.path, child)
+		return h, true, err
+
+	default:
+		if len(path) == 0 {
+			if n.value == nil {
+				return h, false, nil
+			}
+			n.value = nil
+		} else {
+			child, found, err := t.delete(n.children[path[0]], path[1:])
+			if err != nil || !found {
+				return h, found, err
+			}
+			n.children[path[0]] = child
+		}
+		h, err = t.normalizeBranch(n)
+		return h, true, err
+	}
+}
+
+// normalizeBranch collapses a branch that no longer has at least two entries.
+func (t *PatriciaTrie) normalizeBranch(n *patriciaNode) ([]byte, error) {
+	only, count := -1, 0
+	for i, c := range n.children {
+		if c != nil {
+			only = i
+			count++
+		}
+	}
+	if n.value != nil {
+		count++
+	}
+	switch {
+	case count == 0:
+		return nil, nil
+	case count > 1:
+		return t.save(n)
+	case only < 0:
+		return t.save(&patriciaNode{kind: nodeLeaf, path: []byte{}, value: n.value})
+	default:
+		return t.mergePrefix([]byte{byte(only)}, n.children[only])
+	}
+}
+
+// mergePrefix puts prefix in front of the node h, merging it into leaves and extensions.
+func (t *PatriciaTrie) mergePrefix(prefix, h []byte) ([]byte, error) {
+	child, err := t.load(h)
+	if err != nil {
+		return nil, err
+	}
+	switch child.kind {
+	case nodeLeaf:
+		return t.save(&patriciaNode{kind: nodeLeaf, path: concatNibbles(prefix, child.path), value: child.value})
+	case nodeExtension:
+		return t.save(&patriciaNode{kind: nodeExtension, path: concatNibbles(prefix, child.path), child: child.child})
+	default:
+		return t.save(&patriciaNode{kind: nodeExtension, path: prefix, child: h})
+	}
+}
+
+// attach stores value in branch b under the remaining path.
+func (t *PatriciaTrie) attach(b *patriciaNode, path, value []byte) error {
+	if len(path) == 0 {
+		b.value = value
+		return nil
+	}
+	leaf, err := t.save(&patriciaNode{kind: nodeLeaf, path: path[1:], value: value})
+	if err != nil {
+		return err
+	}
+	b.children[path[0]] = leaf
+	return nil
+}
+
+func (t *PatriciaTrie) saveWithPrefix(prefix []byte, b *patriciaNode) ([]byte, error) {
+	h, err := t.save(b)
+	if err != nil || len(prefix) == 0 {
+		return h, err
+	}
+	return t.save(&patriciaNode{kind: nodeExtension, path: prefix, child: h})
+}
+
+func (t *PatriciaTrie) save(n *patriciaNode) ([]byte, error) {
+	data := n.encode()
+	h := t.HashFunction.Hash(data)
+	if err := t.store.Put(h, data); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+func (t *PatriciaTrie) load(h []byte) (*patriciaNode, error) {
+	data, err := t.store.Get(h)
+	if err != nil {
+		return nil, err
+	}
+	return decodePatriciaNode(data)
+}
+
+func (t *PatriciaTrie) emptyRoot() []byte {
+	return t.HashFunction.Hash(nil)
+}
+
+// encoding: kind | fields, every variable length field prefixed with its uvarint length
+
+func (n *patriciaNode) encode() []byte {
+	buf := []byte{n.kind}
+	putBytes := func(b []byte) {
+		buf = binary.AppendUvarint(buf, uint64(len(b)))
+		buf = append(buf, b...)
+	}
+	switch n.kind {
+	case nodeLeaf:
+		putBytes(n.path)
+		putBytes(n.value)
+	case nodeExtension:
+		putBytes(n.path)
+		putBytes(n.child)
+	case nodeBranch:
+		for _, c := range n.children {
+			putBytes(c)
+		}
+		putBytes(n.value)
+	}
+	return buf
+}
+
+func decodePatriciaNode(data []byte) (*patriciaNode, error) {
+	if len(data) == 0 {
+		return nil, errors.New("empty node")
+	}
+	r := &byteReader{data: data[1:]}
+	getBytes := func() ([]byte, error) {
+		n, err := r.uvarint()
+		if err != nil {
+			return nil, err
+		}
+		if n == 0 {
+			return nil, nil
+		}
+		if n > uint64(len(r.data)) {
+			return nil, errors.New("truncated node")
+		}
+		return r.next(int(n))
+	}
+
+	n := &patriciaNode{kind: data[0]}
+	var err error
+	switch n.kind {
+	case nodeLeaf:
+		if n.path, err = getBytes(); err != nil {
+			return nil, err
+		}
+		n.value, err = getBytes()
+	case nodeExtension:
+		if n.path, err = getBytes(); err != nil {
+			return nil, err
+		}
+		n.child, err = getBytes()
+	case nodeBranch:
+		for i := range n.children {
+			if n.children[i], err = getBytes(); err != nil {
+				return nil, err
+			}
+		}
+		n.value, err = getBytes()
+	default:
+		return nil, fmt.Errorf("unknown node kind %d", n.kind)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(r.data) != 0 {
+		return nil, errors.New("trailing bytes after node")
+	}
+	for _, nib := range n.path {
+		if nib > 0x0F {
+			return nil, errors.New("invalid nibble in node path")
+		}
+	}
+	return n, nil
+}
+
+// helpers
+
+func toNibbles(key []byte) []byte {
+	nibbles := make([]byte, 2*len(key))
+	for i, b := range key {
+		nibbles[2*i] = b >> 4
+		nibbles[2*i+1] = b & 0x0F
+	}
+	return nibbles
+}
+
+func commonPrefix(a, b []byte) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
+}
+
+func concatNibbles(a, b []byte) []byte {
+	res := make([]byte, 0, len(a)+len(b))
+	res = append(res, a...)
+	return append(res, b...)
+}
@@ -0,0 +1,148 @@
+package merkle
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"os"
+	"sync"
+	"testing"
+
+	. "github.com/jnsoft/jngo/testhelper"
+)
+
+func TestPatriciaTrie(t *testing.T) {
+	hf := SHA256Hash{}
+
+	t.Run("Put Get Delete", func(t *testing.T) {
+		trie := NewPatriciaTrie(NewMemoryNodeStore(), hf)
+		emptyRoot := trie.RootHash()
+		AssertTrue(t, trie.IsEmpty())
+
+		keys := []string{"do", "dog", "doge", "horse", "h", ""}
+		for _, k := range keys {
+			AssertNil(t, trie.Put([]byte(k), []byte("v-"+k)))
+		}
+		for _, k := range keys {
+			v, ok, err := trie.Get([]byte(k))
+			AssertNil(t, err)
+			AssertTrue(t, ok)
+			AssertEqual(t, string(v), "v-"+k)
+		}
+		_, ok, _ := trie.Get([]byte("d"))
+		AssertFalse(t, ok)
+
+		found, err := trie.Delete([]byte("cat"))
+		AssertNil(t, err)
+		AssertFalse(t, found)
+
+		for _, k := range keys {
+			found, err := trie.Delete([]byte(k))
+			AssertNil(t, err)
+			AssertTrue(t, found)
+		}
+		AssertTrue(t, trie.IsEmpty())
+		AssertTrue(t, bytes.Equal(trie.RootHash(), emptyRoot))
+	})
+
+	t.Run("Random operations against map", func(t *testing.T) {
+		rnd := rand.New(rand.NewSource(42))
+		trie := NewPatriciaTrie(NewMemoryNodeStore(), hf)
+		expected := make(map[string]string)
+		for i := 0; i < 2000; i++ {
+			k := fmt.Sprintf("%x", rnd.Intn(300))
+			if rnd.Intn(3) == 0 {
+				_, _ = trie.Delete([]byte(k))
+				delete(expected, k)
+			} else {
+				v := fmt.Sprint(i)
+				_ = trie.Put([]byte(k), []byte(v))
+				expected[k] = v
+			}
+		}
+		for k, v := range expected {
+			got, ok, _ := trie.Get([]byte(k))
+			if !ok || string(got) != v {
+				t.Fatalf("key %s: got %q, want %q", k, got, v)
+			}
+		}
+
+		// same content inserted fresh gives the same root
+		fresh := NewPatriciaTrie(NewMemoryNodeStore(), hf)
+		for k, v := range expected {
+			_ = fresh.Put([]byte(k), []byte(v))
+		}
+		AssertTrue(t, bytes.Equal(fresh.RootHash(), trie.RootHash()))
+	})
+
+	t.Run("Proofs", func(t *testing.T) {
+		trie := NewPatriciaTrie(NewMemoryNodeStore(), hf)
+		for i := 0; i < 100; i++ {
+			_ = trie.Put([]byte(fmt.Sprintf("key-%d", i)), []byte(fmt.Sprintf("value-%d", i)))
+		}
+		root := trie.RootHash()
+
+		proof, err := trie.GenerateProof([]byte("key-42"))
+		AssertNil(t, err)
+		v, err := VerifyPatriciaProof(root, []byte("key-42"), proof, hf)
+		AssertNil(t, err)
+		AssertEqual(t, string(v), "value-42")
+
+		proof, _ = trie.GenerateProof([]byte("key-1000"))
+		v, err = VerifyPatriciaProof(root, []byte("key-1000"), proof, hf)
+		AssertNil(t, err)
+		AssertTrue(t, v == nil)
+
+		proof, _ = trie.GenerateProof([]byte("key-7"))
+		proof[len(proof)-1] = append([]byte(nil), proof[len(proof)-1]...)
+		proof[len(proof)-1][len(proof[len(proof)-1])-1] ^= 0xFF
+		_, err = VerifyPatriciaProof(root, []byte("key-7"), proof, hf)
+		AssertTrue(t, err != nil)
+
+		_, err = VerifyPatriciaProof(root, []byte("key-7"), proof[:1], hf)
+		AssertTrue(t, err != nil)
+	})
+
+	t.Run("File store reopen", func(t *testing.T) {
+		store, err := NewFileNodeStore(t.TempDir())
+		AssertNil(t, err)
+		trie := NewPatriciaTrie(store, SHA3_256Hash{})
+		_ = trie.Put([]byte("alpha"), []byte("1"))
+		_ = trie.Put([]byte("beta"), []byte("2"))
+		old := trie.RootHash()
+		_ = trie.Put([]byte("alpha"), []byte("3"))
+
+		reopened, err := OpenPatriciaTrie(old, store, SHA3_256Hash{})
+		AssertNil(t, err)
+		v, _, _ := reopened.Get([]byte("alpha"))
+		AssertEqual(t, string(v), "1")
+
+		_, err = OpenPatriciaTrie([]byte("missing"), store, SHA3_256Hash{})
+		AssertTrue(t, err == ErrNodeNotFound)
+	})
+
+	t.Run("File store concurrent puts", func(t *testing.T) {
+		dir := t.TempDir()
+		store, _ := NewFileNodeStore(dir)
+		data := bytes.Repeat([]byte("node"), 1000)
+		hash := hf.Hash(data)
+		var wg sync.WaitGroup
+		errs := make([]error, 16)
+		for i := range errs {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs[i] = store.Put(hash, data)
+			}()
+		}
+		wg.Wait()
+		for _, err := range errs {
+			AssertNil(t, err)
+		}
+		got, err := store.Get(hash)
+		AssertNil(t, err)
+		AssertTrue(t, bytes.Equal(got, data))
+		entries, _ := os.ReadDir(dir)
+		AssertEqual(t, len(entries), 1)
+	})
+}
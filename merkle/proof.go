@@ -5,9 +5,14 @@ import (
 	"errors"
 )
 
+const (
+	POSITION_LEFT  = "left"
+	POSITION_RIGHT = "right"
+)
+
 type ProofItem struct {
 	Hash     []byte
-	Position string // "left" | "right"
+	Position string // POSITION_LEFT | POSITION_RIGHT
 }
 
 type MerkleProof struct {
@@ -41,10 +46,10 @@ func (t *MerkleTree) GenerateProof(index int) (*MerkleProof, error) {
 			// Only add sibling for the current position
 			if pos == i {
 				// left child, add right sibling
-				path = append(path, ProofItem{Hash: right.Hash, Position: "right"})
+				path = append(path, ProofItem{Hash: right.Hash, Position: POSITION_RIGHT})
 			} else if pos == i+1 {
 				// right child, add left sibling
-				path = append(path, ProofItem{Hash: left.Hash, Position: "left"})
+				path = append(path, ProofItem{Hash: left.Hash, Position: POSITION_LEFT})
 			}
 
 		}
@@ -60,7 +65,7 @@ func VerifyProof(leafData []byte, proof *MerkleProof, rootHash []byte, hf HashFu
 	currentHash := hf.Hash(leafData)
 
 	for _, item := range proof.Path {
-		if item.Position == "left" {
+		if item.Position == POSITION_LEFT {
 			currentHash = hf.Hash(append(item.Hash, currentHash...))
 		} else {
 			currentHash = hf.Hash(append(currentHash, item.Hash...))
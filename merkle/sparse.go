@@ -0,0 +1,456 @@
+package merkle
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+const (
+	SMT_DEPTH    = 256 // bits in a key, one tree level per bit
+	SMT_KEY_SIZE = SMT_DEPTH / 8
+)
+
+// Sparse Merkle tree over the full 2^256 key space. Nodes are stored by hash in a NodeStore like
+// the Patricia trie, so every root ever produced stays readable with OpenSparseMerkleTree. A
+// subtree holding a single key is stored as one short node with the key and value, so an update
+// writes a node per level where keys branch, not one per key bit. Hashes are those of the full
+// tree: empty subtrees are the precomputed empty-subtree hashes and a short node hashes like its
+// key's leaf hashed up through empty siblings.
+
+const (
+	smtShort byte = 0 // key | value
+	smtInner byte = 1 // left hash | right hash
+)
+
+type SparseMerkleTree struct {
+	HashFunction HashFunction
+	store        NodeStore
+	root         []byte
+	empty        [][]byte // empty[d] = hash of an empty subtree rooted at depth d
+	size         int
+}
+
+type smtNode struct {
+	kind        byte
+	key         [SMT_KEY_SIZE]byte // short only
+	value       []byte             // short only
+	left, right []byte             // inner only
+}
+
+// SparseMerkleProof holds one sibling per level, ordered from the leaf up to the root.
+type SparseMerkleProof struct {
+	Key      []byte
+	Siblings [][]byte
+}
+
+// CompressedSparseProof drops the siblings that equal the empty-subtree hash.
+// Bit i in Bitmap is set when Siblings contains an entry for level i (leaf = 0).
+type CompressedSparseProof struct {
+	Key      []byte
+	Bitmap   [SMT_KEY_SIZE]byte
+	Siblings [][]byte
+}
+
+func NewSparseMerkleTree(store NodeStore, hf HashFunction) *SparseMerkleTree {
+	empty := emptySubtreeHashes(hf)
+	return &SparseMerkleTree{
+		HashFunction: hf,
+		store:        store,
+		root:         empty[0],
+		empty:        empty,
+	}
+}
+
+// OpenSparseMerkleTree opens the tree with the given root hash in store. It walks the tree once
+// to count the keys.
+func OpenSparseMerkleTree(root []byte, store NodeStore, hf HashFunction) (*SparseMerkleTree, error) {
+	t := NewSparseMerkleTree(store, hf)
+	if bytes.Equal(root, t.empty[0]) {
+		return t, nil
+	}
+	size, err := t.count(root, 0)
+	if err != nil {
+		return nil, err
+	}
+	t.root = append([]byte(nil), root...)
+	t.size = size
+	return t, nil
+}
+
+func emptySubtreeHashes(hf HashFunction) [][]byte {
+	empty := make([][]byte, SMT_DEPTH+1)
+	empty[SMT_DEPTH] = make([]byte, len(hf.Hash(nil)))
+	for d := SMT_DEPTH - 1; d >= 0; d-- {
+		empty[d] = hashPair(hf, empty[d+1], empty[d+1])
+	}
+	return empty
+}
+
+func (t *SparseMerkleTree) Root() []byte {
+	return t.root
+}
+
+// Size returns the number of keys.
+func (t *SparseMerkleTree) Size() int {
+	return t.size
+}
+
+func (t *SparseMerkleTree) Get(key []byte) ([]byte, bool, error) {
+	k, err := toSMTKey(key)
+	if err != nil {
+		return nil, false, err
+	}
+	value, _, err := t.walk(k)
+	return value, value != nil, err
+}
+
+// Update sets the value for key. An empty value removes the key.
+func (t *SparseMerkleTree) Update(key, value []byte) error {
+	k, err := toSMTKey(key)
+	if err != nil {
+		return err
+	}
+	if len(value) == 0 {
+		value = nil
+	} else {
+		value = append([]byte(nil), value...)
+	}
+	root, delta, err := t.update(t.root, 0, k, value)
+	if err != nil {
+		return err
+	}
+	t.root = root
+	t.size += delta
+	return nil
+}
+
+func (t *SparseMerkleTree) Delete(key []byte) error {
+	return t.Update(key, nil)
+}
+
+func (t *SparseMerkleTree) GenerateProof(key []byte) (*SparseMerkleProof, error) {
+	k, err := toSMTKey(key)
+	if err != nil {
+		return nil, err
+	}
+	_, siblings, err := t.walk(k)
+	if err != nil {
+		return nil, err
+	}
+	return &SparseMerkleProof{Key: k[:], Siblings: siblings}, nil
+}
+
+// walk follows k from the root and returns its value, nil if it is absent, and the siblings on
+// the way, ordered from the leaf up like a proof.
+func (t *SparseMerkleTree) walk(k [SMT_KEY_SIZE]byte) ([]byte, [][]byte, error) {
+	siblings := make([][]byte, SMT_DEPTH)
+	for i := range siblings {
+		siblings[i] = t.empty[SMT_DEPTH-i]
+	}
+	h := t.root
+	for d := 0; !bytes.Equal(h, t.empty[d]); d++ {
+		n, err := t.load(h)
+		if err != nil {
+			return nil, nil, err
+		}
+		if n.kind == smtShort {
+			if n.key == k {
+				return n.value, siblings, nil
+			}
+			// the other key is alone in the subtree where the paths split
+			p := d
+			for bitAt(k[:], p) == bitAt(n.key[:], p) {
+				p++
+			}
+			siblings[SMT_DEPTH-1-p] = t.shortHash(p+1, n.key, n.value)
+			return nil, siblings, nil
+		}
+		next, sib := n.left, n.right
+		if bitAt(k[:], d) == 1 {
+			next, sib = sib, next
+		}
+		siblings[SMT_DEPTH-1-d] = sib
+		h = next
+	}
+	return nil, siblings, nil
+}
+
+// update sets k to value, nil to delete it, in the subtree at depth with hash h. It returns the
+// new hash of the subtree and the change in the number of keys.
+func (t *SparseMerkleTree) update(h []byte, depth int, k [SMT_KEY_SIZE]byte, value []byte) ([]byte, int, error) {
+	if bytes.Equal(h, t.empty[depth]) {
+		if value == nil {
+			return h, 0, nil
+		}
+		h, err := t.saveShort(depth, k, value)
+		return h, 1, err
+	}
+	n, err := t.load(h)
+	if err != nil {
+		return nil, 0, err
+	}
+	if n.kind == smtShort {
+		switch {
+		case n.key == k && value == nil:
+			return t.empty[depth], -1, nil
+		case n.key == k:
+			h, err := t.saveShort(depth, k, value)
+			return h, 0, err
+		case value == nil:
+			return h, 0, nil
+		}
+		h, err := t.split(depth, n, k, value)
+		return h, 1, err
+	}
+
+	left, right := n.left, n.right
+	var delta int
+	if bitAt(k[:], depth) == 0 {
+		left, delta, err = t.update(left, depth+1, k, value)
+	} else {
+		right, delta, err = t.update(right, depth+1, k, value)
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	h, err = t.join(depth, left, right)
+	return h, delta, err
+}
+
+// split stores the subtree at depth holding the key of the short node n and k.
+func (t *SparseMerkleTree) split(depth int, n *smtNode, k [SMT_KEY_SIZE]byte, value []byte) ([]byte, error) {
+	bit := bitAt(k[:], depth)
+	if bit == bitAt(n.key[:], depth) {
+		child, err := t.split(depth+1, n, k, value)
+		if err != nil {
+			return nil, err
+		}
+		if bit == 0 {
+			return t.saveInner(child, t.empty[depth+1])
+		}
+		return t.saveInner(t.empty[depth+1], child)
+	}
+	a, err := t.saveShort(depth+1, k, value)
+	if err != nil {
+		return nil, err
+	}
+	b, err := t.saveShort(depth+1, n.key, n.value)
+	if err != nil {
+		return nil, err
+	}
+	if bit == 1 {
+		a, b = b, a
+	}
+	return t.saveInner(a, b)
+}
+
+// join stores the node at depth with the given children, collapsing a subtree left with a
+// single key into a short node.
+func (t *SparseMerkleTree) join(depth int, left, right []byte) ([]byte, error) {
+	empty := t.empty[depth+1]
+	leftEmpty, rightEmpty := bytes.Equal(left, empty), bytes.Equal(right, empty)
+	if leftEmpty && rightEmpty {
+		return t.empty[depth], nil
+	}
+	if leftEmpty || rightEmpty {
+		child := left
+		if leftEmpty {
+			child = right
+		}
+		n, err := t.load(child)
+		if err != nil {
+			return nil, err
+		}
+		if n.kind == smtShort {
+			return t.saveShort(depth, n.key, n.value)
+		}
+	}
+	return t.saveInner(left, right)
+}
+
+// shortHash returns the hash of the subtree at depth holding only k.
+func (t *SparseMerkleTree) shortHash(depth int, k [SMT_KEY_SIZE]byte, value []byte) []byte {
+	h := t.HashFunction.Hash(value)
+	for d := SMT_DEPTH; d > depth; d-- {
+		if bitAt(k[:], d-1) == 0 {
+			h = hashPair(t.HashFunction, h, t.empty[d])
+		} else {
+			h = hashPair(t.HashFunction, t.empty[d], h)
+		}
+	}
+	return h
+}
+
+func (t *SparseMerkleTree) saveShort(depth int, k [SMT_KEY_SIZE]byte, value []byte) ([]byte, error) {
+	h := t.shortHash(depth, k, value)
+	data := make([]byte, 0, 1+SMT_KEY_SIZE+len(value))
+	data = append(data, smtShort)
+	data = append(data, k[:]...)
+	data = append(data, value...)
+	if err := t.store.Put(h, data); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+func (t *SparseMerkleTree) saveInner(left, right []byte) ([]byte, error) {
+	h := hashPair(t.HashFunction, left, right)
+	data := make([]byte, 0, 1+len(left)+len(right))
+	data = append(data, smtInner)
+	data = append(data, left...)
+	data = append(data, right...)
+	if err := t.store.Put(h, data); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+func (t *SparseMerkleTree) load(h []byte) (*smtNode, error) {
+	data, err := t.store.Get(h)
+	if err != nil {
+		return nil, err
+	}
+	size := len(t.empty[0])
+	switch {
+	case len(data) > SMT_KEY_SIZE+1 && data[0] == smtShort:
+		n := &smtNode{kind: smtShort, value: data[1+SMT_KEY_SIZE:]}
+		copy(n.key[:], data[1:])
+		return n, nil
+	case len(data) == 1+2*size && data[0] == smtInner:
+		return &smtNode{kind: smtInner, left: data[1 : 1+size], right: data[1+size:]}, nil
+	}
+	return nil, errors.New("corrupt sparse Merkle tree node")
+}
+
+// count returns the number of keys in the subtree at depth with hash h.
+func (t *SparseMerkleTree) count(h []byte, depth int) (int, error) {
+	if bytes.Equal(h, t.empty[depth]) {
+		return 0, nil
+	}
+	n, err := t.load(h)
+	if err != nil || n.kind == smtShort {
+		return 1, err
+	}
+	left, err := t.count(n.left, depth+1)
+	if err != nil {
+		return 0, err
+	}
+	right, err := t.count(n.right, depth+1)
+	return left + right, err
+}
+
+// VerifyInclusion checks that key maps to value under root.
+func (p *SparseMerkleProof) VerifyInclusion(root, value []byte, hf HashFunction) bool {
+	if len(value) == 0 {
+		return false
+	}
+	return p.verify(root, hf.Hash(value), hf)
+}
+
+// VerifyNonInclusion checks that key is absent under root.
+func (p *SparseMerkleProof) VerifyNonInclusion(root []byte, hf HashFunction) bool {
+	return p.verify(root, make([]byte, len(hf.Hash(nil))), hf)
+}
+
+func (p *SparseMerkleProof) verify(root, leaf []byte, hf HashFunction) bool {
+	if len(p.Key) != SMT_KEY_SIZE || len(p.Siblings) != SMT_DEPTH {
+		return false
+	}
+	cur := leaf
+	for d := SMT_DEPTH; d > 0; d-- {
+		sib := p.Siblings[SMT_DEPTH-d]
+		if bitAt(p.Key, d-1) == 0 {
+			cur = hashPair(hf, cur, sib)
+		} else {
+			cur = hashPair(hf, sib, cur)
+		}
+	}
+	return bytes.Equal(cur, root)
+}
+
+// Compress removes default siblings from the proof.
+func (p *SparseMerkleProof) Compress(hf HashFunction) *CompressedSparseProof {
+	empty := emptySubtreeHashes(hf)
+	cp := &CompressedSparseProof{Key: append([]byte(nil), p.Key...)}
+	for i, sib := range p.Siblings {
+		if !bytes.Equal(sib, empty[SMT_DEPTH-i]) {
+			cp.Bitmap[i/8] |= 1 << (7 - i%8)
+			cp.Siblings = append(cp.Siblings, sib)
+		}
+	}
+	return cp
+}
+
+// Decompress restores the full proof by filling in the empty-subtree hashes.
+func (cp *CompressedSparseProof) Decompress(hf HashFunction) (*SparseMerkleProof, error) {
+	empty := emptySubtreeHashes(hf)
+	siblings := make([][]byte, SMT_DEPTH)
+	next := 0
+	for i := 0; i < SMT_DEPTH; i++ {
+		if bitAt(cp.Bitmap[:], i) == 1 {
+			if next >= len(cp.Siblings) {
+				return nil, errors.New("compressed proof has too few siblings")
+			}
+			siblings[i] = cp.Siblings[next]
+			next++
+		} else {
+			siblings[i] = empty[SMT_DEPTH-i]
+		}
+	}
+	if next != len(cp.Siblings) {
+		return nil, errors.New("compressed proof has too many siblings")
+	}
+	return &SparseMerkleProof{Key: append([]byte(nil), cp.Key...), Siblings: siblings}, nil
+}
+
+// Bytes encodes the proof as key || bitmap || siblings.
+func (cp *CompressedSparseProof) Bytes() []byte {
+	var buf bytes.Buffer
+	buf.Write(cp.Key)
+	buf.Write(cp.Bitmap[:])
+	for _, sib := range cp.Siblings {
+		buf.Write(sib)
+	}
+	return buf.Bytes()
+}
+
+// DecodeCompressedSparseProof parses the output of Bytes, hashSize is the digest length in bytes.
+func DecodeCompressedSparseProof(data []byte, hashSize int) (*CompressedSparseProof, error) {
+	if hashSize <= 0 {
+		return nil, errors.New("hash size must be positive")
+	}
+	header := SMT_KEY_SIZE * 2
+	if len(data) < header || (len(data)-header)%hashSize != 0 {
+		return nil, fmt.Errorf("invalid compressed proof length %d", len(data))
+	}
+	cp := &CompressedSparseProof{Key: append([]byte(nil), data[:SMT_KEY_SIZE]...)}
+	copy(cp.Bitmap[:], data[SMT_KEY_SIZE:header])
+	for off := header; off < len(data); off += hashSize {
+		cp.Siblings = append(cp.Siblings, append([]byte(nil), data[off:off+hashSize]...))
+	}
+	return cp, nil
+}
+
+// helpers
+
+func toSMTKey(key []byte) ([SMT_KEY_SIZE]byte, error) {
+	var k [SMT_KEY_SIZE]byte
+	if len(key) != SMT_KEY_SIZE {
+		return k, fmt.Errorf("key must be %d bytes, got %d", SMT_KEY_SIZE, len(key))
+	}
+	copy(k[:], key)
+	return k, nil
+}
+
+func hashPair(hf HashFunction, left, right []byte) []byte {
+	combined := make([]byte, 0, len(left)+len(right))
+	combined = append(combined, left...)
+	combined = append(combined, right...)
+	return hf.Hash(combined)
+}
+
+// bitAt returns bit i of b, most significant bit first.
+func bitAt(b []byte, i int) byte {
+	return (b[i/8] >> (7 - i%8)) & 1
+}
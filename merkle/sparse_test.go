@@ -0,0 +1,224 @@
+package merkle
+
+import (
+	"bytes"
+	"testing"
+
+	. "github.com/jnsoft/jngo/testhelper"
+)
+
+func TestSparseMerkleTree(t *testing.T) {
+	hf := SHA256Hash{}
+
+	t.Run("Empty root", func(t *testing.T) {
+		a := NewSparseMerkleTree(NewMemoryNodeStore(), hf)
+		b := NewSparseMerkleTree(NewMemoryNodeStore(), hf)
+		AssertTrue(t, bytes.Equal(a.Root(), b.Root()))
+		AssertEqual(t, a.Size(), 0)
+	})
+
+	t.Run("Update Get Delete", func(t *testing.T) {
+		tree := NewSparseMerkleTree(NewMemoryNodeStore(), hf)
+		emptyRoot := tree.Root()
+		k1, k2 := hf.Hash([]byte("k1")), hf.Hash([]byte("k2"))
+
+		AssertNil(t, tree.Update(k1, []byte("v1")))
+		AssertNil(t, tree.Update(k2, []byte("v2")))
+		AssertEqual(t, tree.Size(), 2)
+
+		v, ok, err := tree.Get(k1)
+		AssertNil(t, err)
+		AssertTrue(t, ok)
+		AssertEqual(t, string(v), "v1")
+
+		AssertNil(t, tree.Delete(k1))
+		_, ok, _ = tree.Get(k1)
+		AssertFalse(t, ok)
+
+		AssertNil(t, tree.Delete(k2))
+		AssertTrue(t, bytes.Equal(tree.Root(), emptyRoot))
+		AssertEqual(t, tree.Size(), 0)
+	})
+
+	t.Run("Same root as the full tree", func(t *testing.T) {
+		tree := NewSparseMerkleTree(NewMemoryNodeStore(), hf)
+		values := map[[SMT_KEY_SIZE]byte][]byte{}
+		var keys [][SMT_KEY_SIZE]byte
+		for i := 0; i < 20; i++ {
+			keys = append(keys, [SMT_KEY_SIZE]byte(hf.Hash([]byte{byte(i)})))
+		}
+		// keys that only differ in the last bits split at the bottom of the tree
+		near := keys[0]
+		near[SMT_KEY_SIZE-1] ^= 1
+		keys = append(keys, near)
+		near[SMT_KEY_SIZE-1] ^= 2
+		keys = append(keys, near)
+
+		for i, k := range keys {
+			values[k] = []byte{byte(i), 1}
+			AssertNil(t, tree.Update(k[:], values[k]))
+			AssertTrue(t, bytes.Equal(tree.Root(), fullSMTRoot(hf, values)))
+		}
+		AssertEqual(t, tree.Size(), len(keys))
+		for i, k := range keys {
+			if i%2 == 0 {
+				delete(values, k)
+				AssertNil(t, tree.Delete(k[:]))
+			} else {
+				values[k] = []byte{byte(i), 2}
+				AssertNil(t, tree.Update(k[:], values[k]))
+			}
+			AssertTrue(t, bytes.Equal(tree.Root(), fullSMTRoot(hf, values)))
+		}
+		AssertEqual(t, tree.Size(), len(values))
+
+		for i, k := range keys {
+			proof, err := tree.GenerateProof(k[:])
+			AssertNil(t, err)
+			if i%2 == 0 {
+				AssertTrue(t, proof.VerifyNonInclusion(tree.Root(), hf))
+			} else {
+				AssertTrue(t, proof.VerifyInclusion(tree.Root(), values[k], hf))
+			}
+		}
+	})
+
+	t.Run("Writes grow with the number of keys", func(t *testing.T) {
+		store := &countingStore{NodeStore: NewMemoryNodeStore()}
+		tree := NewSparseMerkleTree(store, hf)
+		for i := 0; i < 1000; i++ {
+			_ = tree.Update(hf.Hash([]byte{byte(i), byte(i >> 8)}), []byte("v"))
+		}
+		store.puts = 0
+		_ = tree.Update(hf.Hash([]byte("one more")), []byte("v"))
+		AssertTrue(t, store.puts < 32)
+	})
+
+	t.Run("Reopen from a file store", func(t *testing.T) {
+		store, err := NewFileNodeStore(t.TempDir())
+		AssertNil(t, err)
+		tree := NewSparseMerkleTree(store, hf)
+		k1, k2 := hf.Hash([]byte("k1")), hf.Hash([]byte("k2"))
+		AssertNil(t, tree.Update(k1, []byte("v1")))
+		old := tree.Root()
+		AssertNil(t, tree.Update(k2, []byte("v2")))
+
+		reopened, err := OpenSparseMerkleTree(tree.Root(), store, hf)
+		AssertNil(t, err)
+		AssertEqual(t, reopened.Size(), 2)
+		v, ok, err := reopened.Get(k2)
+		AssertNil(t, err)
+		AssertTrue(t, ok)
+		AssertEqual(t, string(v), "v2")
+
+		// earlier roots stay readable
+		previous, err := OpenSparseMerkleTree(old, store, hf)
+		AssertNil(t, err)
+		_, ok, _ = previous.Get(k2)
+		AssertFalse(t, ok)
+		proof, err := previous.GenerateProof(k1)
+		AssertNil(t, err)
+		AssertTrue(t, proof.VerifyInclusion(old, []byte("v1"), hf))
+
+		_, err = OpenSparseMerkleTree(hf.Hash([]byte("unknown")), store, hf)
+		AssertTrue(t, err != nil)
+	})
+
+	t.Run("Order independent root", func(t *testing.T) {
+		a := NewSparseMerkleTree(NewMemoryNodeStore(), hf)
+		b := NewSparseMerkleTree(NewMemoryNodeStore(), hf)
+		keys := []string{"a", "b", "c", "d", "e"}
+		for _, k := range keys {
+			_ = a.Update(hf.Hash([]byte(k)), []byte("val-"+k))
+		}
+		for i := len(keys) - 1; i >= 0; i-- {
+			_ = b.Update(hf.Hash([]byte(keys[i])), []byte("val-"+keys[i]))
+		}
+		AssertTrue(t, bytes.Equal(a.Root(), b.Root()))
+	})
+
+	t.Run("Invalid key", func(t *testing.T) {
+		tree := NewSparseMerkleTree(NewMemoryNodeStore(), hf)
+		err := tree.Update([]byte("short"), []byte("v"))
+		AssertTrue(t, err != nil)
+	})
+
+	t.Run("Inclusion and non-inclusion proofs", func(t *testing.T) {
+		tree := NewSparseMerkleTree(NewMemoryNodeStore(), SHA3_256Hash{})
+		sha3 := SHA3_256Hash{}
+		present := sha3.Hash([]byte("present"))
+		absent := sha3.Hash([]byte("absent"))
+		_ = tree.Update(present, []byte("here"))
+		_ = tree.Update(sha3.Hash([]byte("other")), []byte("there"))
+
+		proof, err := tree.GenerateProof(present)
+		AssertNil(t, err)
+		AssertTrue(t, proof.VerifyInclusion(tree.Root(), []byte("here"), sha3))
+		AssertFalse(t, proof.VerifyInclusion(tree.Root(), []byte("wrong"), sha3))
+		AssertFalse(t, proof.VerifyNonInclusion(tree.Root(), sha3))
+
+		proof, err = tree.GenerateProof(absent)
+		AssertNil(t, err)
+		AssertTrue(t, proof.VerifyNonInclusion(tree.Root(), sha3))
+		AssertFalse(t, proof.VerifyInclusion(tree.Root(), []byte("here"), sha3))
+	})
+
+	t.Run("Compressed proof round trip", func(t *testing.T) {
+		tree := NewSparseMerkleTree(NewMemoryNodeStore(), hf)
+		for i := 0; i < 20; i++ {
+			_ = tree.Update(hf.Hash([]byte{byte(i)}), []byte{byte(i), 1})
+		}
+		key := hf.Hash([]byte{7})
+		proof, _ := tree.GenerateProof(key)
+
+		cp := proof.Compress(hf)
+		AssertTrue(t, len(cp.Siblings) < 32)
+
+		decoded, err := DecodeCompressedSparseProof(cp.Bytes(), 32)
+		AssertNil(t, err)
+		full, err := decoded.Decompress(hf)
+		AssertNil(t, err)
+		AssertTrue(t, full.VerifyInclusion(tree.Root(), []byte{7, 1}, hf))
+
+		_, err = DecodeCompressedSparseProof(cp.Bytes()[:70], 32)
+		AssertTrue(t, err != nil)
+	})
+}
+
+type countingStore struct {
+	NodeStore
+	puts int
+}
+
+func (s *countingStore) Put(hash, data []byte) error {
+	s.puts++
+	return s.NodeStore.Put(hash, data)
+}
+
+// fullSMTRoot hashes every level of the tree holding values.
+func fullSMTRoot(hf HashFunction, values map[[SMT_KEY_SIZE]byte][]byte) []byte {
+	empty := emptySubtreeHashes(hf)
+	var root func(depth int, keys [][SMT_KEY_SIZE]byte) []byte
+	root = func(depth int, keys [][SMT_KEY_SIZE]byte) []byte {
+		if len(keys) == 0 {
+			return empty[depth]
+		}
+		if depth == SMT_DEPTH {
+			return hf.Hash(values[keys[0]])
+		}
+		var left, right [][SMT_KEY_SIZE]byte
+		for _, k := range keys {
+			if bitAt(k[:], depth) == 0 {
+				left = append(left, k)
+			} else {
+				right = append(right, k)
+			}
+		}
+		return hashPair(hf, root(depth+1, left), root(depth+1, right))
+	}
+	var keys [][SMT_KEY_SIZE]byte
+	for k := range values {
+		keys = append(keys, k)
+	}
+	return root(0, keys)
+}
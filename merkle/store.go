@@ -0,0 +1,99 @@
+package merkle
+
+import (
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+var ErrNodeNotFound = errors.New("node not found")
+
+// NodeStore is a content-addressed store for encoded tree nodes, keyed by node hash.
+type NodeStore interface {
+	Get(hash []byte) ([]byte, error)
+	Put(hash, data []byte) error
+}
+
+type MemoryNodeStore struct {
+	nodes map[string][]byte
+	mu    sync.RWMutex
+}
+
+// FileNodeStore keeps one file per node, named by the hex encoded hash.
+type FileNodeStore struct {
+	dir string
+}
+
+func NewMemoryNodeStore() *MemoryNodeStore {
+	return &MemoryNodeStore{nodes: make(map[string][]byte)}
+}
+
+func (s *MemoryNodeStore) Get(hash []byte) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	data, ok := s.nodes[string(hash)]
+	if !ok {
+		return nil, ErrNodeNotFound
+	}
+	return data, nil
+}
+
+func (s *MemoryNodeStore) Put(hash, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nodes[string(hash)] = append([]byte(nil), data...)
+	return nil
+}
+
+func (s *MemoryNodeStore) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.nodes)
+}
+
+func NewFileNodeStore(dir string) (*FileNodeStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileNodeStore{dir: dir}, nil
+}
+
+func (s *FileNodeStore) Get(hash []byte) ([]byte, error) {
+	data, err := os.ReadFile(s.path(hash))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNodeNotFound
+	}
+	return data, err
+}
+
+func (s *FileNodeStore) Put(hash, data []byte) error {
+	path := s.path(hash)
+	if _, err := os.Stat(path); err == nil {
+		return nil // content addressed, already stored
+	}
+	// a unique temporary file, so concurrent writers of the same node never share it
+	tmp, err := os.CreateTemp(s.dir, ".node-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+func (s *FileNodeStore) path(hash []byte) string {
+	return filepath.Join(s.dir, hex.EncodeToString(hash))
+}
@@ -0,0 +1,144 @@
+package parallell
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+var ErrPoolClosed = errors.New("pool is closed")
+
+// Pool is a long-lived set of workers executing submitted tasks. The number of workers can be
+// changed while tasks are running.
+type Pool struct {
+	ctx     context.Context
+	tasks   chan func()
+	size    int           // wanted number of workers
+	running int           // started workers that have not exited
+	resized chan struct{} // closed and replaced on every resize to wake idle workers
+	closed  bool
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	closeMu sync.RWMutex // held for reading while submitting, for writing while closing
+}
+
+// Future is the pending result of a task submitted with Submit.
+type Future[T any] struct {
+	done  chan struct{}
+	value T
+	err   error
+}
+
+// NewPool starts a pool with the given number of workers. Tasks receive ctx.
+func NewPool(ctx context.Context, workers int) *Pool {
+	p := &Pool{ctx: ctx, tasks: make(chan func()), resized: make(chan struct{})}
+	_ = p.Resize(workers)
+	return p
+}
+
+// Workers returns the wanted number of workers.
+func (p *Pool) Workers() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.size
+}
+
+// Resize changes the number of workers, at least one. Surplus workers exit after their current task.
+// It returns ErrPoolClosed once Close has been called.
+func (p *Pool) Resize(workers int) error {
+	workers = max(workers, 1)
+	p.closeMu.RLock()
+	defer p.closeMu.RUnlock()
+	if p.closed {
+		return ErrPoolClosed
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.size = workers
+	for p.running < p.size {
+		p.running++
+		p.wg.Add(1)
+		go p.work()
+	}
+	close(p.resized)
+	p.resized = make(chan struct{})
+	return nil
+}
+
+// Close stops accepting tasks and waits until the submitted tasks are done.
+func (p *Pool) Close() {
+	p.closeMu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.tasks)
+	}
+	p.closeMu.Unlock()
+	p.wg.Wait()
+}
+
+// Submit queues fn on the pool, blocking until a worker takes it or ctx is done.
+// A panic in fn is returned as a *PanicError by the future.
+func Submit[T any](ctx context.Context, p *Pool, fn func(context.Context) (T, error)) (*Future[T], error) {
+	f := &Future[T]{done: make(chan struct{})}
+	task := func() {
+		defer close(f.done)
+		f.err = safeCall(func() error {
+			var err error
+			f.value, err = fn(p.ctx)
+			return err
+		})
+	}
+
+	p.closeMu.RLock()
+	defer p.closeMu.RUnlock()
+	if p.closed {
+		return nil, ErrPoolClosed
+	}
+	select {
+	case p.tasks <- task:
+		return f, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Done is closed when the task has finished.
+func (f *Future[T]) Done() <-chan struct{} {
+	return f.done
+}
+
+// Wait blocks until the task has finished or ctx is done.
+func (f *Future[T]) Wait(ctx context.Context) (T, error) {
+	select {
+	case <-f.done:
+		return f.value, f.err
+	case <-ctx.Done():
+		return *new(T), ctx.Err()
+	}
+}
+
+func (p *Pool) work() {
+	defer p.wg.Done()
+	for {
+		p.mu.Lock()
+		if p.running > p.size {
+			p.running--
+			p.mu.Unlock()
+			return
+		}
+		resized := p.resized
+		p.mu.Unlock()
+
+		select {
+		case task, ok := <-p.tasks:
+			if !ok {
+				p.mu.Lock()
+				p.running--
+				p.mu.Unlock()
+				return
+			}
+			task()
+		case <-resized:
+		}
+	}
+}
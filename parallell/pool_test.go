@@ -0,0 +1,137 @@
+package parallell
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/jnsoft/jngo/testhelper"
+)
+
+var errBoom = errors.New("boom")
+
+func TestParallelMap(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Results in input order", func(t *testing.T) {
+		items := []int{5, 1, 4, 2, 3}
+		got, err := ParallelMap(ctx, items, 3, func(_ context.Context, n int) (int, error) {
+			time.Sleep(time.Duration(n) * time.Millisecond)
+			return n * n, nil
+		})
+		AssertNil(t, err)
+		CollectionAssertEqual(t, got, []int{25, 1, 16, 4, 9})
+	})
+
+	t.Run("First error cancels the rest", func(t *testing.T) {
+		var started atomic.Int32
+		items := make([]int, 100)
+		_, err := ParallelMap(ctx, items, 2, func(ctx context.Context, _ int) (int, error) {
+			if started.Add(1) == 1 {
+				return 0, errBoom
+			}
+			<-ctx.Done()
+			return 0, ctx.Err()
+		})
+		AssertTrue(t, errors.Is(err, errBoom))
+		AssertTrue(t, started.Load() < 100)
+	})
+
+	t.Run("Panics become errors", func(t *testing.T) {
+		_, err := ParallelMap(ctx, []int{1, 2}, 2, func(_ context.Context, n int) (int, error) {
+			if n == 2 {
+				panic(errBoom)
+			}
+			return n, nil
+		})
+		var pe *PanicError
+		AssertTrue(t, errors.As(err, &pe))
+		AssertTrue(t, errors.Is(err, errBoom))
+		AssertTrue(t, len(pe.Stack) > 0)
+	})
+}
+
+func TestPool(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Submit and wait", func(t *testing.T) {
+		p := NewPool(ctx, 2)
+		var futures []*Future[int]
+		for i := 0; i < 10; i++ {
+			f, err := Submit(ctx, p, func(context.Context) (int, error) { return i * 2, nil })
+			AssertNil(t, err)
+			futures = append(futures, f)
+		}
+		for i, f := range futures {
+			v, err := f.Wait(ctx)
+			AssertNil(t, err)
+			AssertEqual(t, v, i*2)
+		}
+		p.Close()
+		_, err := Submit(ctx, p, func(context.Context) (int, error) { return 0, nil })
+		AssertTrue(t, errors.Is(err, ErrPoolClosed))
+	})
+
+	t.Run("Resize", func(t *testing.T) {
+		p := NewPool(ctx, 1)
+		defer p.Close()
+		var running, peak atomic.Int32
+		release := make(chan struct{})
+		task := func(context.Context) (struct{}, error) {
+			n := running.Add(1)
+			for {
+				cur := peak.Load()
+				if n <= cur || peak.CompareAndSwap(cur, n) {
+					break
+				}
+			}
+			<-release
+			running.Add(-1)
+			return struct{}{}, nil
+		}
+
+		AssertNil(t, p.Resize(4))
+		AssertEqual(t, p.Workers(), 4)
+		var futures []*Future[struct{}]
+		for i := 0; i < 4; i++ {
+			f, err := Submit(ctx, p, task)
+			AssertNil(t, err)
+			futures = append(futures, f)
+		}
+		for running.Load() < 4 {
+			time.Sleep(time.Millisecond)
+		}
+		close(release)
+		for _, f := range futures {
+			_, _ = f.Wait(ctx)
+		}
+		AssertEqual(t, peak.Load(), 4)
+
+		AssertNil(t, p.Resize(1))
+		AssertEqual(t, p.Workers(), 1)
+		f, err := Submit(ctx, p, func(context.Context) (int, error) { panic("bad") })
+		AssertNil(t, err)
+		_, err = f.Wait(ctx)
+		var pe *PanicError
+		AssertTrue(t, errors.As(err, &pe))
+
+		closed := NewPool(ctx, 2)
+		closed.Close()
+		AssertTrue(t, errors.Is(closed.Resize(4), ErrPoolClosed))
+		AssertEqual(t, closed.Workers(), 2)
+	})
+
+	t.Run("Submit honours context", func(t *testing.T) {
+		p := NewPool(ctx, 1)
+		defer p.Close()
+		block := make(chan struct{})
+		_, _ = Submit(ctx, p, func(context.Context) (int, error) { <-block; return 0, nil })
+		short, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+		_, err := Submit(short, p, func(context.Context) (int, error) { return 0, nil })
+		AssertTrue(t, errors.Is(err, context.DeadlineExceeded))
+		close(block)
+	})
+}
@@ -0,0 +1,243 @@
+package parallell
+
+import (
+	"runtime"
+	"sync"
+)
+
+// Chunking decides how ParallelFor hands index ranges to workers.
+type Chunking int
+
+const (
+	STATIC        Chunking = iota // one contiguous range per worker
+	DYNAMIC                       // ranges of Grain indexes taken from a shared counter
+	GUIDED                        // like DYNAMIC, ranges shrink with the remaining work, down to Grain
+	WORK_STEALING                 // per-worker queues of Grain-sized ranges, idle workers steal from busy ones
+)
+
+const (
+	DEFAULT_GRAIN = 1024
+)
+
+type ForOptions struct {
+	Workers  int // defaults to GOMAXPROCS
+	Chunking Chunking
+	Grain    int // minimum range length, defaults to DEFAULT_GRAIN
+}
+
+// ParallelFor calls body for every index in [start, end).
+func ParallelFor(start, end int, opts ForOptions, body func(i int)) {
+	ParallelForRange(start, end, opts, func(lo, hi int) {
+		for i := lo; i < hi; i++ {
+			body(i)
+		}
+	})
+}
+
+// ParallelForRange splits [start, end) into ranges and calls body for each of them.
+func ParallelForRange(start, end int, opts ForOptions, body func(lo, hi int)) {
+	n := end - start
+	if n <= 0 {
+		return
+	}
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	grain := opts.Grain
+	if grain <= 0 {
+		grain = DEFAULT_GRAIN
+	}
+	workers = min(workers, (n+grain-1)/grain)
+	if workers <= 1 {
+		body(start, end)
+		return
+	}
+
+	switch opts.Chunking {
+	case DYNAMIC:
+		forDynamic(start, end, workers, func(remaining int) int { return grain }, body)
+	case GUIDED:
+		forDynamic(start, end, workers, func(remaining int) int { return max(remaining/(2*workers), grain) }, body)
+	case WORK_STEALING:
+		forStealing(start, end, workers, grain, body)
+	default:
+		forStatic(start, end, workers, func(_, lo, hi int) { body(lo, hi) })
+	}
+}
+
+// ParallelReduce folds each chunk of items with accumulate, starting from identity, and combines
+// the chunk results in order. combine must be associative and identity neutral for it.
+func ParallelReduce[T, A any](items []T, workers int, identity A, accumulate func(A, T) A, combine func(A, A) A) A {
+	if len(items) == 0 {
+		return identity
+	}
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	workers = max(min(workers, len(items)), 1)
+	partial := make([]A, workers)
+	forStatic(0, len(items), workers, func(w, lo, hi int) {
+		acc := identity
+		for _, item := range items[lo:hi] {
+			acc = accumulate(acc, item)
+		}
+		partial[w] = acc
+	})
+	result := identity
+	for _, p := range partial {
+		result = combine(result, p)
+	}
+	return result
+}
+
+// ParallelScan returns the inclusive prefix combination of items, result[i] = items[0] op ... op items[i].
+// op must be associative and identity neutral for it.
+func ParallelScan[T any](items []T, workers int, identity T, op func(T, T) T) []T {
+	result := make([]T, len(items))
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	workers = max(min(workers, len(items)), 1)
+
+	// pass 1: total of every chunk
+	totals := make([]T, workers)
+	forStatic(0, len(items), workers, func(w, lo, hi int) {
+		acc := identity
+		for _, item := range items[lo:hi] {
+			acc = op(acc, item)
+		}
+		totals[w] = acc
+	})
+
+	// exclusive scan of the chunk totals
+	offsets := make([]T, workers)
+	acc := identity
+	for i, t := range totals {
+		offsets[i] = acc
+		acc = op(acc, t)
+	}
+
+	// pass 2: scan every chunk from its offset
+	forStatic(0, len(items), workers, func(w, lo, hi int) {
+		acc := offsets[w]
+		for i := lo; i < hi; i++ {
+			acc = op(acc, items[i])
+			result[i] = acc
+		}
+	})
+	return result
+}
+
+// helpers
+
+// forStatic calls body once per worker w with its contiguous range.
+func forStatic(start, end, workers int, body func(w, lo, hi int)) {
+	n := end - start
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		lo, hi := start+chunkStart(w, n, workers), start+chunkStart(w+1, n, workers)
+		if lo == hi {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			body(w, lo, hi)
+		}()
+	}
+	wg.Wait()
+}
+
+func chunkStart(w, n, workers int) int {
+	return w * n / workers
+}
+
+func forDynamic(start, end, workers int, size func(remaining int) int, body func(lo, hi int)) {
+	next := start
+	var mu sync.Mutex
+	take := func() (int, int, bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		if next >= end {
+			return 0, 0, false
+		}
+		lo := next
+		next = min(lo+size(end-lo), end)
+		return lo, next, true
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for lo, hi, ok := take(); ok; lo, hi, ok = take() {
+				body(lo, hi)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// rangeDeque is a worker queue, the owner pops from the back and thieves steal from the front.
+type rangeDeque struct {
+	ranges [][2]int
+	mu     sync.Mutex
+}
+
+func (d *rangeDeque) pop() ([2]int, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.ranges) == 0 {
+		return [2]int{}, false
+	}
+	r := d.ranges[len(d.ranges)-1]
+	d.ranges = d.ranges[:len(d.ranges)-1]
+	return r, true
+}
+
+func (d *rangeDeque) steal() ([2]int, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.ranges) == 0 {
+		return [2]int{}, false
+	}
+	r := d.ranges[0]
+	d.ranges = d.ranges[1:]
+	return r, true
+}
+
+func forStealing(start, end, workers, grain int, body func(lo, hi int)) {
+	n := end - start
+	deques := make([]*rangeDeque, workers)
+	for w := range deques {
+		lo, hi := start+chunkStart(w, n, workers), start+chunkStart(w+1, n, workers)
+		d := &rangeDeque{}
+		// pushed in reverse so the owner works front to back
+		for s := hi; s > lo; s -= grain {
+			d.ranges = append(d.ranges, [2]int{max(s-grain, lo), s})
+		}
+		deques[w] = d
+	}
+
+	// ranges are never added once the workers start, so a worker that finds every deque empty is done
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				r, ok := deques[w].pop()
+				for i := 1; !ok && i < workers; i++ {
+					r, ok = deques[(w+i)%workers].steal()
+				}
+				if !ok {
+					return
+				}
+				body(r[0], r[1])
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,85 @@
+package parallell
+
+import (
+	"sync/atomic"
+	"testing"
+
+	"github.com/jnsoft/jngo/misc"
+	. "github.com/jnsoft/jngo/testhelper"
+)
+
+func TestParallelFor(t *testing.T) {
+	for _, chunking := range []Chunking{STATIC, DYNAMIC, GUIDED, WORK_STEALING} {
+		hits := make([]atomic.Int32, 10_007)
+		ParallelFor(0, len(hits), ForOptions{Workers: 4, Chunking: chunking, Grain: 100}, func(i int) {
+			hits[i].Add(1)
+		})
+		for i := range hits {
+			if hits[i].Load() != 1 {
+				t.Fatalf("chunking %d: index %d visited %d times", chunking, i, hits[i].Load())
+			}
+		}
+	}
+
+	var calls atomic.Int32
+	ParallelForRange(5, 5, ForOptions{}, func(lo, hi int) { calls.Add(1) })
+	AssertEqual(t, calls.Load(), 0)
+}
+
+func TestParallelReduceAndScan(t *testing.T) {
+	items := misc.Sequence(1, 1000, 1)
+	sum := func(a, b int) int { return a + b }
+
+	AssertEqual(t, ParallelReduce(items, 7, 0, sum, sum), 500500)
+	AssertEqual(t, ParallelReduce([]int{}, 4, 0, sum, sum), 0)
+	product := func(a, b int) int { return a * b }
+	AssertEqual(t, ParallelReduce([]int{}, 4, 1, product, product), 1)
+
+	// combine order matters for non-commutative operations
+	words := []string{"a", "b", "c", "d", "e"}
+	concat := func(a, b string) string { return a + b }
+	AssertEqual(t, ParallelReduce(words, 3, "", concat, concat), "abcde")
+
+	prefix := ParallelScan(items, 6, 0, sum)
+	AssertEqual(t, len(prefix), len(items))
+	for i, v := range prefix {
+		AssertEqual(t, v, (i+1)*(i+2)/2)
+	}
+	CollectionAssertEqual(t, ParallelScan(words, 2, "", concat), []string{"a", "ab", "abc", "abcd", "abcde"})
+}
+
+var benchItems = misc.Sequence(0, 1_000_000, 1)
+
+func BenchmarkReduceSequential(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		misc.Reduce(benchItems, func(acc, x int) int { return acc + x*x%7 }, 0)
+	}
+}
+
+func BenchmarkReduceParallel(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		ParallelReduce(benchItems, 0, 0, func(acc, x int) int { return acc + x*x%7 }, func(a, b int) int { return a + b })
+	}
+}
+
+func BenchmarkParallelFor(b *testing.B) {
+	out := make([]int, len(benchItems))
+	for _, bc := range []struct {
+		name     string
+		chunking Chunking
+	}{{"static", STATIC}, {"dynamic", DYNAMIC}, {"guided", GUIDED}, {"stealing", WORK_STEALING}} {
+		b.Run(bc.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				ParallelFor(0, len(benchItems), ForOptions{Chunking: bc.chunking}, func(j int) {
+					out[j] = benchItems[j] * benchItems[j] % 7
+				})
+			}
+		})
+	}
+}
+
+func BenchmarkScanParallel(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		ParallelScan(benchItems, 0, 0, func(a, b int) int { return a + b })
+	}
+}
@@ -0,0 +1,225 @@
+package pipeline
+
+import (
+	"container/list"
+	"hash/fnv"
+	"math"
+
+	"github.com/jnsoft/jngo/misc"
+	"github.com/jnsoft/jngo/pqueue"
+)
+
+// Keyed aggregation stages. Stream variants emit when the input is closed, window variants
+// take the []T produced by Batch or the window stages and emit once per window.
+
+type KeyValue[K comparable, V any] struct {
+	Key   K
+	Value V
+}
+
+// GroupBy folds the items of each key with accumulator, starting from initialValue, and emits
+// the groups in order of first appearance when the stream ends.
+func GroupBy[T any, K comparable, A any](key func(T) K, accumulator func(A, T) A, initialValue A) Transform[T, KeyValue[K, A]] {
+	return func(in <-chan T) <-chan KeyValue[K, A] {
+		out := make(chan KeyValue[K, A], BUFFER_SIZE)
+		go func() {
+			defer close(out)
+			groups := make(map[K]int)
+			var result []KeyValue[K, A]
+			for item := range in {
+				result = groupInto(result, groups, item, key, accumulator, initialValue)
+			}
+			for _, kv := range result {
+				out <- kv
+			}
+		}()
+		return out
+	}
+}
+
+// GroupByWindow is GroupBy applied to each window.
+func GroupByWindow[T any, K comparable, A any](key func(T) K, accumulator func(A, T) A, initialValue A) Transform[[]T, []KeyValue[K, A]] {
+	return perWindow(func(window []T) []KeyValue[K, A] {
+		groups := make(map[K]int)
+		var result []KeyValue[K, A]
+		for _, item := range window {
+			result = groupInto(result, groups, item, key, accumulator, initialValue)
+		}
+		return result
+	})
+}
+
+// RunningCount emits the updated count of the item's key for every item.
+func RunningCount[T any, K comparable](key func(T) K) Transform[T, KeyValue[K, int]] {
+	return RunningSum(key, func(T) int { return 1 })
+}
+
+// RunningSum emits the updated sum of the item's key for every item.
+func RunningSum[T any, K comparable, N misc.Number](key func(T) K, value func(T) N) Transform[T, KeyValue[K, N]] {
+	return func(in <-chan T) <-chan KeyValue[K, N] {
+		out := make(chan KeyValue[K, N], BUFFER_SIZE)
+		go func() {
+			defer close(out)
+			sums := make(map[K]N)
+			for item := range in {
+				k := key(item)
+				sums[k] += value(item)
+				out <- KeyValue[K, N]{k, sums[k]}
+			}
+		}()
+		return out
+	}
+}
+
+// Distinct passes the first item of each key. With maxKeys > 0 only the most recently seen
+// maxKeys keys are remembered, so a key that was evicted can pass again.
+func Distinct[T any, K comparable](key func(T) K, maxKeys int) Transform[T, T] {
+	return func(in <-chan T) <-chan T {
+		out := make(chan T, BUFFER_SIZE)
+		go func() {
+			defer close(out)
+			seen := make(map[K]*list.Element)
+			recent := list.New() // front = most recently seen
+			for item := range in {
+				k := key(item)
+				if e, ok := seen[k]; ok {
+					recent.MoveToFront(e)
+					continue
+				}
+				seen[k] = recent.PushFront(k)
+				if maxKeys > 0 && recent.Len() > maxKeys {
+					oldest := recent.Back()
+					delete(seen, recent.Remove(oldest).(K))
+				}
+				out <- item
+			}
+		}()
+		return out
+	}
+}
+
+// DistinctApprox passes the first item of each key using a Bloom filter sized for expectedKeys
+// at falsePositiveRate. Memory is fixed, but a new key is dropped with that probability.
+func DistinctApprox[T any](key func(T) string, expectedKeys int, falsePositiveRate float64) Transform[T, T] {
+	return func(in <-chan T) <-chan T {
+		out := make(chan T, BUFFER_SIZE)
+		go func() {
+			defer close(out)
+			filter := newBloomFilter(expectedKeys, falsePositiveRate)
+			for item := range in {
+				if filter.addIfAbsent(key(item)) {
+					out <- item
+				}
+			}
+		}()
+		return out
+	}
+}
+
+// TopK sums weight per key and emits the k heaviest keys, heaviest first, when the stream ends.
+func TopK[T any, K comparable, N misc.Number](key func(T) K, weight func(T) N, k int) Transform[T, []KeyValue[K, N]] {
+	return func(in <-chan T) <-chan []KeyValue[K, N] {
+		out := make(chan []KeyValue[K, N], 1)
+		go func() {
+			defer close(out)
+			sums := make(map[K]N)
+			for item := range in {
+				sums[key(item)] += weight(item)
+			}
+			out <- topK(sums, k)
+		}()
+		return out
+	}
+}
+
+// TopKWindow is TopK applied to each window.
+func TopKWindow[T any, K comparable, N misc.Number](key func(T) K, weight func(T) N, k int) Transform[[]T, []KeyValue[K, N]] {
+	return perWindow(func(window []T) []KeyValue[K, N] {
+		sums := make(map[K]N)
+		for _, item := range window {
+			sums[key(item)] += weight(item)
+		}
+		return topK(sums, k)
+	})
+}
+
+// helpers
+
+func perWindow[T, U any](fn func([]T) U) Transform[[]T, U] {
+	return func(in <-chan []T) <-chan U {
+		out := make(chan U, BUFFER_SIZE)
+		go func() {
+			defer close(out)
+			for window := range in {
+				out <- fn(window)
+			}
+		}()
+		return out
+	}
+}
+
+func groupInto[T any, K comparable, A any](result []KeyValue[K, A], groups map[K]int, item T, key func(T) K, accumulator func(A, T) A, initialValue A) []KeyValue[K, A] {
+	k := key(item)
+	i, ok := groups[k]
+	if !ok {
+		i = len(result)
+		groups[k] = i
+		result = append(result, KeyValue[K, A]{Key: k, Value: initialValue})
+	}
+	result[i].Value = accumulator(result[i].Value, item)
+	return result
+}
+
+// topK keeps the k largest sums in a min-heap of size k.
+func topK[K comparable, N misc.Number](sums map[K]N, k int) []KeyValue[K, N] {
+	if k <= 0 {
+		return nil
+	}
+	pq := pqueue.NewPriorityQueue(func(a, b KeyValue[K, N]) bool { return a.Value < b.Value })
+	for key, sum := range sums {
+		pq.Enqueue(KeyValue[K, N]{key, sum})
+		if pq.Size() > k {
+			_, _ = pq.Dequeue()
+		}
+	}
+	result := make([]KeyValue[K, N], pq.Size())
+	for i := len(result) - 1; i >= 0; i-- {
+		result[i], _ = pq.Dequeue()
+	}
+	return result
+}
+
+type bloomFilter struct {
+	bits   []uint64
+	m      uint64 // number of bits
+	hashes int
+}
+
+func newBloomFilter(n int, p float64) *bloomFilter {
+	n = max(n, 1)
+	if p <= 0 || p >= 1 {
+		p = 0.01
+	}
+	m := uint64(math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2)))
+	m = max(m, 64)
+	k := max(int(math.Round(float64(m)/float64(n)*math.Ln2)), 1)
+	return &bloomFilter{bits: make([]uint64, (m+63)/64), m: m, hashes: k}
+}
+
+// addIfAbsent adds key and reports whether it was (probably) not present.
+func (b *bloomFilter) addIfAbsent(key string) bool {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	h1 := h.Sum64()
+	h2 := h1>>33 | h1<<31 | 1 // double hashing, odd step
+	added := false
+	for i := 0; i < b.hashes; i++ {
+		bit := (h1 + uint64(i)*h2) % b.m
+		word, mask := bit/64, uint64(1)<<(bit%64)
+		if b.bits[word]&mask == 0 {
+			b.bits[word] |= mask
+			added = true
+		}
+	}
+	return added
+}
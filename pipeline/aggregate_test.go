@@ -0,0 +1,74 @@
+package pipeline
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+
+	. "github.com/jnsoft/jngo/testhelper"
+)
+
+func TestAggregate(t *testing.T) {
+	words := strings.Fields("b a c a b a d")
+	self := func(s string) string { return s }
+	count := func(n int, _ string) int { return n + 1 }
+
+	t.Run("GroupBy", func(t *testing.T) {
+		got := collect(FromSource(SliceSource(words)), GroupBy(self, count, 0))
+		CollectionAssertEqual(t, got, []KeyValue[string, int]{{"b", 2}, {"a", 3}, {"c", 1}, {"d", 1}})
+	})
+
+	t.Run("GroupBy per window", func(t *testing.T) {
+		pb := Then(FromSource(SliceSource(words)), TumblingCountWindow[string](4))
+		got := collect(pb, GroupByWindow(self, count, 0))
+		AssertEqual(t, len(got), 2)
+		CollectionAssertEqual(t, got[0], []KeyValue[string, int]{{"b", 1}, {"a", 2}, {"c", 1}})
+		CollectionAssertEqual(t, got[1], []KeyValue[string, int]{{"b", 1}, {"a", 1}, {"d", 1}})
+	})
+
+	t.Run("Running counts and sums", func(t *testing.T) {
+		got := collect(FromSource(SliceSource(words)), RunningCount(self))
+		AssertEqual(t, got[5], KeyValue[string, int]{"a", 3})
+
+		parity := func(n int) bool { return n%2 == 0 }
+		sums := collect(FromSource(SliceSource([]int{1, 2, 3, 4})), RunningSum(parity, func(n int) int { return n }))
+		CollectionAssertEqual(t, sums, []KeyValue[bool, int]{{false, 1}, {true, 2}, {false, 4}, {true, 6}})
+	})
+
+	t.Run("Distinct", func(t *testing.T) {
+		got := collect(FromSource(SliceSource(words)), Distinct(self, 0))
+		CollectionAssertEqual(t, got, []string{"b", "a", "c", "d"})
+
+		// only the last key is remembered, so b passes again after a
+		got = collect(FromSource(SliceSource(strings.Fields("a a b a b"))), Distinct(self, 1))
+		CollectionAssertEqual(t, got, []string{"a", "b", "a", "b"})
+	})
+
+	t.Run("Distinct approx", func(t *testing.T) {
+		var items []int
+		for i := 0; i < 2000; i++ {
+			items = append(items, i%1000)
+		}
+		got := collect(FromSource(SliceSource(items)), DistinctApprox(strconv.Itoa, 1000, 0.01))
+		AssertTrue(t, len(got) <= 1000 && len(got) > 950)
+		AssertEqual(t, got[0], 0)
+	})
+
+	t.Run("Top k", func(t *testing.T) {
+		got := collect(FromSource(SliceSource(words)), TopK(self, func(string) int { return 1 }, 2))
+		AssertEqual(t, len(got), 1)
+		CollectionAssertEqual(t, got[0], []KeyValue[string, int]{{"a", 3}, {"b", 2}})
+
+		pb := Then(FromSource(SliceSource(words)), TumblingCountWindow[string](4))
+		windows := collect(pb, TopKWindow(self, func(string) int { return 1 }, 1))
+		CollectionAssertEqual(t, windows[0], []KeyValue[string, int]{{"a", 2}})
+	})
+
+	t.Run("Lifted into a context pipeline", func(t *testing.T) {
+		out := NewSliceSink[KeyValue[string, int]]()
+		pb := ThenCtx(FromSourceCtx(SourceCtx(SliceSource(words))), Lift(GroupBy(self, count, 0)))
+		AssertNil(t, RunCtx(context.Background(), FinallySink(pb, Sink[KeyValue[string, int]](out))))
+		AssertEqual(t, len(out.Items()), 4)
+	})
+}
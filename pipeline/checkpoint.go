@@ -0,0 +1,349 @@
+package pipeline
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+/*
+Resumable pipelines with FromResumableSource(...)
+
+A resumable source tags every item with the position to resume from once the item is done.
+Stages acknowledge items with Checkpointer.Ack (or use MapCheckpointed / FinallyCheckpointed), and the
+checkpointer stores the highest position up to which every item has been acknowledged. After a crash
+the next run starts from that position, so items are processed at least once.
+
+Only MapCheckpointed and FinallyCheckpointed acknowledge the items that the error policy drops. An
+item dropped anywhere else, by a plain ThenDoCtx or MapStageCtx under SKIP_ERRORS or ROUTE_ERRORS, or
+by a stage that filters or aggregates items, is never acknowledged and holds Committed back from then
+on. Such stages must Ack the items they drop themselves.
+*/
+
+// Checkpointed is an item with the source position that follows it.
+type Checkpointed[T any] struct {
+	Offset int64
+	Value  T
+}
+
+// ResumableSource produces items starting at position from.
+type ResumableSource[T any] func(ctx context.Context, from int64, out chan<- Checkpointed[T]) error
+
+type CheckpointStore interface {
+	Load(name string) (offset int64, found bool, err error)
+	Save(name string, offset int64) error
+}
+
+// FileCheckpointStore keeps one small file per checkpoint name in a directory.
+type FileCheckpointStore struct {
+	dir string
+}
+
+type MemoryCheckpointStore struct {
+	offsets map[string]int64
+	mu      sync.Mutex
+}
+
+type Checkpointer struct {
+	name      string
+	store     CheckpointStore
+	every     int     // save after this many committed items, 0 only saves at the end of a run
+	pending   []int64 // tracked offsets not yet committed, in source order
+	acked     map[int64]bool
+	committed int64
+	saved     int64
+	sinceSave int
+	mu        sync.Mutex
+}
+
+// --- Checkpoint stores ---
+
+func NewFileCheckpointStore(dir string) (*FileCheckpointStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileCheckpointStore{dir: dir}, nil
+}
+
+func (s *FileCheckpointStore) Load(name string) (int64, bool, error) {
+	data, err := os.ReadFile(s.path(name))
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	offset, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("corrupt checkpoint %s: %v", name, err)
+	}
+	return offset, true, nil
+}
+
+// Save writes to a temporary file, syncs it and renames it, so a crash never leaves a partial checkpoint.
+func (s *FileCheckpointStore) Save(name string, offset int64) error {
+	// a unique temporary file, so concurrent saves of the same name never share it
+	tmp, err := os.CreateTemp(s.dir, ".checkpoint-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.WriteString(strconv.FormatInt(offset, 10)); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), s.path(name)); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+func (s *FileCheckpointStore) path(name string) string {
+	return filepath.Join(s.dir, name+".checkpoint")
+}
+
+func NewMemoryCheckpointStore() *MemoryCheckpointStore {
+	return &MemoryCheckpointStore{offsets: make(map[string]int64)}
+}
+
+func (s *MemoryCheckpointStore) Load(name string) (int64, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	offset, ok := s.offsets[name]
+	return offset, ok, nil
+}
+
+func (s *MemoryCheckpointStore) Save(name string, offset int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.offsets[name] = offset
+	return nil
+}
+
+// --- Checkpointer ---
+
+// NewCheckpointer stores the position of the named pipeline in store, saving after every committed items.
+func NewCheckpointer(name string, store CheckpointStore, every int) *Checkpointer {
+	return &Checkpointer{name: name, store: store, every: every, acked: make(map[int64]bool)}
+}
+
+// Position loads the position to resume from, 0 if there is no checkpoint.
+func (c *Checkpointer) Position() (int64, error) {
+	offset, _, err := c.store.Load(c.name)
+	if err != nil {
+		return 0, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.committed, c.saved = offset, offset
+	c.pending = c.pending[:0]
+	clear(c.acked)
+	return offset, nil
+}
+
+// Committed returns the position up to which all items are acknowledged.
+func (c *Checkpointer) Committed() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.committed
+}
+
+// Ack marks the item with offset as done.
+func (c *Checkpointer) Ack(offset int64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.acked[offset] = true
+	for len(c.pending) > 0 && c.acked[c.pending[0]] {
+		delete(c.acked, c.pending[0])
+		c.committed = c.pending[0]
+		c.pending = c.pending[1:]
+		c.sinceSave++
+	}
+	if c.every > 0 && c.sinceSave >= c.every {
+		return c.save()
+	}
+	return nil
+}
+
+// Flush saves the committed position if it changed.
+func (c *Checkpointer) Flush() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.save()
+}
+
+// Reset starts the next run from the beginning.
+func (c *Checkpointer) Reset() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.committed = 0
+	c.pending = c.pending[:0]
+	clear(c.acked)
+	c.saved = -1
+	return c.save()
+}
+
+func (c *Checkpointer) track(offset int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pending = append(c.pending, offset)
+}
+
+func (c *Checkpointer) save() error {
+	c.sinceSave = 0
+	if c.committed == c.saved {
+		return nil
+	}
+	if err := c.store.Save(c.name, c.committed); err != nil {
+		return err
+	}
+	c.saved = c.committed
+	return nil
+}
+
+// --- Builder integration ---
+
+// FromResumableSource starts a context-aware pipeline at the last checkpoint of cp.
+// The checkpoint is flushed when the run ends, a flush error fails the run.
+func FromResumableSource[T any](cp *Checkpointer, src ResumableSource[T]) CtxPipelineBuilder[Checkpointed[T]] {
+	return CtxPipelineBuilder[Checkpointed[T]]{
+		start: func(f *Flow) <-chan Checkpointed[T] {
+			out := make(chan Checkpointed[T], BUFFER_SIZE)
+			f.onDone = append(f.onDone, func() {
+				if err := cp.Flush(); err != nil {
+					f.Fail(err)
+				}
+			})
+			f.Go(func() {
+				defer close(out)
+				from, err := cp.Position()
+				if err != nil {
+					f.Fail(err)
+					return
+				}
+				items := make(chan Checkpointed[T])
+				errCh := make(chan error, 1)
+				go func() {
+					defer close(items)
+					errCh <- src(f.ctx, from, items)
+				}()
+				for item := range items {
+					if f.Stopped() {
+						continue
+					}
+					cp.track(item.Offset)
+					Send(f, out, item)
+				}
+				if err := <-errCh; err != nil {
+					f.Fail(err)
+				}
+			})
+			return out
+		},
+	}
+}
+
+// MapCheckpointed maps the values and keeps their offsets. Items dropped by the error policy are
+// acknowledged, since they will not be retried.
+func MapCheckpointed[A, B any](cp *Checkpointer, workers int, mapper func(context.Context, A) (B, error)) CtxTransform[Checkpointed[A], Checkpointed[B]] {
+	return ParallelMapStageCtx(workers, func(ctx context.Context, item Checkpointed[A]) (Checkpointed[B], error) {
+		v, err := mapper(ctx, item.Value)
+		if err != nil {
+			return Checkpointed[B]{}, &checkpointError{offset: item.Offset, cp: cp, err: err}
+		}
+		return Checkpointed[B]{Offset: item.Offset, Value: v}, nil
+	})
+}
+
+// FinallyCheckpointed consumes the values with final and acknowledges each processed item.
+func FinallyCheckpointed[T any](pb CtxPipelineBuilder[Checkpointed[T]], cp *Checkpointer, final func(context.Context, T) error) CtxPipelineBuilder[Checkpointed[T]] {
+	return FinallyCtx(pb, func(ctx context.Context, item Checkpointed[T]) error {
+		if err := final(ctx, item.Value); err != nil {
+			return &checkpointError{offset: item.Offset, cp: cp, err: err}
+		}
+		return cp.Ack(item.Offset)
+	})
+}
+
+// checkpointError lets Flow.Report acknowledge items that the error policy skips.
+type checkpointError struct {
+	offset int64
+	cp     *Checkpointer
+	err    error
+}
+
+func (e *checkpointError) Error() string { return e.err.Error() }
+func (e *checkpointError) Unwrap() error { return e.err }
+
+func (f *Flow) ackSkipped(err error) {
+	var ce *checkpointError
+	if errors.As(err, &ce) {
+		if aerr := ce.cp.Ack(ce.offset); aerr != nil {
+			f.Fail(aerr)
+		}
+	}
+}
+
+// --- Resumable sources ---
+
+// ResumableFileLineSource emits lines with the byte offset of the following line.
+func ResumableFileLineSource(path string) ResumableSource[string] {
+	return func(ctx context.Context, from int64, out chan<- Checkpointed[string]) error {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		if _, err := file.Seek(from, io.SeekStart); err != nil {
+			return err
+		}
+
+		r := bufio.NewReader(file)
+		offset := from
+		for {
+			line, err := r.ReadString('\n')
+			if len(line) > 0 {
+				offset += int64(len(line))
+				text := strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+				if !sendCtx(ctx, out, Checkpointed[string]{Offset: offset, Value: text}) {
+					return ctx.Err()
+				}
+			}
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// ResumableSliceSource emits items with their index + 1 as offset.
+func ResumableSliceSource[T any](items []T) ResumableSource[T] {
+	return func(ctx context.Context, from int64, out chan<- Checkpointed[T]) error {
+		for i := from; i < int64(len(items)); i++ {
+			if !sendCtx(ctx, out, Checkpointed[T]{Offset: i + 1, Value: items[i]}) {
+				return ctx.Err()
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,117 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	. "github.com/jnsoft/jngo/testhelper"
+)
+
+func TestCheckpoint(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Commits contiguous prefix", func(t *testing.T) {
+		store := NewMemoryCheckpointStore()
+		cp := NewCheckpointer("job", store, 1)
+		_, _ = cp.Position()
+		for _, o := range []int64{1, 2, 3, 4} {
+			cp.track(o)
+		}
+		AssertNil(t, cp.Ack(2))
+		AssertEqual(t, cp.Committed(), 0)
+		AssertNil(t, cp.Ack(1))
+		AssertEqual(t, cp.Committed(), 2)
+		AssertNil(t, cp.Ack(4))
+		AssertEqual(t, cp.Committed(), 2)
+		AssertNil(t, cp.Ack(3))
+		AssertEqual(t, cp.Committed(), 4)
+		offset, found, _ := store.Load("job")
+		AssertTrue(t, found)
+		AssertEqual(t, offset, 4)
+	})
+
+	t.Run("Resume file after crash", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "input.txt")
+		var sb strings.Builder
+		for i := 0; i < 100; i++ {
+			fmt.Fprintf(&sb, "%d\n", i)
+		}
+		_ = os.WriteFile(path, []byte(sb.String()), 0o644)
+
+		store, err := NewFileCheckpointStore(filepath.Join(dir, "checkpoints"))
+		AssertNil(t, err)
+
+		var mu sync.Mutex
+		seen := make(map[int]int)
+		run := func(crashAt int) error {
+			cp := NewCheckpointer("lines", store, 10)
+			pb := FromResumableSource(cp, ResumableFileLineSource(path))
+			pb2 := ThenCtx(pb, MapCheckpointed(cp, 4, func(_ context.Context, s string) (int, error) {
+				return strconv.Atoi(s)
+			}))
+			pb3 := FinallyCheckpointed(pb2, cp, func(_ context.Context, n int) error {
+				if n == crashAt {
+					return errBoom
+				}
+				mu.Lock()
+				seen[n]++
+				mu.Unlock()
+				return nil
+			})
+			return RunCtx(ctx, pb3)
+		}
+
+		err = run(60)
+		AssertTrue(t, errors.Is(err, errBoom))
+		offset, found, _ := store.Load("lines")
+		AssertTrue(t, found)
+		AssertTrue(t, offset > 0 && offset <= int64(len("0\n")*10+len("10\n")*50))
+
+		AssertNil(t, run(-1))
+		for i := 0; i < 100; i++ {
+			AssertTrue(t, seen[i] >= 1)
+		}
+		offset, _, _ = store.Load("lines")
+		AssertEqual(t, offset, int64(sb.Len()))
+
+		// nothing left to do
+		before := len(seen)
+		AssertNil(t, run(-1))
+		AssertEqual(t, len(seen), before)
+
+		// no temporary files are left behind
+		entries, _ := os.ReadDir(filepath.Join(dir, "checkpoints"))
+		AssertEqual(t, len(entries), 1)
+		AssertEqual(t, entries[0].Name(), "lines.checkpoint")
+	})
+
+	t.Run("Skipped items are acknowledged", func(t *testing.T) {
+		store := NewMemoryCheckpointStore()
+		cp := NewCheckpointer("skip", store, 0)
+		items := []string{"1", "x", "3", "y", "5"}
+		var sum int
+		pb := ThenCtx(FromResumableSource(cp, ResumableSliceSource(items)), MapCheckpointed(cp, 1, func(_ context.Context, s string) (int, error) {
+			return strconv.Atoi(s)
+		}))
+		pb2 := FinallyCheckpointed(pb, cp, func(_ context.Context, n int) error {
+			sum += n
+			return nil
+		})
+		AssertNil(t, RunCtx(ctx, pb2, WithErrorPolicy(SKIP_ERRORS)))
+		AssertEqual(t, sum, 9)
+		offset, _, _ := store.Load("skip")
+		AssertEqual(t, offset, 5)
+
+		AssertNil(t, cp.Reset())
+		offset, _, _ = store.Load("skip")
+		AssertEqual(t, offset, 0)
+	})
+}
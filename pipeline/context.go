@@ -0,0 +1,334 @@
+package pipeline
+
+import (
+	"context"
+	"sync"
+)
+
+/*
+Context-aware pipelines with FromSourceCtx(...) / RunCtx(...) and PipelineCtx(...)
+
+The first fatal error or a cancelled context stops every stage. Stages then discard their input
+until it is closed, so all goroutines exit, and the error is returned from RunCtx.
+*/
+
+type ErrorPolicy int
+
+const (
+	FAIL_FAST    ErrorPolicy = iota // first item error cancels the run and is returned
+	SKIP_ERRORS                     // failing items are dropped
+	ROUTE_ERRORS                    // failing items are dropped and their errors sent to an error channel
+)
+
+// Flow holds the state shared by all stages of one context-aware run.
+type Flow struct {
+	ctx     context.Context
+	cancel  context.CancelCauseFunc
+	policy  ErrorPolicy
+	errs    chan<- error
+	wg      sync.WaitGroup
+	metrics *Metrics
+	onDone  []func() // run after all stages have stopped
+}
+
+type RunOption func(*Flow)
+
+// CtxSource produces values into out until it is done or ctx is cancelled. The channel is closed by the pipeline.
+type CtxSource[T any] func(ctx context.Context, out chan<- T) error
+
+// CtxTransform is a context-aware pipeline step that maps from T → U
+type CtxTransform[T, U any] func(f *Flow, in <-chan T) <-chan U
+
+// CtxStage is a context-aware step that transforms T → T
+type CtxStage[T any] func(f *Flow, in <-chan T) <-chan T
+
+type CtxPipelineBuilder[T any] struct {
+	start func(f *Flow) <-chan T
+	final func(context.Context, T) error
+	close func() error // called after the last item, see FinallySink
+}
+
+// WithErrorPolicy selects how item errors are handled, default is FAIL_FAST.
+func WithErrorPolicy(policy ErrorPolicy) RunOption {
+	return func(f *Flow) {
+		f.policy = policy
+	}
+}
+
+// WithErrorChannel routes item errors to errs (ROUTE_ERRORS). The channel is not closed by the pipeline.
+func WithErrorChannel(errs chan<- error) RunOption {
+	return func(f *Flow) {
+		f.policy = ROUTE_ERRORS
+		f.errs = errs
+	}
+}
+
+func newFlow(ctx context.Context, opts []RunOption) *Flow {
+	f := &Flow{policy: FAIL_FAST}
+	f.ctx, f.cancel = context.WithCancelCause(ctx)
+	for _, opt := range opts {
+		opt(f)
+	}
+	return f
+}
+
+// Context is cancelled when the run fails or the parent context is cancelled.
+func (f *Flow) Context() context.Context {
+	return f.ctx
+}
+
+func (f *Flow) Done() <-chan struct{} {
+	return f.ctx.Done()
+}
+
+// Stopped reports whether stages should stop producing output.
+func (f *Flow) Stopped() bool {
+	return f.ctx.Err() != nil
+}
+
+// Err returns the error that stopped the run, or nil while it is running.
+func (f *Flow) Err() error {
+	return context.Cause(f.ctx)
+}
+
+// Fail stops the run with err. Only the first error is kept.
+func (f *Flow) Fail(err error) {
+	f.cancel(err)
+}
+
+// Report handles an item error according to the error policy and returns true if processing may continue.
+func (f *Flow) Report(err error) bool {
+	if f.metrics != nil {
+		f.metrics.errors.Add(1)
+	}
+	switch f.policy {
+	case SKIP_ERRORS:
+		f.ackSkipped(err)
+		return true
+	case ROUTE_ERRORS:
+		f.ackSkipped(err)
+		if f.errs == nil {
+			return true
+		}
+		select {
+		case f.errs <- err:
+			return true
+		case <-f.ctx.Done():
+			return false
+		}
+	default:
+		f.Fail(err)
+		return false
+	}
+}
+
+// Go starts fn in a goroutine that RunCtx waits for before returning.
+func (f *Flow) Go(fn func()) {
+	f.wg.Add(1)
+	go func() {
+		defer f.wg.Done()
+		fn()
+	}()
+}
+
+// Send forwards v to out unless the run is stopped first.
+func Send[T any](f *Flow, out chan<- T, v T) bool {
+	return sendCtx(f.ctx, out, v)
+}
+
+// sendCtx forwards v to out unless ctx is done first, for sources that only have the context.
+func sendCtx[T any](ctx context.Context, out chan<- T, v T) bool {
+	select {
+	case out <- v:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Drain discards the rest of in so upstream stages can finish.
+func Drain[T any](in <-chan T) {
+	for range in {
+	}
+}
+
+// --- Context-aware builder ---
+
+// FromSourceCtx starts a context-aware pipeline from a source.
+func FromSourceCtx[T any](src CtxSource[T]) CtxPipelineBuilder[T] {
+	return CtxPipelineBuilder[T]{
+		start: func(f *Flow) <-chan T {
+			out := make(chan T, BUFFER_SIZE)
+			f.Go(func() {
+				defer close(out)
+				if err := src(f.ctx, out); err != nil {
+					f.Fail(err)
+				}
+			})
+			return out
+		},
+	}
+}
+
+// SourceCtx adapts a Source, which closes its own channel, to a CtxSource. A Source cannot be
+// cancelled, so once ctx is done the rest of it is drained in the background, and the Source may
+// still be running, holding its files or connections, after RunCtx has returned. Prefer a
+// context-aware source such as ReaderLineSource when the run may be cancelled.
+func SourceCtx[T any](src Source[T]) CtxSource[T] {
+	return func(ctx context.Context, out chan<- T) error {
+		ch := make(chan T, BUFFER_SIZE)
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- src(ch)
+		}()
+		for v := range ch {
+			if ctx.Err() == nil {
+				select {
+				case out <- v:
+					continue
+				case <-ctx.Done():
+				}
+			}
+			go Drain(ch)
+			return ctx.Err()
+		}
+		if err := <-errCh; err != nil {
+			return err
+		}
+		return ctx.Err()
+	}
+}
+
+// ThenCtx adds a context-aware transformation to the pipeline.
+func ThenCtx[T, U any](prev CtxPipelineBuilder[T], transform CtxTransform[T, U]) CtxPipelineBuilder[U] {
+	return CtxPipelineBuilder[U]{
+		start: func(f *Flow) <-chan U {
+			return transform(f, prev.start(f))
+		},
+	}
+}
+
+// ThenDoCtx adds a side-effect stage, errors are handled by the error policy.
+func ThenDoCtx[T any](prev CtxPipelineBuilder[T], effect func(context.Context, T) error) CtxPipelineBuilder[T] {
+	return ThenCtx(prev, CtxTransform[T, T](DoStageCtx(effect)))
+}
+
+// FinallyCtx adds a final consumer, errors are handled by the error policy.
+func FinallyCtx[T any](pb CtxPipelineBuilder[T], final func(context.Context, T) error) CtxPipelineBuilder[T] {
+	pb.final = final
+	pb.close = nil
+	return pb
+}
+
+// FinallySink writes the items to sink and closes it when the run ends.
+// Write errors are handled by the error policy, a Close error fails the run.
+func FinallySink[T any](pb CtxPipelineBuilder[T], sink Sink[T]) CtxPipelineBuilder[T] {
+	pb.final = func(_ context.Context, v T) error {
+		return sink.Write(v)
+	}
+	pb.close = sink.Close
+	return pb
+}
+
+// RunCtx runs the pipeline until the source is exhausted, a fatal error occurs or ctx is cancelled.
+// It returns only after every stage has stopped. A Source adapted with SourceCtx is the exception,
+// it is drained in the background after a cancellation.
+func RunCtx[T any](ctx context.Context, pb CtxPipelineBuilder[T], opts ...RunOption) error {
+	f := newFlow(ctx, opts)
+	defer f.cancel(nil)
+
+	for val := range pb.start(f) {
+		if f.Stopped() || pb.final == nil {
+			continue
+		}
+		if err := pb.final(f.ctx, val); err != nil {
+			f.Report(err)
+		}
+	}
+	f.wg.Wait()
+	if pb.close != nil {
+		if err := pb.close(); err != nil {
+			f.Fail(err)
+		}
+	}
+	for _, fn := range f.onDone {
+		fn()
+	}
+	return f.Err()
+}
+
+// PipelineCtx is the context-aware variant of Pipeline.
+func PipelineCtx[A any](ctx context.Context, source CtxSource[A], stages []CtxStage[A], final func(), opts ...RunOption) error {
+	pb := FromSourceCtx(source)
+	for _, stage := range stages {
+		pb = ThenCtx(pb, CtxTransform[A, A](stage))
+	}
+	err := RunCtx(ctx, pb, opts...)
+	if final != nil {
+		final()
+	}
+	return err
+}
+
+// --- Context-aware functional helpers ---
+
+// Lift runs a plain Transform inside a context-aware pipeline. Its output is drained after cancellation.
+func Lift[T, U any](t Transform[T, U]) CtxTransform[T, U] {
+	return func(f *Flow, in <-chan T) <-chan U {
+		out := make(chan U, BUFFER_SIZE)
+		res := t(in)
+		f.Go(func() {
+			defer close(out)
+			for v := range res {
+				if !f.Stopped() {
+					Send(f, out, v)
+				}
+			}
+		})
+		return out
+	}
+}
+
+// MapStageCtx maps items from A to B, failing items are handled by the error policy.
+func MapStageCtx[A, B any](mapper func(context.Context, A) (B, error)) CtxTransform[A, B] {
+	return ParallelMapStageCtx(1, mapper)
+}
+
+// DoStageCtx applies a side-effect to each item and passes it through.
+func DoStageCtx[T any](fn func(context.Context, T) error) CtxStage[T] {
+	return CtxStage[T](MapStageCtx(func(ctx context.Context, v T) (T, error) {
+		return v, fn(ctx, v)
+	}))
+}
+
+// ParallelMapStageCtx processes items with a number of workers, output is in completion order.
+func ParallelMapStageCtx[A, B any](workers int, mapper func(context.Context, A) (B, error)) CtxTransform[A, B] {
+	return func(f *Flow, in <-chan A) <-chan B {
+		out := make(chan B, BUFFER_SIZE)
+		var wg sync.WaitGroup
+
+		for i := 0; i < max(workers, 1); i++ {
+			wg.Add(1)
+			f.Go(func() {
+				defer wg.Done()
+				for item := range in {
+					if f.Stopped() {
+						continue
+					}
+					if result, err := mapper(f.ctx, item); err == nil {
+						Send(f, out, result)
+					} else {
+						f.Report(err)
+					}
+				}
+			})
+		}
+
+		f.Go(func() {
+			wg.Wait()
+			close(out)
+		})
+
+		return out
+	}
+}
@@ -0,0 +1,168 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/jnsoft/jngo/testhelper"
+)
+
+var errBoom = errors.New("boom")
+
+func TestContextPipeline(t *testing.T) {
+
+	t.Run("Run to completion", func(t *testing.T) {
+		var sum int64
+		pb := FromSourceCtx(SourceCtx(SliceSource([]int{1, 2, 3, 4, 5})))
+		pb2 := ThenCtx(pb, ParallelMapStageCtx(3, func(_ context.Context, n int) (int, error) {
+			return n * 10, nil
+		}))
+		pb3 := FinallyCtx(pb2, func(_ context.Context, n int) error {
+			atomic.AddInt64(&sum, int64(n))
+			return nil
+		})
+		AssertNil(t, RunCtx(context.Background(), pb3))
+		AssertEqual(t, sum, 150)
+	})
+
+	t.Run("Fail fast", func(t *testing.T) {
+		before := runtime.NumGoroutine()
+		pb := FromSourceCtx(countingSource(-1))
+		pb2 := ThenCtx(pb, MapStageCtx(func(_ context.Context, n int) (int, error) {
+			if n == 500 {
+				return 0, errBoom
+			}
+			return n, nil
+		}))
+		err := RunCtx(context.Background(), pb2)
+		AssertTrue(t, errors.Is(err, errBoom))
+		assertNoLeak(t, before)
+	})
+
+	t.Run("Source error", func(t *testing.T) {
+		pb := FromSourceCtx(func(ctx context.Context, out chan<- string) error {
+			out <- "a"
+			return errBoom
+		})
+		err := RunCtx(context.Background(), pb)
+		AssertTrue(t, errors.Is(err, errBoom))
+
+		err = RunCtx(context.Background(), FromSourceCtx(SourceCtx(FileLineSource("does-not-exist.txt"))))
+		AssertTrue(t, err != nil)
+	})
+
+	t.Run("Skip errors", func(t *testing.T) {
+		var count int64
+		pb := FromSourceCtx(countingSource(100))
+		pb2 := ThenDoCtx(pb, func(_ context.Context, n int) error {
+			if n%10 == 0 {
+				return errBoom
+			}
+			return nil
+		})
+		pb3 := FinallyCtx(pb2, func(context.Context, int) error {
+			count++
+			return nil
+		})
+		AssertNil(t, RunCtx(context.Background(), pb3, WithErrorPolicy(SKIP_ERRORS)))
+		AssertEqual(t, count, 90)
+	})
+
+	t.Run("Route errors", func(t *testing.T) {
+		errs := make(chan error, 100)
+		pb := FromSourceCtx(countingSource(100))
+		pb2 := FinallyCtx(pb, func(_ context.Context, n int) error {
+			if n%25 == 0 {
+				return fmt.Errorf("item %d: %w", n, errBoom)
+			}
+			return nil
+		})
+		AssertNil(t, RunCtx(context.Background(), pb2, WithErrorChannel(errs)))
+		close(errs)
+		routed := 0
+		for err := range errs {
+			AssertTrue(t, errors.Is(err, errBoom))
+			routed++
+		}
+		AssertEqual(t, routed, 4)
+	})
+
+	t.Run("Cancellation", func(t *testing.T) {
+		before := runtime.NumGoroutine()
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		pb := FromSourceCtx(countingSource(-1))
+		pb2 := ThenCtx(pb, Lift(MapStage(func(n int) (int, error) { return n + 1, nil })))
+		pb3 := ThenCtx(pb2, ParallelMapStageCtx(4, func(_ context.Context, n int) (int, error) { return n, nil }))
+
+		err := RunCtx(ctx, pb3)
+		AssertTrue(t, errors.Is(err, context.DeadlineExceeded))
+		assertNoLeak(t, before)
+	})
+
+	t.Run("Cancellation stops reading a plain source", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		slow := func(out chan<- int) error {
+			defer close(out)
+			for i := 0; i < 100; i++ {
+				out <- i
+				time.Sleep(10 * time.Millisecond)
+			}
+			return nil
+		}
+		start := time.Now()
+		err := RunCtx(ctx, FromSourceCtx(SourceCtx(slow)))
+		AssertTrue(t, errors.Is(err, context.DeadlineExceeded))
+		AssertTrue(t, time.Since(start) < 500*time.Millisecond)
+	})
+
+	t.Run("Manual pipeline", func(t *testing.T) {
+		var out []string
+		err := PipelineCtx(context.Background(), SourceCtx(SliceSource([]string{"a", "b"})), []CtxStage[string]{
+			CtxStage[string](MapStageCtx(func(_ context.Context, s string) (string, error) {
+				return strings.ToUpper(s), nil
+			})),
+			DoStageCtx(func(_ context.Context, s string) error {
+				out = append(out, s)
+				return nil
+			}),
+		}, nil)
+		AssertNil(t, err)
+		CollectionAssertEqual(t, out, []string{"A", "B"})
+	})
+}
+
+// helpers
+
+// countingSource emits 0..n-1, or counts until cancelled if n < 0.
+func countingSource(n int) CtxSource[int] {
+	return func(ctx context.Context, out chan<- int) error {
+		for i := 0; n < 0 || i < n; i++ {
+			select {
+			case out <- i:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		return nil
+	}
+}
+
+func assertNoLeak(t *testing.T, before int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if n := runtime.NumGoroutine(); n > before {
+		t.Errorf("goroutine leak: %d before, %d after", before, n)
+	}
+}
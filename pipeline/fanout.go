@@ -0,0 +1,299 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// --- Fan-in and zip of sources ---
+
+// Merge runs all sources concurrently and interleaves their items. It returns the joined source errors.
+func Merge[T any](sources ...Source[T]) Source[T] {
+	return func(out chan<- T) error {
+		defer close(out)
+		errs := make([]error, len(sources))
+		var wg sync.WaitGroup
+		for i, src := range sources {
+			ch := make(chan T, BUFFER_SIZE)
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				errs[i] = src(ch)
+			}()
+			go func() {
+				defer wg.Done()
+				for v := range ch {
+					out <- v
+				}
+			}()
+		}
+		wg.Wait()
+		return errors.Join(errs...)
+	}
+}
+
+// MergeCtx is the context-aware variant of Merge.
+func MergeCtx[T any](sources ...CtxSource[T]) CtxSource[T] {
+	return func(ctx context.Context, out chan<- T) error {
+		errs := make([]error, len(sources))
+		var wg sync.WaitGroup
+		for i, src := range sources {
+			ch := make(chan T, BUFFER_SIZE)
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				defer close(ch)
+				errs[i] = src(ctx, ch)
+			}()
+			go func() {
+				defer wg.Done()
+				for v := range ch {
+					if ctx.Err() == nil {
+						select {
+						case out <- v:
+						case <-ctx.Done():
+						}
+					}
+				}
+			}()
+		}
+		wg.Wait()
+		if err := errors.Join(errs...); err != nil {
+			return err
+		}
+		return ctx.Err()
+	}
+}
+
+// Zip pairs the items of two sources and stops when the shorter one ends.
+// The longer source is drained so it can finish.
+func Zip[A, B, R any](a Source[A], b Source[B], fn func(A, B) R) Source[R] {
+	return func(out chan<- R) error {
+		defer close(out)
+		ca, cb := make(chan A, BUFFER_SIZE), make(chan B, BUFFER_SIZE)
+		errA, errB := make(chan error, 1), make(chan error, 1)
+		go func() { errA <- a(ca) }()
+		go func() { errB <- b(cb) }()
+
+		for {
+			va, ok := <-ca
+			if !ok {
+				break
+			}
+			vb, ok := <-cb
+			if !ok {
+				break
+			}
+			out <- fn(va, vb)
+		}
+		Drain(ca)
+		Drain(cb)
+		return errors.Join(<-errA, <-errB)
+	}
+}
+
+// ZipCtx is the context-aware variant of Zip. The longer source is cancelled when the shorter one ends.
+func ZipCtx[A, B, R any](a CtxSource[A], b CtxSource[B], fn func(A, B) R) CtxSource[R] {
+	return func(ctx context.Context, out chan<- R) error {
+		zctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		ca, cb := make(chan A, BUFFER_SIZE), make(chan B, BUFFER_SIZE)
+		errA, errB := make(chan error, 1), make(chan error, 1)
+		go func() {
+			defer close(ca)
+			errA <- a(zctx, ca)
+		}()
+		go func() {
+			defer close(cb)
+			errB <- b(zctx, cb)
+		}()
+
+	loop:
+		for {
+			va, ok := <-ca
+			if !ok {
+				break
+			}
+			vb, ok := <-cb
+			if !ok {
+				break
+			}
+			select {
+			case out <- fn(va, vb):
+			case <-ctx.Done():
+				break loop
+			}
+		}
+		cancel()
+		Drain(ca)
+		Drain(cb)
+
+		errs := []error{<-errA, <-errB}
+		for i, err := range errs {
+			if errors.Is(err, context.Canceled) && ctx.Err() == nil {
+				errs[i] = nil // cancelled by us
+			}
+		}
+		if err := errors.Join(errs...); err != nil {
+			return err
+		}
+		return ctx.Err()
+	}
+}
+
+// --- Fan-out to sub-pipelines ---
+
+// FanIn merges several channels into one that is closed when all of them are closed.
+func FanIn[T any](f *Flow, ins ...<-chan T) <-chan T {
+	out := make(chan T, BUFFER_SIZE)
+	var wg sync.WaitGroup
+	for _, in := range ins {
+		wg.Add(1)
+		f.Go(func() {
+			defer wg.Done()
+			for v := range in {
+				if !f.Stopped() {
+					Send(f, out, v)
+				}
+			}
+		})
+	}
+	f.Go(func() {
+		wg.Wait()
+		close(out)
+	})
+	return out
+}
+
+// FanOut sends each item to the branch with the index returned by route, items with an
+// out of range index are dropped. The branch outputs are merged.
+func FanOut[T, U any](route func(T) int, branches ...CtxTransform[T, U]) CtxTransform[T, U] {
+	return func(f *Flow, in <-chan T) <-chan U {
+		return fanOut(f, in, branches, func(v T, buf []int) []int {
+			return append(buf, route(v))
+		})
+	}
+}
+
+// SplitStage sends items matching pred to matched and all others to rest.
+func SplitStage[T, U any](pred func(T) bool, matched, rest CtxTransform[T, U]) CtxTransform[T, U] {
+	return FanOut(func(v T) int {
+		if pred(v) {
+			return 0
+		}
+		return 1
+	}, matched, rest)
+}
+
+// RouteByKey sends each item to the branch registered for its key, or to fallback if there is none.
+// A nil fallback drops unrouted items.
+func RouteByKey[T any, K comparable, U any](key func(T) K, routes map[K]CtxTransform[T, U], fallback CtxTransform[T, U]) CtxTransform[T, U] {
+	index := make(map[K]int, len(routes))
+	branches := make([]CtxTransform[T, U], 0, len(routes)+1)
+	for k, b := range routes {
+		index[k] = len(branches)
+		branches = append(branches, b)
+	}
+	if fallback != nil {
+		branches = append(branches, fallback)
+	}
+	return FanOut(func(v T) int {
+		if i, ok := index[key(v)]; ok {
+			return i
+		}
+		if fallback != nil {
+			return len(branches) - 1
+		}
+		return -1
+	}, branches...)
+}
+
+// BroadcastStage sends every item to all branches and merges their outputs.
+// Branches receive the same value, so reference types must not be modified.
+func BroadcastStage[T, U any](branches ...CtxTransform[T, U]) CtxTransform[T, U] {
+	return func(f *Flow, in <-chan T) <-chan U {
+		return fanOut(f, in, branches, func(_ T, buf []int) []int {
+			for i := range branches {
+				buf = append(buf, i)
+			}
+			return buf
+		})
+	}
+}
+
+// TerminalStageCtx consumes items with fn and emits nothing, for branches that end in a side-effect.
+func TerminalStageCtx[T any](fn func(context.Context, T) error) CtxStage[T] {
+	return func(f *Flow, in <-chan T) <-chan T {
+		done := make(chan T)
+		f.Go(func() {
+			defer close(done)
+			for item := range in {
+				if f.Stopped() {
+					continue
+				}
+				if err := fn(f.ctx, item); err != nil {
+					f.Report(err)
+				}
+			}
+		})
+		return done
+	}
+}
+
+// fanOut wires branches to in. Once a branch has closed its output it no longer receives items,
+// so a branch that finishes early does not block the others.
+func fanOut[T, U any](f *Flow, in <-chan T, branches []CtxTransform[T, U], targets func(T, []int) []int) <-chan U {
+	n := len(branches)
+	ins := make([]chan T, n)
+	finished := make([]chan struct{}, n)
+	outs := make([]<-chan U, n)
+	for i, branch := range branches {
+		ins[i] = make(chan T, BUFFER_SIZE)
+		finished[i] = make(chan struct{})
+		outs[i] = branch(f, ins[i])
+	}
+
+	for i, o := range outs {
+		outs[i] = watchClose(f, o, finished[i])
+	}
+	merged := FanIn(f, outs...)
+
+	f.Go(func() {
+		defer func() {
+			for _, ch := range ins {
+				close(ch)
+			}
+		}()
+		buf := make([]int, 0, n)
+		for item := range in {
+			if f.Stopped() {
+				continue
+			}
+			for _, i := range targets(item, buf[:0]) {
+				if i < 0 || i >= n {
+					continue
+				}
+				select {
+				case ins[i] <- item:
+				case <-finished[i]:
+				case <-f.Done():
+				}
+			}
+		}
+	})
+	return merged
+}
+
+// watchClose forwards in and closes finished once in is closed.
+func watchClose[T any](f *Flow, in <-chan T, finished chan struct{}) <-chan T {
+	out := make(chan T)
+	f.Go(func() {
+		defer close(out)
+		defer close(finished)
+		for v := range in {
+			out <- v
+		}
+	})
+	return out
+}
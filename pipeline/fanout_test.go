@@ -0,0 +1,137 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sort"
+	"sync/atomic"
+	"testing"
+
+	. "github.com/jnsoft/jngo/testhelper"
+)
+
+func TestFanOutFanIn(t *testing.T) {
+
+	t.Run("Merge sources", func(t *testing.T) {
+		got := collect(FromSource(Merge(SliceSource([]int{1, 2, 3}), SliceSource([]int{10, 20}))), MapStage(func(n int) (int, error) { return n, nil }))
+		sort.Ints(got)
+		CollectionAssertEqual(t, got, []int{1, 2, 3, 10, 20})
+
+		failing := func(out chan<- int) error {
+			close(out)
+			return errBoom
+		}
+		err := Merge(SliceSource([]int{1}), failing)(make(chan int, 10))
+		AssertTrue(t, errors.Is(err, errBoom))
+	})
+
+	t.Run("Merge context sources", func(t *testing.T) {
+		var sum int64
+		pb := FinallyCtx(FromSourceCtx(MergeCtx(countingSource(10), countingSource(5))), func(_ context.Context, n int) error {
+			sum += int64(n)
+			return nil
+		})
+		AssertNil(t, RunCtx(context.Background(), pb))
+		AssertEqual(t, sum, 45+10)
+	})
+
+	t.Run("Zip", func(t *testing.T) {
+		src := Zip(SliceSource([]string{"a", "b", "c"}), SliceSource([]int{1, 2}), func(s string, n int) string {
+			return fmt.Sprintf("%s%d", s, n)
+		})
+		got := collect(FromSource(src), MapStage(func(s string) (string, error) { return s, nil }))
+		CollectionAssertEqual(t, got, []string{"a1", "b2"})
+	})
+
+	t.Run("Zip context stops longer source", func(t *testing.T) {
+		before := runtime.NumGoroutine()
+		var got []int
+		src := ZipCtx(countingSource(-1), countingSource(3), func(a, b int) int { return a + b })
+		pb := FinallyCtx(FromSourceCtx(src), func(_ context.Context, n int) error {
+			got = append(got, n)
+			return nil
+		})
+		AssertNil(t, RunCtx(context.Background(), pb))
+		CollectionAssertEqual(t, got, []int{0, 2, 4})
+		assertNoLeak(t, before)
+	})
+
+	t.Run("Split by predicate", func(t *testing.T) {
+		var evens, odds int64
+		stage := SplitStage(func(n int) bool { return n%2 == 0 },
+			CtxTransform[int, int](DoStageCtx(func(context.Context, int) error { atomic.AddInt64(&evens, 1); return nil })),
+			CtxTransform[int, int](DoStageCtx(func(context.Context, int) error { atomic.AddInt64(&odds, 1); return nil })))
+		var total int
+		pb := FinallyCtx(ThenCtx(FromSourceCtx(countingSource(11)), stage), func(context.Context, int) error {
+			total++
+			return nil
+		})
+		AssertNil(t, RunCtx(context.Background(), pb))
+		AssertEqual(t, evens, 6)
+		AssertEqual(t, odds, 5)
+		AssertEqual(t, total, 11)
+	})
+
+	t.Run("Route by key", func(t *testing.T) {
+		label := func(name string) CtxTransform[int, string] {
+			return MapStageCtx(func(_ context.Context, n int) (string, error) { return fmt.Sprintf("%s:%d", name, n), nil })
+		}
+		stage := RouteByKey(func(n int) int { return n % 3 }, map[int]CtxTransform[int, string]{
+			0: label("zero"),
+			1: label("one"),
+		}, nil)
+		var got []string
+		pb := FinallyCtx(ThenCtx(FromSourceCtx(countingSource(6)), stage), func(_ context.Context, s string) error {
+			got = append(got, s)
+			return nil
+		})
+		AssertNil(t, RunCtx(context.Background(), pb))
+		sort.Strings(got)
+		CollectionAssertEqual(t, got, []string{"one:1", "one:4", "zero:0", "zero:3"})
+	})
+
+	t.Run("Broadcast with early finishing branch", func(t *testing.T) {
+		before := runtime.NumGoroutine()
+		takeTwo := func(f *Flow, in <-chan int) <-chan int {
+			out := make(chan int)
+			f.Go(func() {
+				defer close(out)
+				for i := 0; i < 2; i++ {
+					out <- <-in
+				}
+			})
+			return out
+		}
+		var sinkCount int64
+		sink := TerminalStageCtx(func(context.Context, int) error {
+			atomic.AddInt64(&sinkCount, 1)
+			return nil
+		})
+		var got int
+		pb := FinallyCtx(ThenCtx(FromSourceCtx(countingSource(1000)), BroadcastStage(takeTwo, CtxTransform[int, int](sink))), func(context.Context, int) error {
+			got++
+			return nil
+		})
+		AssertNil(t, RunCtx(context.Background(), pb))
+		AssertEqual(t, got, 2)
+		AssertEqual(t, sinkCount, 1000)
+		assertNoLeak(t, before)
+	})
+
+	t.Run("Branch error stops run", func(t *testing.T) {
+		before := runtime.NumGoroutine()
+		failing := MapStageCtx(func(_ context.Context, n int) (int, error) {
+			if n == 50 {
+				return 0, errBoom
+			}
+			return n, nil
+		})
+		ok := MapStageCtx(func(_ context.Context, n int) (int, error) { return n, nil })
+		pb := ThenCtx(FromSourceCtx(countingSource(-1)), BroadcastStage(failing, ok))
+		err := RunCtx(context.Background(), pb)
+		AssertTrue(t, errors.Is(err, errBoom))
+		assertNoLeak(t, before)
+	})
+}
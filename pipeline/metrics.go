@@ -0,0 +1,356 @@
+package pipeline
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"math"
+	"math/bits"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Per-stage instrumentation. Stages are wrapped with Instrument/InstrumentCtx, which count items
+// in and out and expose the occupancy of the stage input channel. Mappers wrapped with
+// InstrumentMapper/InstrumentCtxMapper record processing latency and errors for the same stage name.
+
+const (
+	HISTOGRAM_BUCKETS = 32 // bucket i counts latencies below 2^i microseconds, the last bucket is unbounded
+)
+
+var expvarMu sync.Mutex // makes the check and publish of PublishExpvar atomic
+
+type TraceKind int
+
+const (
+	TRACE_IN TraceKind = iota
+	TRACE_OUT
+	TRACE_ERROR
+	TRACE_DONE // an instrumented mapper returned, Latency is set
+)
+
+// TraceEvent is passed to the tracer set with Metrics.SetTracer.
+type TraceEvent struct {
+	Stage   string
+	Kind    TraceKind
+	Err     error
+	Latency time.Duration
+}
+
+type Metrics struct {
+	start  time.Time
+	stages []*StageMetrics
+	byName map[string]*StageMetrics
+	errors atomic.Int64 // item errors reported to a run with WithMetrics
+	tracer atomic.Pointer[func(TraceEvent)]
+	mu     sync.Mutex
+}
+
+type StageMetrics struct {
+	name    string
+	m       *Metrics
+	in      atomic.Int64
+	out     atomic.Int64
+	errs    atomic.Int64
+	latency Histogram
+	queue   atomic.Pointer[func() (int, int)]
+}
+
+// Histogram counts durations in exponential buckets, safe for concurrent use.
+type Histogram struct {
+	buckets [HISTOGRAM_BUCKETS]atomic.Int64
+	count   atomic.Int64
+	sum     atomic.Int64
+	max     atomic.Int64
+}
+
+type HistogramSnapshot struct {
+	Count   int64
+	Mean    time.Duration
+	Max     time.Duration
+	P50     time.Duration // percentiles are bucket upper bounds
+	P90     time.Duration
+	P99     time.Duration
+	Buckets []int64
+}
+
+type StageSnapshot struct {
+	Name     string
+	In       int64
+	Out      int64
+	Errors   int64
+	Latency  HistogramSnapshot
+	QueueLen int
+	QueueCap int
+}
+
+type Snapshot struct {
+	Elapsed time.Duration
+	Errors  int64 // item errors reported to the run
+	Stages  []StageSnapshot
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{start: time.Now(), byName: make(map[string]*StageMetrics)}
+}
+
+// Stage returns the metrics for name, creating them on first use.
+func (m *Metrics) Stage(name string) *StageMetrics {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if s, ok := m.byName[name]; ok {
+		return s
+	}
+	s := &StageMetrics{name: name, m: m}
+	m.byName[name] = s
+	m.stages = append(m.stages, s)
+	return s
+}
+
+// SetTracer installs a callback that receives every instrumentation event. It must be fast.
+func (m *Metrics) SetTracer(fn func(TraceEvent)) {
+	m.tracer.Store(&fn)
+}
+
+// Snapshot returns a consistent-enough copy of all counters, stages in creation order.
+func (m *Metrics) Snapshot() Snapshot {
+	m.mu.Lock()
+	stages := append([]*StageMetrics(nil), m.stages...)
+	m.mu.Unlock()
+
+	snap := Snapshot{Elapsed: time.Since(m.start), Errors: m.errors.Load(), Stages: make([]StageSnapshot, len(stages))}
+	for i, s := range stages {
+		snap.Stages[i] = s.Snapshot()
+	}
+	return snap
+}
+
+// PublishExpvar exposes the snapshot under name in expvar. It returns an error if the name is
+// already published, expvar names are unique per process.
+func (m *Metrics) PublishExpvar(name string) error {
+	expvarMu.Lock()
+	defer expvarMu.Unlock()
+	if expvar.Get(name) != nil {
+		return fmt.Errorf("expvar %q is already published", name)
+	}
+	expvar.Publish(name, expvar.Func(func() any { return m.Snapshot() }))
+	return nil
+}
+
+func (m *Metrics) trace(e TraceEvent) {
+	if fn := m.tracer.Load(); fn != nil {
+		(*fn)(e)
+	}
+}
+
+func (s *StageMetrics) Snapshot() StageSnapshot {
+	snap := StageSnapshot{
+		Name:    s.name,
+		In:      s.in.Load(),
+		Out:     s.out.Load(),
+		Errors:  s.errs.Load(),
+		Latency: s.latency.Snapshot(),
+	}
+	if q := s.queue.Load(); q != nil {
+		snap.QueueLen, snap.QueueCap = (*q)()
+	}
+	return snap
+}
+
+func (s *StageMetrics) recordIn() {
+	s.in.Add(1)
+	s.m.trace(TraceEvent{Stage: s.name, Kind: TRACE_IN})
+}
+
+func (s *StageMetrics) recordOut() {
+	s.out.Add(1)
+	s.m.trace(TraceEvent{Stage: s.name, Kind: TRACE_OUT})
+}
+
+func (s *StageMetrics) recordCall(d time.Duration, err error) {
+	s.latency.Observe(d)
+	s.m.trace(TraceEvent{Stage: s.name, Kind: TRACE_DONE, Latency: d, Err: err})
+	if err != nil {
+		s.errs.Add(1)
+		s.m.trace(TraceEvent{Stage: s.name, Kind: TRACE_ERROR, Err: err})
+	}
+}
+
+// --- Histogram ---
+
+func (h *Histogram) Observe(d time.Duration) {
+	us := max(d.Microseconds(), 0)
+	i := min(bits.Len64(uint64(us)), HISTOGRAM_BUCKETS-1)
+	h.buckets[i].Add(1)
+	h.count.Add(1)
+	h.sum.Add(int64(d))
+	for {
+		cur := h.max.Load()
+		if int64(d) <= cur || h.max.CompareAndSwap(cur, int64(d)) {
+			break
+		}
+	}
+}
+
+func (h *Histogram) Snapshot() HistogramSnapshot {
+	snap := HistogramSnapshot{Count: h.count.Load(), Max: time.Duration(h.max.Load()), Buckets: make([]int64, HISTOGRAM_BUCKETS)}
+	for i := range h.buckets {
+		snap.Buckets[i] = h.buckets[i].Load()
+	}
+	if snap.Count == 0 {
+		return snap
+	}
+	snap.Mean = time.Duration(h.sum.Load() / snap.Count)
+	snap.P50 = snap.percentile(0.50)
+	snap.P90 = snap.percentile(0.90)
+	snap.P99 = snap.percentile(0.99)
+	return snap
+}
+
+func (hs HistogramSnapshot) percentile(p float64) time.Duration {
+	var total int64
+	for _, c := range hs.Buckets {
+		total += c
+	}
+	target := int64(math.Ceil(p * float64(total)))
+	var seen int64
+	for i, c := range hs.Buckets {
+		seen += c
+		if seen >= target {
+			if i == HISTOGRAM_BUCKETS-1 {
+				return hs.Max
+			}
+			return min(time.Duration(1<<i)*time.Microsecond, hs.Max)
+		}
+	}
+	return hs.Max
+}
+
+// --- Stage wrappers ---
+
+// Instrument wraps a Transform and counts the items going in and out of it.
+func Instrument[T, U any](m *Metrics, name string, t Transform[T, U]) Transform[T, U] {
+	s := m.Stage(name)
+	return func(in <-chan T) <-chan U {
+		stageIn := make(chan T, BUFFER_SIZE)
+		watchOccupancy(s, stageIn)
+		go func() {
+			defer close(stageIn)
+			for v := range in {
+				s.recordIn()
+				stageIn <- v
+			}
+		}()
+		out := make(chan U, BUFFER_SIZE)
+		res := t(stageIn)
+		go func() {
+			defer close(out)
+			for v := range res {
+				s.recordOut()
+				out <- v
+			}
+		}()
+		return out
+	}
+}
+
+// InstrumentCtx is the context-aware variant of Instrument.
+func InstrumentCtx[T, U any](m *Metrics, name string, t CtxTransform[T, U]) CtxTransform[T, U] {
+	s := m.Stage(name)
+	return func(f *Flow, in <-chan T) <-chan U {
+		stageIn := make(chan T, BUFFER_SIZE)
+		watchOccupancy(s, stageIn)
+		f.Go(func() {
+			defer close(stageIn)
+			for v := range in {
+				if !f.Stopped() {
+					s.recordIn()
+					Send(f, stageIn, v)
+				}
+			}
+		})
+		out := make(chan U, BUFFER_SIZE)
+		res := t(f, stageIn)
+		f.Go(func() {
+			defer close(out)
+			for v := range res {
+				if !f.Stopped() {
+					s.recordOut()
+					Send(f, out, v)
+				}
+			}
+		})
+		return out
+	}
+}
+
+// InstrumentMapper records the latency and errors of mapper under the stage name.
+func InstrumentMapper[A, B any](m *Metrics, name string, mapper func(A) (B, error)) func(A) (B, error) {
+	s := m.Stage(name)
+	return func(a A) (B, error) {
+		start := time.Now()
+		b, err := mapper(a)
+		s.recordCall(time.Since(start), err)
+		return b, err
+	}
+}
+
+// InstrumentCtxMapper is the context-aware variant of InstrumentMapper.
+func InstrumentCtxMapper[A, B any](m *Metrics, name string, mapper CtxMapper[A, B]) CtxMapper[A, B] {
+	s := m.Stage(name)
+	return func(ctx context.Context, a A) (B, error) {
+		start := time.Now()
+		b, err := mapper(ctx, a)
+		s.recordCall(time.Since(start), err)
+		return b, err
+	}
+}
+
+// --- Run options ---
+
+// WithMetrics counts the item errors reported to the run in m.
+func WithMetrics(m *Metrics) RunOption {
+	return func(f *Flow) {
+		f.metrics = m
+	}
+}
+
+// WithProgress calls fn with a snapshot of m every interval while the run is active, and once at the end.
+// With an interval <= 0 fn is only called at the end.
+func WithProgress(m *Metrics, interval time.Duration, fn func(Snapshot)) RunOption {
+	return func(f *Flow) {
+		f.metrics = m
+		if interval <= 0 {
+			f.onDone = append(f.onDone, func() { fn(m.Snapshot()) })
+			return
+		}
+		stop := make(chan struct{})
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					fn(m.Snapshot())
+				case <-stop:
+					return
+				}
+			}
+		}()
+		f.onDone = append(f.onDone, func() {
+			close(stop)
+			<-done
+			fn(m.Snapshot())
+		})
+	}
+}
+
+// helpers
+
+func watchOccupancy[T any](s *StageMetrics, ch chan T) {
+	q := func() (int, int) { return len(ch), cap(ch) }
+	s.queue.Store(&q)
+}
@@ -0,0 +1,121 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/jnsoft/jngo/testhelper"
+)
+
+var expvarRuns atomic.Int64 // expvar names cannot be reused, so every run of the test gets its own
+
+func TestMetrics(t *testing.T) {
+
+	t.Run("Stage counters and latency", func(t *testing.T) {
+		m := NewMetrics()
+		mapper := InstrumentMapper(m, "parse", func(n int) (int, error) {
+			if n%10 == 0 {
+				return 0, errBoom
+			}
+			time.Sleep(100 * time.Microsecond)
+			return n, nil
+		})
+		stage := Instrument(m, "parse", ParallelMapStage(4, mapper))
+		got := collect(FromSource(SliceSource(make([]int, 0))), stage)
+		AssertEqual(t, len(got), 0)
+
+		input := make([]int, 100)
+		for i := range input {
+			input[i] = i
+		}
+		got = collect(FromSource(SliceSource(input)), stage)
+		AssertEqual(t, len(got), 90)
+
+		snap := m.Snapshot()
+		AssertEqual(t, len(snap.Stages), 1)
+		s := snap.Stages[0]
+		AssertEqual(t, s.Name, "parse")
+		AssertEqual(t, s.In, 100)
+		AssertEqual(t, s.Out, 90)
+		AssertEqual(t, s.Errors, 10)
+		AssertEqual(t, s.Latency.Count, 100)
+		AssertEqual(t, s.QueueCap, BUFFER_SIZE)
+		AssertTrue(t, s.Latency.P99 >= s.Latency.P50)
+		AssertTrue(t, s.Latency.Max >= 100*time.Microsecond)
+	})
+
+	t.Run("Context run with tracer and progress", func(t *testing.T) {
+		m := NewMetrics()
+		var traced atomic.Int64
+		m.SetTracer(func(e TraceEvent) {
+			if e.Kind == TRACE_OUT && e.Stage == "double" {
+				traced.Add(1)
+			}
+		})
+
+		var mu sync.Mutex
+		var progress []Snapshot
+		pb := ThenCtx(FromSourceCtx(countingSource(50)), InstrumentCtx(m, "double", MapStageCtx(InstrumentCtxMapper(m, "double",
+			func(_ context.Context, n int) (int, error) {
+				if n == 7 {
+					return 0, errors.New("seven")
+				}
+				return n * 2, nil
+			}))))
+		pb2 := ThenCtx(pb, InstrumentCtx(m, "identity", CtxTransform[int, int](DoStageCtx(func(context.Context, int) error { return nil }))))
+
+		err := RunCtx(context.Background(), pb2, WithErrorPolicy(SKIP_ERRORS), WithProgress(m, time.Millisecond, func(s Snapshot) {
+			mu.Lock()
+			progress = append(progress, s)
+			mu.Unlock()
+		}))
+		AssertNil(t, err)
+		AssertEqual(t, traced.Load(), 49)
+
+		mu.Lock()
+		defer mu.Unlock()
+		AssertTrue(t, len(progress) >= 1)
+		last := progress[len(progress)-1]
+		AssertEqual(t, last.Errors, 1)
+		AssertEqual(t, len(last.Stages), 2)
+		AssertEqual(t, last.Stages[1].Name, "identity")
+		AssertEqual(t, last.Stages[1].Out, 49)
+
+		// without an interval the snapshot is only reported at the end
+		calls := 0
+		err = RunCtx(context.Background(), FromSourceCtx(countingSource(5)), WithProgress(NewMetrics(), 0, func(Snapshot) { calls++ }))
+		AssertNil(t, err)
+		AssertEqual(t, calls, 1)
+	})
+
+	t.Run("Histogram percentiles", func(t *testing.T) {
+		var h Histogram
+		for i := 0; i < 99; i++ {
+			h.Observe(10 * time.Microsecond)
+		}
+		h.Observe(time.Second)
+		snap := h.Snapshot()
+		AssertEqual(t, snap.Count, 100)
+		AssertEqual(t, snap.P50, 16*time.Microsecond)
+		AssertEqual(t, snap.P99, 16*time.Microsecond)
+		AssertEqual(t, snap.Max, time.Second)
+	})
+
+	t.Run("Expvar", func(t *testing.T) {
+		name := fmt.Sprintf("pipeline_metrics_test_%d", expvarRuns.Add(1))
+		m := NewMetrics()
+		m.Stage("load").in.Add(3)
+		AssertNil(t, m.PublishExpvar(name))
+		var snap Snapshot
+		AssertNil(t, json.Unmarshal([]byte(expvar.Get(name).String()), &snap))
+		AssertEqual(t, snap.Stages[0].In, 3)
+		AssertNotEqual(t, NewMetrics().PublishExpvar(name), nil)
+	})
+}
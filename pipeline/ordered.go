@@ -0,0 +1,117 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+)
+
+type sequenced[T any] struct {
+	seq int
+	val T
+	err error
+}
+
+// OrderedParallelMapStage maps items with a number of workers and emits results in input order.
+// At most window items are in flight or waiting to be reordered, failing items are dropped.
+func OrderedParallelMapStage[A, B any](workers, window int, mapper func(A) (B, error)) Transform[A, B] {
+	return func(in <-chan A) <-chan B {
+		out := make(chan B, BUFFER_SIZE)
+		orderedMap(in, workers, window,
+			func(run func()) { go run() },
+			func() bool { return false },
+			mapper,
+			func(b B) { out <- b },
+			func(err error) { fmt.Fprintf(os.Stderr, "Ordered map error: %v\n", err) },
+			func() { close(out) })
+		return out
+	}
+}
+
+// OrderedParallelMapStageCtx is the context-aware variant of OrderedParallelMapStage.
+func OrderedParallelMapStageCtx[A, B any](workers, window int, mapper func(context.Context, A) (B, error)) CtxTransform[A, B] {
+	return func(f *Flow, in <-chan A) <-chan B {
+		out := make(chan B, BUFFER_SIZE)
+		orderedMap(in, workers, window,
+			f.Go,
+			f.Stopped,
+			func(a A) (B, error) { return mapper(f.ctx, a) },
+			func(b B) { Send(f, out, b) },
+			func(err error) { f.Report(err) },
+			func() { close(out) })
+		return out
+	}
+}
+
+// orderedMap tags items with a sequence number, maps them on workers and reorders the results.
+// A semaphore of window slots bounds the reorder buffer.
+func orderedMap[A, B any](in <-chan A, workers, window int,
+	spawn func(func()), stopped func() bool,
+	mapper func(A) (B, error), emit func(B), onErr func(error), done func()) {
+
+	workers = max(workers, 1)
+	window = max(window, workers)
+
+	jobs := make(chan sequenced[A])
+	results := make(chan sequenced[B])
+	sem := make(chan struct{}, window)
+
+	spawn(func() {
+		defer close(jobs)
+		seq := 0
+		for item := range in {
+			if stopped() {
+				continue
+			}
+			sem <- struct{}{}
+			jobs <- sequenced[A]{seq: seq, val: item}
+			seq++
+		}
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		spawn(func() {
+			defer wg.Done()
+			for job := range jobs {
+				var r sequenced[B]
+				r.seq = job.seq
+				if !stopped() {
+					r.val, r.err = mapper(job.val)
+				}
+				results <- r
+			}
+		})
+	}
+	spawn(func() {
+		wg.Wait()
+		close(results)
+	})
+
+	spawn(func() {
+		defer done()
+		pending := make(map[int]sequenced[B], window)
+		next := 0
+		for r := range results {
+			pending[r.seq] = r
+			for {
+				p, ok := pending[next]
+				if !ok {
+					break
+				}
+				delete(pending, next)
+				next++
+				<-sem
+				switch {
+				case stopped():
+				case p.err != nil:
+					onErr(p.err)
+				default:
+					emit(p.val)
+				}
+			}
+		}
+	})
+}
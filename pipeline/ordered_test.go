@@ -0,0 +1,90 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/jnsoft/jngo/testhelper"
+)
+
+func TestOrderedParallelMap(t *testing.T) {
+
+	t.Run("Preserves order", func(t *testing.T) {
+		input := make([]int, 1000)
+		for i := range input {
+			input[i] = i
+		}
+		var got []int
+		pb := FromSource(SliceSource(input))
+		pb2 := Then(pb, OrderedParallelMapStage(8, 32, func(n int) (int, error) {
+			time.Sleep(time.Duration(rand.Intn(50)) * time.Microsecond)
+			return n * 2, nil
+		}))
+		Run(Finally(pb2, func(n int) { got = append(got, n) }), nil)
+
+		AssertEqual(t, len(got), len(input))
+		for i, v := range got {
+			if v != i*2 {
+				t.Fatalf("item %d out of order: %d", i, v)
+			}
+		}
+	})
+
+	t.Run("File lines keep order", func(t *testing.T) {
+		if err := generateTestFile(FNAME, 500); err != nil {
+			t.Fatalf("failed to generate test file: %v", err)
+		}
+		defer os.Remove(FNAME)
+		raw, _ := os.ReadFile(FNAME)
+		expected := strings.Split(strings.TrimSpace(string(raw)), "\n")
+
+		var got []string
+		pb := FromSource(FileLineSource(FNAME))
+		pb2 := Then(pb, OrderedParallelMapStage(4, 16, func(s string) (string, error) {
+			return strings.ToUpper(s), nil
+		}))
+		Run(Finally(pb2, func(s string) { got = append(got, s) }), nil)
+
+		AssertEqual(t, len(got), len(expected))
+		for i := range expected {
+			AssertEqual(t, got[i], strings.ToUpper(expected[i]))
+		}
+	})
+
+	t.Run("Context variant skips errors in order", func(t *testing.T) {
+		var got []int
+		pb := FromSourceCtx(countingSource(200))
+		pb2 := ThenCtx(pb, OrderedParallelMapStageCtx(6, 12, func(_ context.Context, n int) (int, error) {
+			if n%3 == 0 {
+				return 0, errors.New("divisible by 3")
+			}
+			return n, nil
+		}))
+		pb3 := FinallyCtx(pb2, func(_ context.Context, n int) error {
+			got = append(got, n)
+			return nil
+		})
+		AssertNil(t, RunCtx(context.Background(), pb3, WithErrorPolicy(SKIP_ERRORS)))
+		AssertEqual(t, len(got), 133)
+		for i := 1; i < len(got); i++ {
+			AssertTrue(t, got[i-1] < got[i])
+		}
+	})
+
+	t.Run("Context variant fails fast", func(t *testing.T) {
+		pb := FromSourceCtx(countingSource(-1))
+		pb2 := ThenCtx(pb, OrderedParallelMapStageCtx(4, 8, func(_ context.Context, n int) (int, error) {
+			if n == 100 {
+				return 0, errBoom
+			}
+			return n, nil
+		}))
+		err := RunCtx(context.Background(), pb2)
+		AssertTrue(t, errors.Is(err, errBoom))
+	})
+}
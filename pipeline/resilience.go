@@ -0,0 +1,324 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/jnsoft/jngo/randx"
+)
+
+// Decorators for stage mappers: retry with backoff, per-item timeout, rate limiting and circuit breaking.
+// They wrap a CtxMapper, use FromMapper/ToMapper to combine them with MapStage and ParallelMapStage.
+
+var (
+	ErrItemTimeout = errors.New("item timed out")
+	ErrCircuitOpen = errors.New("circuit breaker is open")
+)
+
+type CircuitState int
+
+const (
+	CIRCUIT_CLOSED CircuitState = iota
+	CIRCUIT_OPEN
+	CIRCUIT_HALF_OPEN
+)
+
+// CtxMapper is a mapper that can be cancelled.
+type CtxMapper[A, B any] func(context.Context, A) (B, error)
+
+// Clock abstracts time so the decorators can be tested without sleeping.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type systemClock struct{}
+
+var SystemClock Clock = systemClock{}
+
+func (systemClock) Now() time.Time                         { return time.Now() }
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+type RetryPolicy struct {
+	MaxAttempts int              // total attempts including the first, at least 1
+	BaseDelay   time.Duration    // delay before the first retry
+	MaxDelay    time.Duration    // upper bound for the delay, 0 for none
+	Multiplier  float64          // delay growth per attempt, defaults to 2
+	Jitter      float64          // fraction of the delay that is randomized, 0..1
+	Retryable   func(error) bool // nil retries every error
+	Clock       Clock            // nil uses SystemClock
+}
+
+// TokenBucket allows rate operations per second with bursts of up to burst operations.
+type TokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	clock  Clock
+	mu     sync.Mutex
+}
+
+// CircuitBreaker opens after threshold consecutive failures and lets one trial call through
+// once openTimeout has passed. A successful trial closes it again.
+type CircuitBreaker struct {
+	threshold   int
+	openTimeout time.Duration
+	clock       Clock
+	state       CircuitState
+	failures    int
+	openedAt    time.Time
+	trial       bool
+	mu          sync.Mutex
+}
+
+// FromMapper adapts a plain mapper, it ignores the context.
+func FromMapper[A, B any](mapper func(A) (B, error)) CtxMapper[A, B] {
+	return func(_ context.Context, a A) (B, error) {
+		return mapper(a)
+	}
+}
+
+// ToMapper adapts a CtxMapper for MapStage and ParallelMapStage, using a background context.
+func ToMapper[A, B any](mapper CtxMapper[A, B]) func(A) (B, error) {
+	return func(a A) (B, error) {
+		return mapper(context.Background(), a)
+	}
+}
+
+// RetryMapper retries failing items with exponential backoff and jitter.
+func RetryMapper[A, B any](mapper CtxMapper[A, B], policy RetryPolicy) CtxMapper[A, B] {
+	clock := clockOrDefault(policy.Clock)
+	return func(ctx context.Context, a A) (B, error) {
+		var (
+			res B
+			err error
+		)
+		for attempt := 1; ; attempt++ {
+			res, err = mapper(ctx, a)
+			if err == nil || attempt >= policy.MaxAttempts || ctx.Err() != nil {
+				return res, err
+			}
+			if policy.Retryable != nil && !policy.Retryable(err) {
+				return res, err
+			}
+			if serr := sleep(ctx, clock, policy.Backoff(attempt)); serr != nil {
+				return res, err
+			}
+		}
+	}
+}
+
+// Backoff returns the delay after the given failed attempt (1 based).
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	mult := p.Multiplier
+	if mult <= 0 {
+		mult = 2
+	}
+	if p.BaseDelay <= 0 {
+		return 0
+	}
+	d := float64(p.BaseDelay) * math.Pow(mult, float64(max(attempt-1, 0)))
+	if p.MaxDelay > 0 && d > float64(p.MaxDelay) {
+		d = float64(p.MaxDelay)
+	}
+	// large attempts overflow the float, clamp before the jitter so that Inf*0 never gives NaN
+	if math.IsNaN(d) || d >= float64(math.MaxInt64) {
+		d = float64(math.MaxInt64)
+	}
+	if p.Jitter > 0 {
+		j := math.Min(p.Jitter, 1)
+		d = d*(1-j) + d*j*randx.Float64()
+	}
+	// float64(MaxInt64) rounds up to 2^63, which does not convert to a positive duration
+	if d >= float64(math.MaxInt64) {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(d)
+}
+
+// TimeoutMapper fails an item with ErrItemTimeout if mapper does not return within d.
+// The context passed to mapper is cancelled on timeout.
+func TimeoutMapper[A, B any](mapper CtxMapper[A, B], d time.Duration, clock Clock) CtxMapper[A, B] {
+	clock = clockOrDefault(clock)
+	type result struct {
+		val B
+		err error
+	}
+	return func(ctx context.Context, a A) (B, error) {
+		cctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		ch := make(chan result, 1)
+		go func() {
+			v, err := mapper(cctx, a)
+			ch <- result{v, err}
+		}()
+		select {
+		case r := <-ch:
+			return r.val, r.err
+		case <-clock.After(d):
+			return *new(B), ErrItemTimeout
+		case <-ctx.Done():
+			return *new(B), ctx.Err()
+		}
+	}
+}
+
+// RateLimitMapper waits for a token from limiter before each call.
+func RateLimitMapper[A, B any](mapper CtxMapper[A, B], limiter *TokenBucket) CtxMapper[A, B] {
+	return func(ctx context.Context, a A) (B, error) {
+		if err := limiter.Wait(ctx); err != nil {
+			return *new(B), err
+		}
+		return mapper(ctx, a)
+	}
+}
+
+// CircuitBreakerMapper fails fast with ErrCircuitOpen while breaker is open.
+func CircuitBreakerMapper[A, B any](mapper CtxMapper[A, B], breaker *CircuitBreaker) CtxMapper[A, B] {
+	return func(ctx context.Context, a A) (B, error) {
+		if err := breaker.Allow(); err != nil {
+			return *new(B), err
+		}
+		v, err := mapper(ctx, a)
+		breaker.Record(err)
+		return v, err
+	}
+}
+
+// --- Token bucket ---
+
+func NewTokenBucket(rate float64, burst int, clock Clock) (*TokenBucket, error) {
+	if !(rate > 0) {
+		return nil, errors.New("rate must be positive")
+	}
+	clock = clockOrDefault(clock)
+	b := math.Max(float64(burst), 1)
+	return &TokenBucket{rate: rate, burst: b, tokens: b, last: clock.Now(), clock: clock}, nil
+}
+
+// Allow takes a token if one is available without waiting.
+func (tb *TokenBucket) Allow() bool {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	tb.refill()
+	if tb.tokens >= 1 {
+		tb.tokens--
+		return true
+	}
+	return false
+}
+
+// Wait reserves a token and blocks until it is available or ctx is done.
+func (tb *TokenBucket) Wait(ctx context.Context) error {
+	tb.mu.Lock()
+	tb.refill()
+	tb.tokens--
+	var wait time.Duration
+	if tb.tokens < 0 {
+		wait = time.Duration(-tb.tokens / tb.rate * float64(time.Second))
+	}
+	tb.mu.Unlock()
+
+	if wait == 0 {
+		return nil
+	}
+	if err := sleep(ctx, tb.clock, wait); err != nil {
+		tb.mu.Lock()
+		tb.tokens = math.Min(tb.burst, tb.tokens+1) // give the reservation back
+		tb.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+func (tb *TokenBucket) refill() {
+	now := tb.clock.Now()
+	if elapsed := now.Sub(tb.last); elapsed > 0 {
+		tb.tokens = math.Min(tb.burst, tb.tokens+elapsed.Seconds()*tb.rate)
+		tb.last = now
+	}
+}
+
+// --- Circuit breaker ---
+
+func NewCircuitBreaker(threshold int, openTimeout time.Duration, clock Clock) *CircuitBreaker {
+	return &CircuitBreaker{threshold: max(threshold, 1), openTimeout: openTimeout, clock: clockOrDefault(clock)}
+}
+
+func (cb *CircuitBreaker) State() CircuitState {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.update()
+	return cb.state
+}
+
+// Allow returns ErrCircuitOpen if a call may not be made now.
+func (cb *CircuitBreaker) Allow() error {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.update()
+	switch cb.state {
+	case CIRCUIT_OPEN:
+		return ErrCircuitOpen
+	case CIRCUIT_HALF_OPEN:
+		if cb.trial {
+			return ErrCircuitOpen
+		}
+		cb.trial = true
+	}
+	return nil
+}
+
+// Record reports the outcome of an allowed call. Cancellation says nothing about the health of
+// the callee, so context errors are not counted, a canceled trial lets the next call try again.
+func (cb *CircuitBreaker) Record(err error) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		cb.trial = false
+		return
+	}
+	if err == nil {
+		cb.state = CIRCUIT_CLOSED
+		cb.failures = 0
+		cb.trial = false
+		return
+	}
+	cb.failures++
+	if cb.state == CIRCUIT_HALF_OPEN || cb.failures >= cb.threshold {
+		cb.state = CIRCUIT_OPEN
+		cb.openedAt = cb.clock.Now()
+		cb.trial = false
+	}
+}
+
+func (cb *CircuitBreaker) update() {
+	if cb.state == CIRCUIT_OPEN && cb.clock.Now().Sub(cb.openedAt) >= cb.openTimeout {
+		cb.state = CIRCUIT_HALF_OPEN
+	}
+}
+
+// helpers
+
+func sleep(ctx context.Context, clock Clock, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	select {
+	case <-clock.After(d):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func clockOrDefault(clock Clock) Clock {
+	if clock == nil {
+		return SystemClock
+	}
+	return clock
+}
@@ -0,0 +1,242 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/jnsoft/jngo/testhelper"
+)
+
+// fakeClock advances its own time instead of sleeping
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	sleeps []time.Duration
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	c.sleeps = append(c.sleeps, d)
+	ch := make(chan time.Time, 1)
+	ch <- c.now
+	return ch
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// neverClock never fires
+type neverClock struct{}
+
+func (neverClock) Now() time.Time                       { return time.Time{} }
+func (neverClock) After(time.Duration) <-chan time.Time { return nil }
+
+// parkedClock never fires, and reports each sleep on waiting
+type parkedClock struct {
+	fakeClock
+	waiting chan struct{}
+}
+
+func (c *parkedClock) After(time.Duration) <-chan time.Time {
+	c.waiting <- struct{}{}
+	return nil
+}
+
+func TestResilience(t *testing.T) {
+
+	t.Run("Retry with backoff", func(t *testing.T) {
+		clock := &fakeClock{}
+		calls := 0
+		mapper := RetryMapper(FromMapper(func(n int) (int, error) {
+			calls++
+			if calls < 4 {
+				return 0, errBoom
+			}
+			return n * 2, nil
+		}), RetryPolicy{MaxAttempts: 5, BaseDelay: 10 * time.Millisecond, MaxDelay: 30 * time.Millisecond, Clock: clock})
+
+		v, err := mapper(context.Background(), 21)
+		AssertNil(t, err)
+		AssertEqual(t, v, 42)
+		AssertEqual(t, calls, 4)
+		CollectionAssertEqual(t, clock.sleeps, []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 30 * time.Millisecond})
+	})
+
+	t.Run("Retry gives up", func(t *testing.T) {
+		calls := 0
+		permanent := errors.New("permanent")
+		mapper := RetryMapper(FromMapper(func(n int) (int, error) {
+			calls++
+			return 0, permanent
+		}), RetryPolicy{MaxAttempts: 5, Clock: &fakeClock{}, Retryable: func(err error) bool { return err != permanent }})
+		_, err := mapper(context.Background(), 1)
+		AssertEqual(t, err, permanent)
+		AssertEqual(t, calls, 1)
+
+		calls = 0
+		stage := MapStage(ToMapper(RetryMapper(FromMapper(func(n int) (int, error) {
+			calls++
+			return 0, errBoom
+		}), RetryPolicy{MaxAttempts: 3, Clock: &fakeClock{}})))
+		got := collect(FromSource(SliceSource([]int{1})), stage)
+		AssertEqual(t, len(got), 0)
+		AssertEqual(t, calls, 3)
+	})
+
+	t.Run("Jitter stays in range", func(t *testing.T) {
+		p := RetryPolicy{BaseDelay: 100 * time.Millisecond, Jitter: 0.5}
+		for i := 0; i < 100; i++ {
+			d := p.Backoff(1)
+			AssertTrue(t, d >= 50*time.Millisecond && d <= 100*time.Millisecond)
+		}
+	})
+
+	t.Run("Backoff does not overflow", func(t *testing.T) {
+		p := RetryPolicy{BaseDelay: time.Second}
+		for _, attempt := range []int{40, 64, 100, 2000} {
+			AssertEqual(t, p.Backoff(attempt), time.Duration(math.MaxInt64))
+		}
+		p.MaxDelay = time.Minute
+		AssertEqual(t, p.Backoff(2000), time.Minute)
+		AssertEqual(t, RetryPolicy{}.Backoff(2000), 0)
+
+		// full jitter on an infinite delay
+		p = RetryPolicy{BaseDelay: time.Second, Jitter: 1}
+		for i := 0; i < 100; i++ {
+			AssertTrue(t, p.Backoff(2000) >= 0)
+		}
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		cancelled := make(chan struct{})
+		slow := TimeoutMapper(func(ctx context.Context, n int) (int, error) {
+			<-ctx.Done()
+			close(cancelled)
+			return 0, ctx.Err()
+		}, time.Second, &fakeClock{})
+		_, err := slow(context.Background(), 1)
+		AssertEqual(t, err, ErrItemTimeout)
+		<-cancelled
+
+		fast := TimeoutMapper(FromMapper(func(n int) (int, error) { return n, nil }), time.Second, neverClock{})
+		v, err := fast(context.Background(), 7)
+		AssertNil(t, err)
+		AssertEqual(t, v, 7)
+	})
+
+	t.Run("Token bucket", func(t *testing.T) {
+		clock := &fakeClock{}
+		tb, err := NewTokenBucket(10, 2, clock)
+		AssertNil(t, err)
+		AssertTrue(t, tb.Allow())
+		AssertTrue(t, tb.Allow())
+		AssertFalse(t, tb.Allow())
+
+		clock.Advance(100 * time.Millisecond)
+		AssertTrue(t, tb.Allow())
+
+		mapper := RateLimitMapper(FromMapper(func(n int) (int, error) { return n, nil }), tb)
+		for i := 0; i < 3; i++ {
+			_, err := mapper(context.Background(), i)
+			AssertNil(t, err)
+		}
+		CollectionAssertEqual(t, clock.sleeps, []time.Duration{100 * time.Millisecond, 100 * time.Millisecond, 100 * time.Millisecond})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		blocked, _ := NewTokenBucket(1, 1, neverClock{})
+		AssertTrue(t, blocked.Allow())
+		AssertTrue(t, errors.Is(blocked.Wait(ctx), context.Canceled))
+
+		// cancelled reservations do not fill the bucket past burst
+		parked := &parkedClock{waiting: make(chan struct{})}
+		full, _ := NewTokenBucket(1, 1, parked)
+		AssertTrue(t, full.Allow())
+		ctx, cancel = context.WithCancel(context.Background())
+		errs := make(chan error, 2)
+		for i := 0; i < 2; i++ {
+			go func() { errs <- full.Wait(ctx) }()
+			<-parked.waiting
+		}
+		parked.Advance(time.Minute)
+		AssertTrue(t, full.Allow())
+		cancel()
+		for i := 0; i < 2; i++ {
+			AssertTrue(t, errors.Is(<-errs, context.Canceled))
+		}
+		AssertTrue(t, full.Allow())
+		AssertFalse(t, full.Allow())
+
+		for _, rate := range []float64{0, -1, math.NaN()} {
+			_, err := NewTokenBucket(rate, 1, clock)
+			AssertNotEqual(t, err, nil)
+		}
+	})
+
+	t.Run("Circuit breaker", func(t *testing.T) {
+		clock := &fakeClock{}
+		cb := NewCircuitBreaker(3, time.Minute, clock)
+		fail := true
+		calls := 0
+		mapper := CircuitBreakerMapper(FromMapper(func(n int) (int, error) {
+			calls++
+			if fail {
+				return 0, errBoom
+			}
+			return n, nil
+		}), cb)
+
+		for i := 0; i < 3; i++ {
+			_, err := mapper(context.Background(), i)
+			AssertEqual(t, err, errBoom)
+		}
+		AssertEqual(t, cb.State(), CIRCUIT_OPEN)
+		_, err := mapper(context.Background(), 0)
+		AssertEqual(t, err, ErrCircuitOpen)
+		AssertEqual(t, calls, 3)
+
+		clock.Advance(time.Minute)
+		AssertEqual(t, cb.State(), CIRCUIT_HALF_OPEN)
+		_, err = mapper(context.Background(), 0)
+		AssertEqual(t, err, errBoom)
+		AssertEqual(t, cb.State(), CIRCUIT_OPEN)
+
+		clock.Advance(time.Minute)
+		fail = false
+		v, err := mapper(context.Background(), 5)
+		AssertNil(t, err)
+		AssertEqual(t, v, 5)
+		AssertEqual(t, cb.State(), CIRCUIT_CLOSED)
+
+		// canceled calls are not failures, and do not use up the trial
+		for i := 0; i < 5; i++ {
+			cb.Record(context.Canceled)
+			cb.Record(fmt.Errorf("call: %w", context.DeadlineExceeded))
+		}
+		AssertEqual(t, cb.State(), CIRCUIT_CLOSED)
+		for i := 0; i < 3; i++ {
+			cb.Record(errBoom)
+		}
+		clock.Advance(time.Minute)
+		AssertNil(t, cb.Allow())
+		cb.Record(context.Canceled)
+		AssertEqual(t, cb.State(), CIRCUIT_HALF_OPEN)
+		AssertNil(t, cb.Allow())
+	})
+}
@@ -0,0 +1,170 @@
+package pipeline
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+)
+
+// Sink consumes the items at the end of a pipeline, see FinallySink.
+// Close is called once after the last item and flushes buffered output.
+type Sink[T any] interface {
+	Write(item T) error
+	Close() error
+}
+
+// LineSink writes each string as a line.
+type LineSink struct {
+	w *bufferedWriter
+}
+
+// JSONLinesSink writes each item as one line of JSON.
+type JSONLinesSink[T any] struct {
+	w   *bufferedWriter
+	enc *json.Encoder
+}
+
+// CSVSink writes each item as a CSV record.
+type CSVSink struct {
+	w   *bufferedWriter
+	csv *csv.Writer
+}
+
+// SliceSink collects the items in memory.
+type SliceSink[T any] struct {
+	items []T
+	mu    sync.Mutex
+}
+
+type bufferedWriter struct {
+	*bufio.Writer
+	closer io.Closer // set if the sink owns the underlying writer
+}
+
+func NewLineSink(w io.Writer) *LineSink {
+	return &LineSink{w: newBufferedWriter(w, nil)}
+}
+
+// NewFileLineSink creates (or truncates) the file at path.
+func NewFileLineSink(path string) (*LineSink, error) {
+	w, err := createBufferedWriter(path)
+	if err != nil {
+		return nil, err
+	}
+	return &LineSink{w: w}, nil
+}
+
+func (s *LineSink) Write(line string) error {
+	if _, err := s.w.WriteString(line); err != nil {
+		return err
+	}
+	return s.w.WriteByte('\n')
+}
+
+func (s *LineSink) Close() error {
+	return s.w.close()
+}
+
+func NewJSONLinesSink[T any](w io.Writer) *JSONLinesSink[T] {
+	bw := newBufferedWriter(w, nil)
+	return &JSONLinesSink[T]{w: bw, enc: json.NewEncoder(bw)}
+}
+
+func NewFileJSONLinesSink[T any](path string) (*JSONLinesSink[T], error) {
+	bw, err := createBufferedWriter(path)
+	if err != nil {
+		return nil, err
+	}
+	return &JSONLinesSink[T]{w: bw, enc: json.NewEncoder(bw)}, nil
+}
+
+func (s *JSONLinesSink[T]) Write(item T) error {
+	return s.enc.Encode(item) // Encode terminates each value with a newline
+}
+
+func (s *JSONLinesSink[T]) Close() error {
+	return s.w.close()
+}
+
+// NewCSVSink writes records separated by comma, a zero comma means ','.
+func NewCSVSink(w io.Writer, comma rune) *CSVSink {
+	return newCSVSink(newBufferedWriter(w, nil), comma)
+}
+
+func NewFileCSVSink(path string, comma rune) (*CSVSink, error) {
+	bw, err := createBufferedWriter(path)
+	if err != nil {
+		return nil, err
+	}
+	return newCSVSink(bw, comma), nil
+}
+
+func newCSVSink(bw *bufferedWriter, comma rune) *CSVSink {
+	cw := csv.NewWriter(bw)
+	if comma != 0 {
+		cw.Comma = comma
+	}
+	return &CSVSink{w: bw, csv: cw}
+}
+
+func (s *CSVSink) Write(record []string) error {
+	return s.csv.Write(record)
+}
+
+func (s *CSVSink) Close() error {
+	s.csv.Flush()
+	if err := s.csv.Error(); err != nil {
+		s.w.close()
+		return err
+	}
+	return s.w.close()
+}
+
+func NewSliceSink[T any]() *SliceSink[T] {
+	return &SliceSink[T]{}
+}
+
+func (s *SliceSink[T]) Write(item T) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.items = append(s.items, item)
+	return nil
+}
+
+func (s *SliceSink[T]) Close() error {
+	return nil
+}
+
+// Items returns a copy of the collected items.
+func (s *SliceSink[T]) Items() []T {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]T(nil), s.items...)
+}
+
+// helpers
+
+func newBufferedWriter(w io.Writer, closer io.Closer) *bufferedWriter {
+	return &bufferedWriter{Writer: bufio.NewWriter(w), closer: closer}
+}
+
+func createBufferedWriter(path string) (*bufferedWriter, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return newBufferedWriter(f, f), nil
+}
+
+func (w *bufferedWriter) close() error {
+	err := w.Flush()
+	if w.closer != nil {
+		if cerr := w.closer.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
@@ -2,9 +2,22 @@ package pipeline
 
 import (
 	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"iter"
 	"os"
+	"path/filepath"
 )
 
+// FileLineSource emits the lines of the file at path. It cannot be cancelled, so under RunCtx it keeps
+// the file open until it has been read to the end, see SourceCtx. Open the file and use
+// ReaderLineSource to stop reading when the run stops.
 func FileLineSource(path string) Source[string] {
 	return func(out chan<- string) error {
 		defer close(out)
@@ -32,3 +45,178 @@ func SliceSource[T any](items []T) Source[T] {
 		return nil
 	}
 }
+
+// SourceFromCtx adapts a CtxSource for FromSource and Pipeline, using a background context.
+func SourceFromCtx[T any](src CtxSource[T]) Source[T] {
+	return func(out chan<- T) error {
+		defer close(out)
+		return src(context.Background(), out)
+	}
+}
+
+// SeqSource emits the values of an iterator.
+func SeqSource[T any](seq iter.Seq[T]) CtxSource[T] {
+	return func(ctx context.Context, out chan<- T) error {
+		for v := range seq {
+			if !sendCtx(ctx, out, v) {
+				return ctx.Err()
+			}
+		}
+		return nil
+	}
+}
+
+// ReaderChunkSource splits r into chunks of size bytes, the last chunk may be shorter.
+func ReaderChunkSource(r io.Reader, size int) CtxSource[[]byte] {
+	return func(ctx context.Context, out chan<- []byte) error {
+		if size <= 0 {
+			return errors.New("chunk size must be positive")
+		}
+		for {
+			buf := make([]byte, size)
+			n, err := io.ReadFull(r, buf)
+			if n > 0 && !sendCtx(ctx, out, buf[:n]) {
+				return ctx.Err()
+			}
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// ReaderLineSource emits the lines of r.
+func ReaderLineSource(r io.Reader) CtxSource[string] {
+	return func(ctx context.Context, out chan<- string) error {
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+		for scanner.Scan() {
+			if !sendCtx(ctx, out, scanner.Text()) {
+				return ctx.Err()
+			}
+		}
+		return scanner.Err()
+	}
+}
+
+// GzipFileLineSource emits the lines of a gzip compressed file.
+func GzipFileLineSource(path string) CtxSource[string] {
+	return func(ctx context.Context, out chan<- string) error {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		zr, err := gzip.NewReader(file)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		defer zr.Close()
+		return ReaderLineSource(zr)(ctx, out)
+	}
+}
+
+// CSVReaderSource emits the records of r. A zero comma means ','.
+func CSVReaderSource(r io.Reader, comma rune) CtxSource[[]string] {
+	return func(ctx context.Context, out chan<- []string) error {
+		cr := csv.NewReader(r)
+		if comma != 0 {
+			cr.Comma = comma
+		}
+		for {
+			record, err := cr.Read()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if !sendCtx(ctx, out, record) {
+				return ctx.Err()
+			}
+		}
+	}
+}
+
+func CSVSource(path string, comma rune) CtxSource[[]string] {
+	return fileSource(path, func(r io.Reader) CtxSource[[]string] {
+		return CSVReaderSource(r, comma)
+	})
+}
+
+// JSONLinesReaderSource decodes each non-empty line of r into a T.
+func JSONLinesReaderSource[T any](r io.Reader) CtxSource[T] {
+	return func(ctx context.Context, out chan<- T) error {
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+		for line := 1; scanner.Scan(); line++ {
+			if len(scanner.Bytes()) == 0 {
+				continue
+			}
+			var v T
+			if err := json.Unmarshal(scanner.Bytes(), &v); err != nil {
+				return fmt.Errorf("line %d: %w", line, err)
+			}
+			if !sendCtx(ctx, out, v) {
+				return ctx.Err()
+			}
+		}
+		return scanner.Err()
+	}
+}
+
+func JSONLinesSource[T any](path string) CtxSource[T] {
+	return fileSource(path, JSONLinesReaderSource[T])
+}
+
+// DirSource walks root recursively and emits the paths of regular files whose name
+// matches one of the glob patterns, or all files if there are none.
+func DirSource(root string, patterns ...string) CtxSource[string] {
+	return func(ctx context.Context, out chan<- string) error {
+		for _, p := range patterns {
+			if _, err := filepath.Match(p, ""); err != nil {
+				return fmt.Errorf("invalid pattern %q: %w", p, err)
+			}
+		}
+		return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.Type().IsRegular() || !matchAny(d.Name(), patterns) {
+				return nil
+			}
+			if !sendCtx(ctx, out, path) {
+				return ctx.Err()
+			}
+			return nil
+		})
+	}
+}
+
+// helpers
+
+func fileSource[T any](path string, fromReader func(io.Reader) CtxSource[T]) CtxSource[T] {
+	return func(ctx context.Context, out chan<- T) error {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		return fromReader(file)(ctx, out)
+	}
+}
+
+func matchAny(name string, patterns []string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, p := range patterns {
+		if ok, _ := filepath.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
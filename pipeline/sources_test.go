@@ -0,0 +1,118 @@
+package pipeline
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+
+	. "github.com/jnsoft/jngo/testhelper"
+)
+
+type record struct {
+	Name  string `json:"name"`
+	Value int    `json:"value"`
+}
+
+// failingWriter fails every write
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errBoom }
+
+func TestSourcesAndSinks(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("CSV round trip", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "data.csv")
+		sink, err := NewFileCSVSink(path, ';')
+		AssertNil(t, err)
+		pb := FinallySink(FromSourceCtx(SourceCtx(SliceSource([][]string{{"a", "1"}, {"b;c", "2"}}))), Sink[[]string](sink))
+		AssertNil(t, RunCtx(ctx, pb))
+
+		out := NewSliceSink[[]string]()
+		AssertNil(t, RunCtx(ctx, FinallySink(FromSourceCtx(CSVSource(path, ';')), Sink[[]string](out))))
+		records := out.Items()
+		AssertEqual(t, len(records), 2)
+		AssertEqual(t, records[1][0], "b;c")
+	})
+
+	t.Run("JSON Lines round trip", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "data.jsonl")
+		sink, err := NewFileJSONLinesSink[record](path)
+		AssertNil(t, err)
+		items := []record{{"x", 1}, {"y", 2}, {"z", 3}}
+		AssertNil(t, RunCtx(ctx, FinallySink(FromSourceCtx(SourceCtx(SliceSource(items))), Sink[record](sink))))
+
+		out := NewSliceSink[record]()
+		AssertNil(t, RunCtx(ctx, FinallySink(FromSourceCtx(JSONLinesSource[record](path)), Sink[record](out))))
+		CollectionAssertEqual(t, out.Items(), items)
+
+		bad := JSONLinesReaderSource[record](strings.NewReader("{\"name\":\"a\"}\n\nnot json\n"))
+		err = RunCtx(ctx, FromSourceCtx(bad))
+		AssertTrue(t, err != nil && strings.Contains(err.Error(), "line 3"))
+	})
+
+	t.Run("Directory walk with globs", func(t *testing.T) {
+		dir := t.TempDir()
+		_ = os.MkdirAll(filepath.Join(dir, "nested"), 0o755)
+		for _, name := range []string{"a.txt", "b.log", filepath.Join("nested", "c.txt")} {
+			_ = os.WriteFile(filepath.Join(dir, name), nil, 0o644)
+		}
+		out := NewSliceSink[string]()
+		AssertNil(t, RunCtx(ctx, FinallySink(FromSourceCtx(DirSource(dir, "*.txt")), Sink[string](out))))
+		got := out.Items()
+		sort.Strings(got)
+		CollectionAssertEqual(t, got, []string{filepath.Join(dir, "a.txt"), filepath.Join(dir, "nested", "c.txt")})
+
+		err := RunCtx(ctx, FromSourceCtx(DirSource(dir, "[")))
+		AssertTrue(t, err != nil)
+	})
+
+	t.Run("Gzip lines", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "lines.gz")
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		_, _ = zw.Write([]byte("one\ntwo\nthree\n"))
+		_ = zw.Close()
+		_ = os.WriteFile(path, buf.Bytes(), 0o644)
+
+		out := NewSliceSink[string]()
+		AssertNil(t, RunCtx(ctx, FinallySink(FromSourceCtx(GzipFileLineSource(path)), Sink[string](out))))
+		CollectionAssertEqual(t, out.Items(), []string{"one", "two", "three"})
+	})
+
+	t.Run("Reader chunks and iterator", func(t *testing.T) {
+		var sizes []int
+		src := ReaderChunkSource(strings.NewReader(strings.Repeat("x", 25)), 10)
+		Run(Finally(FromSource(SourceFromCtx(src)), func(b []byte) { sizes = append(sizes, len(b)) }), nil)
+		CollectionAssertEqual(t, sizes, []int{10, 10, 5})
+
+		out := NewSliceSink[int]()
+		AssertNil(t, RunCtx(ctx, FinallySink(FromSourceCtx(SeqSource(slices.Values([]int{3, 1, 2}))), Sink[int](out))))
+		CollectionAssertEqual(t, out.Items(), []int{3, 1, 2})
+	})
+
+	t.Run("Line sink", func(t *testing.T) {
+		var buf bytes.Buffer
+		pb := FinallySink(FromSourceCtx(ReaderLineSource(strings.NewReader("a\nb"))), Sink[string](NewLineSink(&buf)))
+		AssertNil(t, RunCtx(ctx, pb))
+		AssertEqual(t, buf.String(), "a\nb\n")
+	})
+
+	t.Run("Write errors are returned", func(t *testing.T) {
+		pb := FinallySink(FromSourceCtx(countingSource(10)), Sink[int](NewJSONLinesSink[int](failingWriter{})))
+		err := RunCtx(ctx, pb)
+		AssertTrue(t, errors.Is(err, errBoom))
+
+		// errors that only surface when the buffer is flushed fail the run on Close
+		pb2 := FinallySink(FromSourceCtx(SourceCtx(SliceSource([]string{"short"}))), Sink[string](NewLineSink(failingWriter{})))
+		err = RunCtx(ctx, pb2, WithErrorPolicy(SKIP_ERRORS))
+		AssertTrue(t, errors.Is(err, errBoom))
+	})
+}
@@ -0,0 +1,262 @@
+package pipeline
+
+import (
+	"time"
+)
+
+// Batching and windowing transforms. They all close their output when the input is closed,
+// flushing whatever is buffered, and block on a full output channel (backpressure).
+
+type timedItem[T any] struct {
+	at  time.Time
+	val T
+}
+
+type session[T any] struct {
+	items []T
+	last  time.Time
+}
+
+// Batch groups items into slices of size, a partial batch is emitted maxWait after its first item.
+// A maxWait of zero disables the time based flush.
+func Batch[T any](size int, maxWait time.Duration) Transform[T, []T] {
+	return func(in <-chan T) <-chan []T {
+		out := make(chan []T, BUFFER_SIZE)
+		go func() {
+			defer close(out)
+			var batch []T
+			var timer *time.Timer
+			var timeout <-chan time.Time
+
+			flush := func() {
+				if timer != nil {
+					timer.Stop()
+					timeout = nil
+				}
+				if len(batch) > 0 {
+					out <- batch
+					batch = nil
+				}
+			}
+
+			for {
+				select {
+				case item, ok := <-in:
+					if !ok {
+						flush()
+						return
+					}
+					batch = append(batch, item)
+					if len(batch) == 1 && maxWait > 0 {
+						timer = time.NewTimer(maxWait)
+						timeout = timer.C
+					}
+					if size > 0 && len(batch) >= size {
+						flush()
+					}
+				case <-timeout:
+					flush()
+				}
+			}
+		}()
+		return out
+	}
+}
+
+// TumblingCountWindow emits consecutive, non-overlapping windows of size items.
+func TumblingCountWindow[T any](size int) Transform[T, []T] {
+	return Batch[T](size, 0)
+}
+
+// SlidingCountWindow emits the last size items every step items.
+// If the stream ends before a full window, the partial window is emitted.
+func SlidingCountWindow[T any](size, step int) Transform[T, []T] {
+	size, step = max(size, 1), max(step, 1)
+	return func(in <-chan T) <-chan []T {
+		out := make(chan []T, BUFFER_SIZE)
+		go func() {
+			defer close(out)
+			window := make([]T, 0, size)
+			seen := 0
+			for item := range in {
+				if len(window) == size {
+					window = append(window[:0], window[1:]...)
+				}
+				window = append(window, item)
+				seen++
+				if seen >= size && (seen-size)%step == 0 {
+					out <- append([]T(nil), window...)
+				}
+			}
+			if seen > 0 && seen < size {
+				out <- window
+			}
+		}()
+		return out
+	}
+}
+
+// TumblingTimeWindow emits the items received during each period of length d. Empty windows are skipped.
+// Like Batch, a d of zero or less disables the time based flush, everything is emitted when the input closes.
+func TumblingTimeWindow[T any](d time.Duration) Transform[T, []T] {
+	return func(in <-chan T) <-chan []T {
+		out := make(chan []T, BUFFER_SIZE)
+		go func() {
+			defer close(out)
+			var tick <-chan time.Time
+			if d > 0 {
+				ticker := time.NewTicker(d)
+				defer ticker.Stop()
+				tick = ticker.C
+			}
+			var window []T
+			for {
+				select {
+				case item, ok := <-in:
+					if !ok {
+						if len(window) > 0 {
+							out <- window
+						}
+						return
+					}
+					window = append(window, item)
+				case <-tick:
+					if len(window) > 0 {
+						out <- window
+						window = nil
+					}
+				}
+			}
+		}()
+		return out
+	}
+}
+
+// SlidingTimeWindow emits, every slide, the items received during the last size duration.
+// Like Batch, zero or less disables the time limit: a size of zero keeps every item, a slide of zero
+// emits only when the input closes.
+func SlidingTimeWindow[T any](size, slide time.Duration) Transform[T, []T] {
+	return func(in <-chan T) <-chan []T {
+		out := make(chan []T, BUFFER_SIZE)
+		go func() {
+			defer close(out)
+			var tick <-chan time.Time
+			if slide > 0 {
+				ticker := time.NewTicker(slide)
+				defer ticker.Stop()
+				tick = ticker.C
+			}
+			var items []timedItem[T]
+			dirty := false // items arrived since last emit
+
+			emit := func(now time.Time) {
+				cut := 0
+				for size > 0 && cut < len(items) && now.Sub(items[cut].at) > size {
+					cut++
+				}
+				items = items[cut:]
+				if len(items) == 0 {
+					return
+				}
+				window := make([]T, len(items))
+				for i, it := range items {
+					window[i] = it.val
+				}
+				out <- window
+				dirty = false
+			}
+
+			for {
+				select {
+				case item, ok := <-in:
+					if !ok {
+						if dirty {
+							emit(time.Now())
+						}
+						return
+					}
+					items = append(items, timedItem[T]{time.Now(), item})
+					dirty = true
+				case now := <-tick:
+					emit(now)
+				}
+			}
+		}()
+		return out
+	}
+}
+
+// SessionWindow groups items per key into sessions that close after gap without a new item for the key.
+// Open sessions are emitted when the input is closed. Like Batch, a gap of zero or less disables the
+// time based close, each key then has a single session.
+func SessionWindow[T any, K comparable](key func(T) K, gap time.Duration) Transform[T, []T] {
+	return func(in <-chan T) <-chan []T {
+		out := make(chan []T, BUFFER_SIZE)
+		go func() {
+			defer close(out)
+			sessions := make(map[K]*session[T])
+			var order []K // keys in order of session start, for deterministic flushing
+			var timer *time.Timer
+			var timeout <-chan time.Time
+
+			schedule := func(now time.Time) {
+				var next time.Time
+				for _, s := range sessions {
+					if deadline := s.last.Add(gap); next.IsZero() || deadline.Before(next) {
+						next = deadline
+					}
+				}
+				if next.IsZero() {
+					timeout = nil
+					return
+				}
+				timer = time.NewTimer(max(next.Sub(now), 0))
+				timeout = timer.C
+			}
+
+			closeExpired := func(now time.Time, all bool) {
+				kept := order[:0]
+				for _, k := range order {
+					s := sessions[k]
+					if all || now.Sub(s.last) >= gap {
+						out <- s.items
+						delete(sessions, k)
+					} else {
+						kept = append(kept, k)
+					}
+				}
+				order = kept
+			}
+
+			for {
+				select {
+				case item, ok := <-in:
+					if !ok {
+						if timer != nil {
+							timer.Stop()
+						}
+						closeExpired(time.Now(), true)
+						return
+					}
+					now := time.Now()
+					k := key(item)
+					s, found := sessions[k]
+					if !found {
+						s = &session[T]{}
+						sessions[k] = s
+						order = append(order, k)
+					}
+					s.items = append(s.items, item)
+					s.last = now
+					if timeout == nil && gap > 0 {
+						schedule(now)
+					}
+				case now := <-timeout:
+					closeExpired(now, false)
+					schedule(now)
+				}
+			}
+		}()
+		return out
+	}
+}
@@ -0,0 +1,157 @@
+package pipeline
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/jnsoft/jngo/testhelper"
+)
+
+func TestWindows(t *testing.T) {
+
+	t.Run("Batch by size", func(t *testing.T) {
+		got := collect(FromSource(SliceSource([]int{1, 2, 3, 4, 5, 6, 7})), Batch[int](3, time.Second))
+		AssertEqual(t, len(got), 3)
+		CollectionAssertEqual(t, got[0], []int{1, 2, 3})
+		CollectionAssertEqual(t, got[2], []int{7})
+	})
+
+	t.Run("Batch by time", func(t *testing.T) {
+		src := func(out chan<- int) error {
+			defer close(out)
+			out <- 1
+			out <- 2
+			time.Sleep(60 * time.Millisecond)
+			out <- 3
+			return nil
+		}
+		got := collect(FromSource(src), Batch[int](100, 20*time.Millisecond))
+		AssertEqual(t, len(got), 2)
+		CollectionAssertEqual(t, got[0], []int{1, 2})
+		CollectionAssertEqual(t, got[1], []int{3})
+	})
+
+	t.Run("Tumbling count window", func(t *testing.T) {
+		got := collect(FromSource(SliceSource([]int{1, 2, 3, 4})), TumblingCountWindow[int](2))
+		AssertEqual(t, len(got), 2)
+		CollectionAssertEqual(t, got[1], []int{3, 4})
+	})
+
+	t.Run("Sliding count window", func(t *testing.T) {
+		got := collect(FromSource(SliceSource([]int{1, 2, 3, 4, 5, 6})), SlidingCountWindow[int](3, 2))
+		AssertEqual(t, len(got), 2)
+		CollectionAssertEqual(t, got[0], []int{1, 2, 3})
+		CollectionAssertEqual(t, got[1], []int{3, 4, 5})
+
+		got = collect(FromSource(SliceSource([]int{1, 2})), SlidingCountWindow[int](3, 1))
+		AssertEqual(t, len(got), 1)
+		CollectionAssertEqual(t, got[0], []int{1, 2})
+	})
+
+	t.Run("Tumbling time window", func(t *testing.T) {
+		src := func(out chan<- int) error {
+			defer close(out)
+			out <- 1
+			out <- 2
+			time.Sleep(80 * time.Millisecond)
+			out <- 3
+			return nil
+		}
+		got := collect(FromSource(src), TumblingTimeWindow[int](30*time.Millisecond))
+		AssertEqual(t, len(got), 2)
+		CollectionAssertEqual(t, got[0], []int{1, 2})
+		CollectionAssertEqual(t, got[1], []int{3})
+	})
+
+	t.Run("Sliding time window", func(t *testing.T) {
+		src := func(out chan<- int) error {
+			defer close(out)
+			for i := 0; i < 5; i++ {
+				out <- i
+				time.Sleep(10 * time.Millisecond)
+			}
+			return nil
+		}
+		got := collect(FromSource(src), SlidingTimeWindow[int](time.Second, 15*time.Millisecond))
+		AssertTrue(t, len(got) >= 2)
+		// nothing expires within a second, so the last window holds everything
+		CollectionAssertEqual(t, got[len(got)-1], []int{0, 1, 2, 3, 4})
+		for i := 1; i < len(got); i++ {
+			AssertTrue(t, len(got[i]) >= len(got[i-1]))
+		}
+	})
+
+	t.Run("Non-positive durations disable the time limit", func(t *testing.T) {
+		for _, window := range []Transform[int, []int]{
+			TumblingTimeWindow[int](0),
+			SlidingTimeWindow[int](0, 0),
+			SlidingTimeWindow[int](-time.Second, -time.Second),
+			SessionWindow(func(int) bool { return true }, 0),
+		} {
+			got := collect(FromSource(SliceSource([]int{1, 2, 3})), window)
+			AssertEqual(t, len(got), 1)
+			CollectionAssertEqual(t, got[0], []int{1, 2, 3})
+		}
+
+		// items never expire from a window without a size
+		src := func(out chan<- int) error {
+			defer close(out)
+			out <- 1
+			time.Sleep(30 * time.Millisecond)
+			out <- 2
+			return nil
+		}
+		got := collect(FromSource(src), SlidingTimeWindow[int](0, 10*time.Millisecond))
+		CollectionAssertEqual(t, got[len(got)-1], []int{1, 2})
+	})
+
+	t.Run("Session window", func(t *testing.T) {
+		type event struct {
+			user string
+			n    int
+		}
+		src := func(out chan<- event) error {
+			defer close(out)
+			out <- event{"a", 1}
+			out <- event{"b", 1}
+			out <- event{"a", 2}
+			time.Sleep(80 * time.Millisecond)
+			out <- event{"a", 3}
+			return nil
+		}
+		got := collect(FromSource(src), SessionWindow(func(e event) string { return e.user }, 30*time.Millisecond))
+		AssertEqual(t, len(got), 3)
+		var sessionsA [][]event
+		for _, s := range got {
+			if s[0].user == "a" {
+				sessionsA = append(sessionsA, s)
+			} else {
+				AssertEqual(t, len(s), 1)
+			}
+		}
+		AssertEqual(t, len(sessionsA), 2)
+		AssertEqual(t, len(sessionsA[0]), 2)
+		AssertEqual(t, sessionsA[0][1].n, 2)
+		AssertEqual(t, sessionsA[1][0].n, 3)
+	})
+
+	t.Run("Context pipeline", func(t *testing.T) {
+		var sizes []int
+		pb := ThenCtx(FromSourceCtx(countingSource(10)), Lift(Batch[int](4, 0)))
+		pb2 := FinallyCtx(pb, func(_ context.Context, b []int) error {
+			sizes = append(sizes, len(b))
+			return nil
+		})
+		AssertNil(t, RunCtx(context.Background(), pb2))
+		CollectionAssertEqual(t, sizes, []int{4, 4, 2})
+	})
+}
+
+// helpers
+
+func collect[T, U any](pb PipelineBuilder[T], t Transform[T, U]) []U {
+	var got []U
+	Run(Finally(Then(pb, t), func(u U) { got = append(got, u) }), nil)
+	return got
+}
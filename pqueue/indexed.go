@@ -0,0 +1,198 @@
+package pqueue
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// IndexedPriorityQueue associates keys with the indexes 0..capacity-1, so the key of an index can be
+// changed while it is in the queue (decrease-key). Dequeue returns the index with the smallest key.
+type IndexedPriorityQueue[T any] struct {
+	pq   []int // binary heap of indexes, 1-based
+	qp   []int // qp[i] = position of index i in pq, -1 if absent
+	keys []T
+	N    int
+	less func(i, j T) bool
+	mu   sync.RWMutex
+}
+
+func NewIndexedPriorityQueue[T any](capacity int, less func(i, j T) bool) *IndexedPriorityQueue[T] {
+	qp := make([]int, capacity)
+	for i := range qp {
+		qp[i] = -1
+	}
+	return &IndexedPriorityQueue[T]{
+		pq:   make([]int, capacity+1),
+		qp:   qp,
+		keys: make([]T, capacity),
+		less: less,
+	}
+}
+
+func (pq *IndexedPriorityQueue[T]) IsEmpty() bool {
+	pq.mu.RLock()
+	defer pq.mu.RUnlock()
+	return pq.N == 0
+}
+
+func (pq *IndexedPriorityQueue[T]) Size() int {
+	pq.mu.RLock()
+	defer pq.mu.RUnlock()
+	return pq.N
+}
+
+func (pq *IndexedPriorityQueue[T]) Contains(i int) bool {
+	pq.mu.RLock()
+	defer pq.mu.RUnlock()
+	return pq.valid(i) == nil && pq.qp[i] != -1
+}
+
+func (pq *IndexedPriorityQueue[T]) Insert(i int, key T) error {
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+	if err := pq.valid(i); err != nil {
+		return err
+	}
+	if pq.qp[i] != -1 {
+		return fmt.Errorf("index %d is already in the queue", i)
+	}
+	pq.N++
+	pq.qp[i] = pq.N
+	pq.pq[pq.N] = i
+	pq.keys[i] = key
+	pq.swim(pq.N)
+	return nil
+}
+
+func (pq *IndexedPriorityQueue[T]) KeyOf(i int) (T, error) {
+	pq.mu.RLock()
+	defer pq.mu.RUnlock()
+	if err := pq.present(i); err != nil {
+		return *new(T), err
+	}
+	return pq.keys[i], nil
+}
+
+// ChangeKey sets the key of i, moving it up or down as needed.
+func (pq *IndexedPriorityQueue[T]) ChangeKey(i int, key T) error {
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+	if err := pq.present(i); err != nil {
+		return err
+	}
+	pq.keys[i] = key
+	pq.swim(pq.qp[i])
+	pq.sink(pq.qp[i])
+	return nil
+}
+
+// DecreaseKey sets the key of i to a key that must not be greater than the current one.
+func (pq *IndexedPriorityQueue[T]) DecreaseKey(i int, key T) error {
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+	if err := pq.present(i); err != nil {
+		return err
+	}
+	if pq.less(pq.keys[i], key) {
+		return fmt.Errorf("key of index %d would increase", i)
+	}
+	pq.keys[i] = key
+	pq.swim(pq.qp[i])
+	return nil
+}
+
+// Peek returns the index with the smallest key and its key.
+func (pq *IndexedPriorityQueue[T]) Peek() (int, T, error) {
+	pq.mu.RLock()
+	defer pq.mu.RUnlock()
+	if pq.N == 0 {
+		return -1, *new(T), errors.New(EMPTY_QUEUE)
+	}
+	return pq.pq[1], pq.keys[pq.pq[1]], nil
+}
+
+// Dequeue removes and returns the index with the smallest key and its key.
+func (pq *IndexedPriorityQueue[T]) Dequeue() (int, T, error) {
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+	if pq.N == 0 {
+		return -1, *new(T), errors.New(EMPTY_QUEUE)
+	}
+	i := pq.pq[1]
+	key := pq.keys[i]
+	pq.removeAt(1)
+	return i, key, nil
+}
+
+func (pq *IndexedPriorityQueue[T]) Delete(i int) error {
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+	if err := pq.present(i); err != nil {
+		return err
+	}
+	pq.removeAt(pq.qp[i])
+	return nil
+}
+
+func (pq *IndexedPriorityQueue[T]) valid(i int) error {
+	if i < 0 || i >= len(pq.qp) {
+		return fmt.Errorf("index %d out of bounds", i)
+	}
+	return nil
+}
+
+func (pq *IndexedPriorityQueue[T]) present(i int) error {
+	if err := pq.valid(i); err != nil {
+		return err
+	}
+	if pq.qp[i] == -1 {
+		return fmt.Errorf("index %d is not in the queue", i)
+	}
+	return nil
+}
+
+func (pq *IndexedPriorityQueue[T]) removeAt(k int) {
+	i := pq.pq[k]
+	pq.exch(k, pq.N)
+	pq.N--
+	if k <= pq.N {
+		pq.swim(k)
+		pq.sink(k)
+	}
+	pq.qp[i] = -1
+	pq.keys[i] = *new(T) // no loitering
+}
+
+// heap helper functions
+
+func (pq *IndexedPriorityQueue[T]) greater(i, j int) bool {
+	return pq.less(pq.keys[pq.pq[j]], pq.keys[pq.pq[i]])
+}
+
+func (pq *IndexedPriorityQueue[T]) exch(i, j int) {
+	pq.pq[i], pq.pq[j] = pq.pq[j], pq.pq[i]
+	pq.qp[pq.pq[i]] = i
+	pq.qp[pq.pq[j]] = j
+}
+
+func (pq *IndexedPriorityQueue[T]) swim(k int) {
+	for k > 1 && pq.greater(k/2, k) {
+		pq.exch(k, k/2)
+		k = k / 2
+	}
+}
+
+func (pq *IndexedPriorityQueue[T]) sink(k int) {
+	for 2*k <= pq.N {
+		j := 2 * k
+		if j < pq.N && pq.greater(j, j+1) {
+			j++
+		}
+		if !pq.greater(k, j) {
+			break
+		}
+		pq.exch(k, j)
+		k = j
+	}
+}
@@ -0,0 +1,61 @@
+package pqueue
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+
+	. "github.com/jnsoft/jngo/testhelper"
+)
+
+func TestIndexedPriorityQueue(t *testing.T) {
+	t.Run("decrease key", func(t *testing.T) {
+		q := NewIndexedPriorityQueue[float64](5, func(i, j float64) bool { return i < j })
+		AssertTrue(t, q.IsEmpty())
+		AssertNil(t, q.Insert(0, 5.0))
+		AssertNil(t, q.Insert(3, 2.0))
+		AssertNil(t, q.Insert(4, 9.0))
+		AssertTrue(t, q.Insert(3, 1.0) != nil)
+		AssertTrue(t, q.Insert(7, 1.0) != nil)
+		AssertTrue(t, q.Contains(4))
+		AssertFalse(t, q.Contains(1))
+
+		AssertNil(t, q.DecreaseKey(4, 1.0))
+		AssertTrue(t, q.DecreaseKey(0, 6.0) != nil)
+		i, key, err := q.Peek()
+		AssertNil(t, err)
+		AssertEqual(t, i, 4)
+		AssertEqual(t, key, 1.0)
+
+		AssertNil(t, q.ChangeKey(4, 10.0))
+		AssertNil(t, q.Delete(3))
+		i, _, _ = q.Dequeue()
+		AssertEqual(t, i, 0)
+		i, key, _ = q.Dequeue()
+		AssertEqual(t, i, 4)
+		AssertEqual(t, key, 10.0)
+		_, _, err = q.Dequeue()
+		AssertTrue(t, err != nil)
+	})
+
+	t.Run("random keys come out sorted", func(t *testing.T) {
+		n := 1000
+		q := NewIndexedPriorityQueue[int](n, func(i, j int) bool { return i < j })
+		keys := make([]int, n)
+		for i := range keys {
+			keys[i] = rand.Intn(10 * n)
+			_ = q.Insert(i, keys[i])
+		}
+		for i := 0; i < n; i += 3 {
+			keys[i] = rand.Intn(10 * n)
+			_ = q.ChangeKey(i, keys[i])
+		}
+		sort.Ints(keys)
+		for _, want := range keys {
+			i, key, err := q.Dequeue()
+			AssertNil(t, err)
+			AssertEqual(t, key, want)
+			AssertFalse(t, q.Contains(i))
+		}
+	})
+}
@@ -0,0 +1,83 @@
+package symbolgraph
+
+import (
+	"io"
+
+	"github.com/jnsoft/jngo/graph"
+	"github.com/jnsoft/jngo/red_black_bst"
+)
+
+// Interchange converts sg for the graph package readers and writers, using the vertex names.
+func (sg *SymbolGraph) Interchange() *graph.Interchange {
+	return sg.g.Interchange(sg.Name)
+}
+
+// builds a symbol graph with the vertices and edges of x, numbering vertices in the order of x.Nodes
+func NewSymbolGraphFromInterchange(x *graph.Interchange) *SymbolGraph {
+	st := red_black_bst.NewRedBlackTree[string, int]()
+	var keys []string
+	index := func(name string) int {
+		if ix, err := st.Get(name); err == nil {
+			return ix
+		}
+		st.Put(name, len(keys))
+		keys = append(keys, name)
+		return len(keys) - 1
+	}
+	for _, name := range x.Nodes {
+		index(name)
+	}
+	for _, e := range x.Edges {
+		index(e.From)
+		index(e.To)
+	}
+
+	g, _ := graph.NewGraph(len(keys), x.Directed)
+	for _, e := range x.Edges {
+		g.AddEdge(index(e.From), index(e.To))
+	}
+	return &SymbolGraph{
+		st:   st,
+		keys: keys,
+		g:    g,
+	}
+}
+
+func (sg *SymbolGraph) WriteDOT(w io.Writer) error {
+	return sg.Interchange().WriteDOT(w)
+}
+
+func (sg *SymbolGraph) WriteGraphML(w io.Writer) error {
+	return sg.Interchange().WriteGraphML(w)
+}
+
+func (sg *SymbolGraph) WriteEdgeList(w io.Writer) error {
+	return sg.Interchange().WriteEdgeList(w)
+}
+
+func (sg *SymbolGraph) WriteJSON(w io.Writer) error {
+	return sg.Interchange().WriteJSON(w)
+}
+
+func ReadDOT(r io.Reader) (*SymbolGraph, error) {
+	return fromInterchange(graph.ParseDOT(r))
+}
+
+func ReadGraphML(r io.Reader) (*SymbolGraph, error) {
+	return fromInterchange(graph.ParseGraphML(r))
+}
+
+func ReadEdgeList(r io.Reader, is_directed bool) (*SymbolGraph, error) {
+	return fromInterchange(graph.ParseEdgeList(r, is_directed))
+}
+
+func ReadJSON(r io.Reader) (*SymbolGraph, error) {
+	return fromInterchange(graph.ParseJSON(r))
+}
+
+func fromInterchange(x *graph.Interchange, err error) (*SymbolGraph, error) {
+	if err != nil {
+		return nil, err
+	}
+	return NewSymbolGraphFromInterchange(x), nil
+}
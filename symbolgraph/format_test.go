@@ -0,0 +1,55 @@
+package symbolgraph
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	. "github.com/jnsoft/jngo/testhelper"
+)
+
+func TestFormats(t *testing.T) {
+	input := `Movie One/Actor A/Actor B
+Movie Two/Actor B/Actor "C"
+`
+
+	t.Run("Round trip with names", func(t *testing.T) {
+		sg, _ := NewSymbolGraph(input, "/", false)
+		write := map[string]func(*bytes.Buffer) error{
+			"DOT":       func(b *bytes.Buffer) error { return sg.WriteDOT(b) },
+			"GraphML":   func(b *bytes.Buffer) error { return sg.WriteGraphML(b) },
+			"Edge list": func(b *bytes.Buffer) error { return sg.WriteEdgeList(b) },
+			"JSON":      func(b *bytes.Buffer) error { return sg.WriteJSON(b) },
+		}
+		read := map[string]func(*bytes.Buffer) (*SymbolGraph, error){
+			"DOT":       func(b *bytes.Buffer) (*SymbolGraph, error) { return ReadDOT(b) },
+			"GraphML":   func(b *bytes.Buffer) (*SymbolGraph, error) { return ReadGraphML(b) },
+			"Edge list": func(b *bytes.Buffer) (*SymbolGraph, error) { return ReadEdgeList(b, false) },
+			"JSON":      func(b *bytes.Buffer) (*SymbolGraph, error) { return ReadJSON(b) },
+		}
+		for format, w := range write {
+			var buf bytes.Buffer
+			AssertNil(t, w(&buf))
+			AssertTrue(t, strings.Contains(buf.String(), "Movie One"))
+			sg2, err := read[format](&buf)
+			AssertNil(t, err)
+			for v := 0; v < sg.g.V(); v++ {
+				AssertEqual(t, sg2.Index(sg.Name(v)), v)
+			}
+			AssertEqual(t, sg2.g.E(), sg.g.E())
+			hops, _ := sg2.DegreesOfSeparation("Actor A", `Actor "C"`)
+			AssertEqual(t, hops, 5)
+		}
+	})
+
+	t.Run("Read DOT with names", func(t *testing.T) {
+		sg, err := ReadDOT(strings.NewReader(`digraph { "web" -> "api" -> "db"; "api" -> cache }`))
+		AssertNil(t, err)
+		AssertTrue(t, sg.Contains("cache"))
+		AssertEqual(t, sg.Name(sg.Index("db")), "db")
+		AssertEqual(t, sg.g.E(), 3)
+		AssertTrue(t, sg.g.IsDirected())
+		_, err = ReadDOT(strings.NewReader(`digraph { "web" -> }`))
+		AssertNotEqual(t, err, nil)
+	})
+}
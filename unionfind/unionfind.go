@@ -0,0 +1,87 @@
+package unionfind
+
+import "fmt"
+
+// UnionFind is a disjoint-set forest over the elements 0..n-1, using weighted quick-union with
+// path compression. Find, Union and Connected take nearly constant amortized time.
+type UnionFind struct {
+	parent []int // parent[p] = parent of p, p itself for a root
+	size   []int // size[p] = number of elements in the tree rooted at p
+	count  int   // number of sets
+}
+
+func NewUnionFind(n int) (*UnionFind, error) {
+	if n < 0 {
+		return nil, fmt.Errorf("number of elements must be nonnegative: %d", n)
+	}
+	uf := &UnionFind{parent: make([]int, n), size: make([]int, n), count: n}
+	for p := 0; p < n; p++ {
+		uf.parent[p] = p
+		uf.size[p] = 1
+	}
+	return uf, nil
+}
+
+// number of sets
+func (uf *UnionFind) Count() int {
+	return uf.count
+}
+
+// Find returns the root of the set containing p.
+func (uf *UnionFind) Find(p int) (int, error) {
+	if p < 0 || p >= len(uf.parent) {
+		return -1, fmt.Errorf("element out of bounds: %d", p)
+	}
+	root := p
+	for root != uf.parent[root] {
+		root = uf.parent[root]
+	}
+	for p != root {
+		p, uf.parent[p] = uf.parent[p], root
+	}
+	return root, nil
+}
+
+func (uf *UnionFind) Connected(p, q int) (bool, error) {
+	rootP, err := uf.Find(p)
+	if err != nil {
+		return false, err
+	}
+	rootQ, err := uf.Find(q)
+	if err != nil {
+		return false, err
+	}
+	return rootP == rootQ, nil
+}
+
+// Union merges the sets containing p and q, hanging the smaller tree below the larger one.
+// It returns false if they already were in the same set.
+func (uf *UnionFind) Union(p, q int) (bool, error) {
+	rootP, err := uf.Find(p)
+	if err != nil {
+		return false, err
+	}
+	rootQ, err := uf.Find(q)
+	if err != nil {
+		return false, err
+	}
+	if rootP == rootQ {
+		return false, nil
+	}
+	if uf.size[rootP] < uf.size[rootQ] {
+		rootP, rootQ = rootQ, rootP
+	}
+	uf.parent[rootQ] = rootP
+	uf.size[rootP] += uf.size[rootQ]
+	uf.count--
+	return true, nil
+}
+
+// number of elements in the set containing p
+func (uf *UnionFind) Size(p int) (int, error) {
+	root, err := uf.Find(p)
+	if err != nil {
+		return 0, err
+	}
+	return uf.size[root], nil
+}
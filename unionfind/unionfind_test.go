@@ -0,0 +1,53 @@
+package unionfind
+
+import (
+	"testing"
+
+	. "github.com/jnsoft/jngo/testhelper"
+)
+
+func TestUnionFind(t *testing.T) {
+
+	t.Run("tinyUF", func(t *testing.T) {
+		uf, err := NewUnionFind(10)
+		AssertNil(t, err)
+		pairs := [][2]int{{4, 3}, {3, 8}, {6, 5}, {9, 4}, {2, 1}, {8, 9}, {5, 0}, {7, 2}, {6, 1}, {1, 0}, {6, 7}}
+		merged := 0
+		for _, p := range pairs {
+			ok, err := uf.Union(p[0], p[1])
+			AssertNil(t, err)
+			if ok {
+				merged++
+			}
+		}
+		AssertEqual(t, merged, 8)
+		AssertEqual(t, uf.Count(), 2)
+		connected, _ := uf.Connected(0, 7)
+		AssertTrue(t, connected)
+		connected, _ = uf.Connected(0, 9)
+		AssertFalse(t, connected)
+		size, _ := uf.Size(3)
+		AssertEqual(t, size, 4)
+	})
+
+	t.Run("Out of bounds", func(t *testing.T) {
+		uf, _ := NewUnionFind(3)
+		_, err := uf.Find(3)
+		AssertNotEqual(t, err, nil)
+		_, err = uf.Union(-1, 0)
+		AssertNotEqual(t, err, nil)
+		_, err = NewUnionFind(-1)
+		AssertNotEqual(t, err, nil)
+	})
+
+	t.Run("Long chain", func(t *testing.T) {
+		n := 100_000
+		uf, _ := NewUnionFind(n)
+		for p := 1; p < n; p++ {
+			_, _ = uf.Union(p-1, p)
+		}
+		AssertEqual(t, uf.Count(), 1)
+		size, _ := uf.Size(n / 2)
+		AssertEqual(t, size, n)
+	})
+}